@@ -0,0 +1,624 @@
+// prompt_eval 离线评估提示模板：用标注好的数据集调用简历筛选和面试题生成流程，
+// 按提供方、模型和模板版本输出准确率、精确率/召回率、JSON解析失败率、耗时和令牌费用
+//
+// 用法:
+//
+//	go run ./cmd/prompt_eval -dataset cmd/prompt_eval/testdata/sample.jsonl -provider vertex -model gemini-2.0-flash-001 -versions 1,2 -out report.json
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/GiantClam/ai-resume/models"
+	"github.com/GiantClam/ai-resume/services"
+	"github.com/joho/godotenv"
+)
+
+// 评估的任务
+const (
+	taskScreening = "screening"
+	taskQuestions = "questions"
+)
+
+// 筛选结论
+const (
+	expectPass = "pass"
+	expectFail = "fail"
+)
+
+// defaultMatchThreshold 生成的面试题与参考题的相似度达到该值时视为覆盖了参考题
+const defaultMatchThreshold = 0.3
+
+// Case 数据集中的一条样本，每行一个JSON对象
+type Case struct {
+	ID                 string   `json:"id"`
+	Task               string   `json:"task"` // screening 或 questions
+	Industry           string   `json:"industry"`
+	IndustryKeywords   string   `json:"industryKeywords"`
+	JobRequirements    string   `json:"jobRequirements"`
+	Resume             string   `json:"resume"`     // 简历文件路径，相对于数据集文件
+	ResumeText         string   `json:"resumeText"` // 简历文本，未指定文件时使用
+	Expected           string   `json:"expected"`   // 筛选的预期结论 pass 或 fail
+	ReferenceQuestions []string `json:"referenceQuestions"`
+	Language           string   `json:"language"`
+}
+
+// CaseResult 一条样本的评估结果
+type CaseResult struct {
+	ID         string  `json:"id"`
+	Task       string  `json:"task"`
+	Version    string  `json:"version"` // 实际使用的模板版本
+	Expected   string  `json:"expected,omitempty"`
+	Predicted  string  `json:"predicted,omitempty"`
+	Coverage   float64 `json:"coverage,omitempty"` // 参考题被生成的面试题覆盖的比例
+	ParseError bool    `json:"parseError,omitempty"`
+	Error      string  `json:"error,omitempty"`
+	LatencyMS  int64   `json:"latencyMs"`
+	Calls      int     `json:"calls"`
+
+	Usage services.TokenUsage `json:"usage"`
+	Cost  float64             `json:"cost"`
+}
+
+// Summary 一个任务在一个模板版本下的汇总指标
+type Summary struct {
+	Task     string `json:"task"`
+	Version  string `json:"version"`
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	Cases    int    `json:"cases"`
+	Errors   int    `json:"errors"` // 除解析失败外的调用错误
+
+	ParseFailures    int     `json:"parseFailures"`
+	ParseFailureRate float64 `json:"parseFailureRate"`
+
+	// 简历筛选，以“通过”为正类
+	Accuracy  *float64 `json:"accuracy,omitempty"`
+	Precision *float64 `json:"precision,omitempty"`
+	Recall    *float64 `json:"recall,omitempty"`
+
+	// 面试题生成
+	Coverage *float64 `json:"coverage,omitempty"`
+
+	LatencyAvgMS int64   `json:"latencyAvgMs"`
+	LatencyP50MS int64   `json:"latencyP50Ms"`
+	LatencyP95MS int64   `json:"latencyP95Ms"`
+	Tokens       int     `json:"tokens"`
+	Cost         float64 `json:"cost"`
+	Unpriced     bool    `json:"unpriced,omitempty"` // 价格表中没有该模型，费用为0
+}
+
+// Report 完整的评估报告
+type Report struct {
+	Dataset   string       `json:"dataset"`
+	StartedAt time.Time    `json:"startedAt"`
+	Summaries []Summary    `json:"summaries"`
+	Cases     []CaseResult `json:"cases"`
+}
+
+func main() {
+	envFile := flag.String("env", ".env", "环境变量文件，不存在时忽略")
+	dataset := flag.String("dataset", "", "JSONL 格式的标注数据集")
+	provider := flag.String("provider", "", "提供方 vertex、openai、local 或 fake，为空时使用任务路由配置")
+	model := flag.String("model", "", "模型名称，为空时使用提供方的默认模型")
+	versions := flag.String("versions", "", "逗号分隔的模板版本，逐个评估后对比，为空时使用当前生效的版本")
+	task := flag.String("task", "", "只评估指定任务 screening 或 questions，为空时评估全部")
+	threshold := flag.Float64("match-threshold", defaultMatchThreshold, "生成的面试题与参考题视为匹配的最低相似度")
+	out := flag.String("out", "", "JSON 报告的输出路径，为 - 时输出到标准输出")
+	flag.Parse()
+
+	if *dataset == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := godotenv.Load(*envFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("[WARN] 无法加载 %s: %v", *envFile, err)
+	}
+
+	cases, err := loadDataset(*dataset)
+	if err != nil {
+		log.Fatalf("加载数据集失败: %v", err)
+	}
+	if *task != "" {
+		cases = filterTask(cases, *task)
+	}
+	if len(cases) == 0 {
+		log.Fatalf("数据集中没有可评估的样本")
+	}
+
+	// 评估直接调用任务路由的提供方，不经过响应缓存
+	if *provider != "" {
+		route := *provider
+		if *model != "" {
+			route += ":" + *model
+		}
+		os.Setenv("LLM_ROUTE_SCREENING", route)
+		os.Setenv("LLM_ROUTE_QUESTIONS", route)
+	}
+
+	if err := services.InitPrompts(); err != nil {
+		log.Fatalf("加载提示模板失败: %v", err)
+	}
+	if err := services.InitLLMProviders(); err != nil {
+		log.Fatalf("初始化大模型提供方失败: %v", err)
+	}
+	defer services.CloseLLMProviders()
+
+	report := Report{Dataset: *dataset, StartedAt: time.Now()}
+	for _, version := range splitVersions(*versions) {
+		if version != "" {
+			os.Setenv("PROMPT_VERSION_"+strings.ToUpper(services.ScreeningPrompt.Name()), version)
+			os.Setenv("PROMPT_VERSION_"+strings.ToUpper(services.QuestionsPrompt.Name()), version)
+		}
+
+		var results []CaseResult
+		for i, c := range cases {
+			log.Printf("[INFO] 版本 %s 评估样本 %d/%d: %s", versionLabel(version), i+1, len(cases), c.ID)
+			result := runCase(c, *threshold)
+			if result.Version == "" {
+				result.Version = versionLabel(version)
+			}
+			results = append(results, result)
+		}
+		report.Cases = append(report.Cases, results...)
+		report.Summaries = append(report.Summaries, summarize(results)...)
+	}
+
+	printTable(os.Stdout, report.Summaries)
+	if *out != "" {
+		if err := writeReport(*out, report); err != nil {
+			log.Fatalf("写入报告失败: %v", err)
+		}
+	}
+}
+
+// loadDataset 读取 JSONL 数据集，简历文件路径相对于数据集所在目录
+func loadDataset(path string) ([]Case, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var cases []Case
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var c Case
+		if err := json.Unmarshal([]byte(text), &c); err != nil {
+			return nil, fmt.Errorf("第 %d 行: %w", line, err)
+		}
+		if c.ID == "" {
+			c.ID = fmt.Sprintf("line-%d", line)
+		}
+		if c.Task == "" {
+			c.Task = taskScreening
+		}
+		if err := validateCase(c); err != nil {
+			return nil, fmt.Errorf("第 %d 行 (%s): %w", line, c.ID, err)
+		}
+		if c.Resume != "" && !filepath.IsAbs(c.Resume) {
+			c.Resume = filepath.Join(filepath.Dir(path), c.Resume)
+		}
+		cases = append(cases, c)
+	}
+	return cases, scanner.Err()
+}
+
+// validateCase 检查样本的必填字段
+func validateCase(c Case) error {
+	if c.Resume == "" && c.ResumeText == "" {
+		return errors.New("resume 和 resumeText 不能同时为空")
+	}
+	switch c.Task {
+	case taskScreening:
+		if c.Expected != expectPass && c.Expected != expectFail {
+			return fmt.Errorf("expected 必须为 %s 或 %s", expectPass, expectFail)
+		}
+	case taskQuestions:
+		if len(c.ReferenceQuestions) == 0 {
+			return errors.New("referenceQuestions 不能为空")
+		}
+	default:
+		return fmt.Errorf("未知的任务: %s", c.Task)
+	}
+	return nil
+}
+
+// filterTask 只保留指定任务的样本
+func filterTask(cases []Case, task string) []Case {
+	var filtered []Case
+	for _, c := range cases {
+		if c.Task == task {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered
+}
+
+// splitVersions 解析逗号分隔的版本列表，为空时返回一个空版本表示使用当前生效的版本
+func splitVersions(spec string) []string {
+	var versions []string
+	for _, v := range strings.Split(spec, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			versions = append(versions, strings.TrimPrefix(strings.ToLower(v), "v"))
+		}
+	}
+	if len(versions) == 0 {
+		return []string{""}
+	}
+	return versions
+}
+
+// versionLabel 报告中显示的版本
+func versionLabel(version string) string {
+	if version == "" {
+		return "current"
+	}
+	return "v" + version
+}
+
+// runCase 按线上处理器的流程评估一条样本
+func runCase(c Case, threshold float64) CaseResult {
+	result := CaseResult{ID: c.ID, Task: c.Task, Expected: c.Expected}
+
+	content, err := resumeContent(c)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	lang, ok := services.NormalizeLanguage(c.Language)
+	if !ok {
+		lang = services.DefaultLanguage
+	}
+
+	ctx, cancel := services.RequestContext(context.Background())
+	defer cancel()
+
+	// 累计所有调用（含格式修复重试）的令牌用量
+	var model string
+	track := func(r *services.GenerateResult, err error) (*services.GenerateResult, error) {
+		result.Calls++
+		if r != nil {
+			model = r.Model
+			result.Usage.PromptTokens += r.Usage.PromptTokens
+			result.Usage.CandidateTokens += r.Usage.CandidateTokens
+			result.Usage.TotalTokens += r.Usage.TotalTokens
+		}
+		return r, err
+	}
+
+	start := time.Now()
+	switch c.Task {
+	case taskScreening:
+		err = runScreening(ctx, c, content, lang, track, &result)
+	case taskQuestions:
+		err = runQuestions(ctx, c, content, lang, threshold, track, &result)
+	}
+	result.LatencyMS = time.Since(start).Milliseconds()
+
+	if err != nil {
+		result.ParseError = errors.Is(err, services.ErrInvalidAIResponse)
+		result.Error = err.Error()
+		log.Printf("[WARN] 样本 %s 评估失败: %v", c.ID, err)
+	}
+	if cost, ok := services.EstimateCost(model, result.Usage.PromptTokens, result.Usage.CandidateTokens); ok {
+		result.Cost = cost
+	}
+	return result
+}
+
+// resumeContent 读取样本的简历内容
+func resumeContent(c Case) ([]byte, error) {
+	if c.Resume == "" {
+		return []byte(c.ResumeText), nil
+	}
+	return os.ReadFile(c.Resume)
+}
+
+// trackFunc 记录一次模型调用的结果
+type trackFunc func(*services.GenerateResult, error) (*services.GenerateResult, error)
+
+// runScreening 与简历筛选接口相同：携带简历文件调用模型，有通过的记录即视为通过
+func runScreening(ctx context.Context, c Case, content []byte, lang string, track trackFunc, result *CaseResult) error {
+	fileName := filepath.Base(c.Resume)
+	if c.Resume == "" {
+		fileName = c.ID
+	}
+	rendered, err := services.ScreeningPrompt.RenderIn(lang, services.ScreeningPromptInput{
+		Industry:        c.Industry,
+		JobRequirements: c.JobRequirements,
+		FileName:        services.PromptFileName(fileName),
+	})
+	if err != nil {
+		return err
+	}
+	result.Version = fmt.Sprintf("v%d", rendered.Version)
+
+	provider := services.NewLLMProviderForTask(services.TaskScreening)
+	params := services.ParamsForTask(services.TaskScreening)
+	schema := services.WithResponseSchema(services.SchemaFor(models.ScreeningResponse{}))
+
+	// 与接口相同，只有PDF作为文件发送，其他格式发送提取的文本
+	format := services.ResolveResumeFormat(content, c.Resume)
+	text := ""
+	if format != services.FormatPDF {
+		if text, err = services.ExtractPlainText(ctx, content, format.MimeType()); err != nil {
+			return fmt.Errorf("提取简历文本失败: %w", err)
+		}
+	}
+	screening, _, err := services.GenerateJSON[models.ScreeningResponse]("评估 "+c.ID, rendered.Prompt, func(p string) (*services.GenerateResult, error) {
+		if text != "" {
+			return track(provider.GenerateContent(ctx, rendered.System, p+"\n\n"+services.UntrustedBlock("简历内容", text), schema, services.WithGenerationParams(params)))
+		}
+		return track(provider.GenerateContentWithBinaryFile(ctx, rendered.System, string(content), format.MimeType(), p, schema, services.WithGenerationParams(params)))
+	})
+	if err != nil {
+		return err
+	}
+
+	result.Predicted = expectFail
+	if len(screening.Passed) > 0 {
+		result.Predicted = expectPass
+	}
+	return nil
+}
+
+// runQuestions 与面试题生成接口相同：基于简历文本生成面试题，按参考题的覆盖比例评分
+func runQuestions(ctx context.Context, c Case, content []byte, lang string, threshold float64, track trackFunc, result *CaseResult) error {
+	text, err := services.ExtractPlainText(ctx, content, services.ResolveResumeFormat(content, c.Resume).MimeType())
+	if err != nil {
+		return fmt.Errorf("提取简历文本失败: %w", err)
+	}
+	rendered, err := services.QuestionsPrompt.RenderIn(lang, services.QuestionsPromptInput{
+		Industry:         c.Industry,
+		IndustryKeywords: c.IndustryKeywords,
+		JobRequirements:  c.JobRequirements,
+		ResumeContent:    text,
+	})
+	if err != nil {
+		return err
+	}
+	result.Version = fmt.Sprintf("v%d", rendered.Version)
+
+	provider := services.NewLLMProviderForTask(services.TaskQuestions)
+	params := services.ParamsForTask(services.TaskQuestions)
+	schema := services.WithResponseSchema(services.SchemaFor(models.QuestionsResponse{}))
+	questions, _, err := services.GenerateJSON[models.QuestionsResponse]("评估 "+c.ID, rendered.Prompt, func(p string) (*services.GenerateResult, error) {
+		return track(provider.GenerateContent(ctx, rendered.System, p, schema, services.WithGenerationParams(params)))
+	})
+	if err != nil {
+		return err
+	}
+
+	generated := make([]string, len(questions.Questions))
+	for i, q := range questions.Questions {
+		generated[i] = q.Question
+	}
+	result.Coverage = coverage(c.ReferenceQuestions, generated, threshold)
+	return nil
+}
+
+// coverage 参考题中至少与一道生成的题目相似度达到阈值的比例
+func coverage(references, generated []string, threshold float64) float64 {
+	if len(references) == 0 {
+		return 0
+	}
+	matched := 0
+	for _, ref := range references {
+		for _, q := range generated {
+			if similarity(ref, q) >= threshold {
+				matched++
+				break
+			}
+		}
+	}
+	return float64(matched) / float64(len(references))
+}
+
+// similarity 两段文本字符二元组的 Jaccard 相似度，不依赖分词，中英文都适用
+func similarity(a, b string) float64 {
+	x, y := bigrams(a), bigrams(b)
+	if len(x) == 0 || len(y) == 0 {
+		return 0
+	}
+	inter := 0
+	for g := range x {
+		if y[g] {
+			inter++
+		}
+	}
+	return float64(inter) / float64(len(x)+len(y)-inter)
+}
+
+// bigrams 去掉空白和标点后的字符二元组
+func bigrams(s string) map[string]bool {
+	var runes []rune
+	for _, r := range strings.ToLower(s) {
+		if strings.ContainsRune(" \t\r\n,.?!;:，。？！；：、（）()\"'“”", r) {
+			continue
+		}
+		runes = append(runes, r)
+	}
+	grams := map[string]bool{}
+	for i := 0; i+1 < len(runes); i++ {
+		grams[string(runes[i:i+2])] = true
+	}
+	return grams
+}
+
+// summarize 按任务汇总指标
+func summarize(results []CaseResult) []Summary {
+	byTask := map[string][]CaseResult{}
+	var tasks []string
+	for _, r := range results {
+		if _, ok := byTask[r.Task]; !ok {
+			tasks = append(tasks, r.Task)
+		}
+		byTask[r.Task] = append(byTask[r.Task], r)
+	}
+
+	var summaries []Summary
+	for _, task := range tasks {
+		items := byTask[task]
+		s := Summary{Task: task, Version: items[0].Version, Cases: len(items)}
+		s.Provider, s.Model = routeLabel(task)
+
+		var latencies []int64
+		var tp, fp, fn, correct, scored int
+		var coverageSum float64
+		for _, r := range items {
+			latencies = append(latencies, r.LatencyMS)
+			s.Tokens += r.Usage.TotalTokens
+			s.Cost += r.Cost
+			if r.Usage.TotalTokens > 0 && r.Cost == 0 {
+				s.Unpriced = true
+			}
+			switch {
+			case r.ParseError:
+				s.ParseFailures++
+				continue
+			case r.Error != "":
+				s.Errors++
+				continue
+			}
+
+			scored++
+			coverageSum += r.Coverage
+			if r.Predicted == r.Expected {
+				correct++
+			}
+			switch {
+			case r.Predicted == expectPass && r.Expected == expectPass:
+				tp++
+			case r.Predicted == expectPass:
+				fp++
+			case r.Expected == expectPass:
+				fn++
+			}
+		}
+
+		s.ParseFailureRate = ratio(s.ParseFailures, s.Cases)
+		// 解析失败和调用错误的样本不计入准确率等指标
+		if scored > 0 {
+			switch task {
+			case taskScreening:
+				s.Accuracy = ptr(ratio(correct, scored))
+				s.Precision = ptr(ratio(tp, tp+fp))
+				s.Recall = ptr(ratio(tp, tp+fn))
+			case taskQuestions:
+				s.Coverage = ptr(coverageSum / float64(scored))
+			}
+		}
+		s.LatencyAvgMS, s.LatencyP50MS, s.LatencyP95MS = latencyStats(latencies)
+		summaries = append(summaries, s)
+	}
+	return summaries
+}
+
+// routeLabel 任务路由链中第一项的提供方和模型
+func routeLabel(task string) (string, string) {
+	routes, err := services.RoutesForTask(services.Task(task))
+	if err != nil || len(routes) == 0 {
+		return "", ""
+	}
+	provider, model := routes[0].Provider, routes[0].Model
+	if provider == "" {
+		provider = services.ProviderVertex
+	}
+	if model == "" {
+		model = "default"
+	}
+	return provider, model
+}
+
+// ratio 计算比例，分母为0时返回0
+func ratio(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+func ptr(v float64) *float64 {
+	return &v
+}
+
+// latencyStats 返回平均值、P50 和 P95（毫秒）
+func latencyStats(latencies []int64) (avg, p50, p95 int64) {
+	if len(latencies) == 0 {
+		return 0, 0, 0
+	}
+	sorted := append([]int64(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var sum int64
+	for _, l := range sorted {
+		sum += l
+	}
+	percentile := func(p float64) int64 {
+		return sorted[int(p*float64(len(sorted)-1)+0.5)]
+	}
+	return sum / int64(len(sorted)), percentile(0.5), percentile(0.95)
+}
+
+// printTable 以表格输出汇总指标
+func printTable(w *os.File, summaries []Summary) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TASK\tVERSION\tPROVIDER\tMODEL\tCASES\tACCURACY\tPRECISION\tRECALL\tCOVERAGE\tPARSE_FAIL\tERRORS\tAVG_MS\tP50_MS\tP95_MS\tTOKENS\tCOST_USD")
+	for _, s := range summaries {
+		cost := strconv.FormatFloat(s.Cost, 'f', 4, 64)
+		if s.Unpriced {
+			cost += "*"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\t%d\t%s\n",
+			s.Task, s.Version, s.Provider, s.Model, s.Cases,
+			percent(s.Accuracy), percent(s.Precision), percent(s.Recall), percent(s.Coverage),
+			percent(&s.ParseFailureRate), s.Errors,
+			s.LatencyAvgMS, s.LatencyP50MS, s.LatencyP95MS, s.Tokens, cost)
+	}
+	tw.Flush()
+	for _, s := range summaries {
+		if s.Unpriced {
+			fmt.Fprintln(w, "* 价格表中缺少该模型，费用未计入，可通过 LLM_PRICE_TABLE_FILE 补充")
+			break
+		}
+	}
+}
+
+// percent 格式化比例，未计算时显示 -
+func percent(v *float64) string {
+	if v == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", *v*100)
+}
+
+// writeReport 写入 JSON 报告
+func writeReport(path string, report Report) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if path == "-" {
+		_, err = fmt.Fprintln(os.Stdout, string(data))
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
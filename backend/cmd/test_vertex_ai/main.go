@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -88,6 +89,7 @@ func main() {
 
 	// 创建 Vertex AI 客户端
 	vertexClient := services.NewVertexAIClient()
+	defer services.CloseVertexAI()
 
 	// 设置简单的测试提示
 	sysInstruction := "你是一个简单的测试助手。请简短回复。"
@@ -95,13 +97,13 @@ func main() {
 
 	// 尝试生成内容
 	log.Printf("尝试调用 Vertex AI...")
-	response, err := vertexClient.GenerateContent(sysInstruction, prompt)
+	result, err := vertexClient.GenerateContent(context.Background(), sysInstruction, prompt)
 	if err != nil {
 		log.Fatalf("Vertex AI 请求失败: %v", err)
 	}
 
 	// 输出成功信息
 	fmt.Printf("\n=== 测试成功! ===\n")
-	fmt.Printf("回复: %s\n", response)
+	fmt.Printf("回复: %s\n", result.Text)
 	fmt.Printf("=================\n")
 }
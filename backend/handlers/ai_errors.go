@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/GiantClam/ai-resume/services"
+	"github.com/gin-gonic/gin"
+)
+
+// statusClientClosedRequest 客户端在响应前断开连接（沿用 nginx 的 499 约定，仅用于日志）
+const statusClientClosedRequest = 499
+
+// aiError AI调用失败时返回给客户端的状态码、错误类型和提示
+type aiError struct {
+	status  int
+	kind    string
+	message string
+}
+
+// classifyAIError 根据服务层的错误分类确定返回给客户端的状态码和提示
+func classifyAIError(err error) aiError {
+	if errors.Is(err, services.ErrNoTextLayer) {
+		return aiError{http.StatusUnprocessableEntity, "no_text_layer", "PDF中没有可提取的文字（可能是扫描件），请上传带文字的PDF或Word文件"}
+	}
+	if errors.Is(err, services.ErrPDFEncrypted) {
+		return aiError{http.StatusUnprocessableEntity, "pdf_encrypted", "PDF已加密，请上传未加密的文件"}
+	}
+	if errors.Is(err, services.ErrDocumentEncrypted) {
+		return aiError{http.StatusUnprocessableEntity, "document_encrypted", "文档受密码保护，请上传未加密的文件"}
+	}
+	if errors.Is(err, services.ErrDocumentTooLarge) {
+		return aiError{http.StatusUnprocessableEntity, "document_too_large", "文档解压后的内容过大，无法提取文字"}
+	}
+	if errors.Is(err, services.ErrTruncatedResponse) {
+		return aiError{http.StatusBadGateway, "truncated", "AI输出超过长度限制，请缩短输入后重试"}
+	}
+	if errors.Is(err, services.ErrInvalidAIResponse) {
+		return aiError{http.StatusInternalServerError, "invalid_response", "无法解析AI响应"}
+	}
+
+	switch services.LLMErrorKindOf(err) {
+	case services.ErrKindQuota:
+		return aiError{http.StatusTooManyRequests, string(services.ErrKindQuota), "AI服务请求过于频繁或配额不足，请稍后重试"}
+	case services.ErrKindSafety:
+		return aiError{http.StatusUnprocessableEntity, string(services.ErrKindSafety), "内容被AI安全策略拦截，请检查简历或输入内容"}
+	case services.ErrKindBadInput:
+		return aiError{http.StatusBadRequest, string(services.ErrKindBadInput), "AI无法处理该输入，请检查文件格式或内容"}
+	case services.ErrKindCanceled:
+		return aiError{statusClientClosedRequest, string(services.ErrKindCanceled), "请求已取消"}
+	case services.ErrKindTimeout:
+		return aiError{http.StatusGatewayTimeout, string(services.ErrKindTimeout), "AI服务响应超时，请稍后重试"}
+	case services.ErrKindUnavailable, services.ErrKindEmpty:
+		return aiError{http.StatusServiceUnavailable, string(services.ErrKindUnavailable), "AI服务暂时不可用，请稍后重试"}
+	default:
+		return aiError{http.StatusInternalServerError, string(services.LLMErrorKindOf(err)), "AI生成失败"}
+	}
+}
+
+// aiErrorMessage 返回AI调用失败时给客户端的提示
+func aiErrorMessage(err error) string {
+	return classifyAIError(err).message
+}
+
+// respondAIError 按错误分类返回对应的状态码和提示
+func respondAIError(c *gin.Context, err error) {
+	e := classifyAIError(err)
+	c.JSON(e.status, gin.H{"error": e.message, "errorType": e.kind})
+}
+
+// sendStreamError 在SSE流中发送带分类的错误事件
+func sendStreamError(c *gin.Context, err error) {
+	e := classifyAIError(err)
+	data, _ := json.Marshal(gin.H{"status": "error", "message": e.message, "errorType": e.kind})
+	fmt.Fprintf(c.Writer, "data: %s\n\n", string(data))
+	c.Writer.Flush()
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"github.com/GiantClam/ai-resume/models"
+	"github.com/GiantClam/ai-resume/services"
+)
+
+// aiMeta 汇总AI结果所用的提示模板、生成参数和实际处理请求的模型，result 为空时不包含模型信息
+func aiMeta(rendered *services.RenderedPrompt, params models.GenerationParams, result *services.GenerateResult) models.AIMeta {
+	meta := models.AIMeta{
+		PromptName:    rendered.Name,
+		PromptVersion: rendered.Version,
+		Language:      rendered.Language,
+		Params:        &params,
+	}
+	if result != nil {
+		meta.Provider = result.Provider
+		meta.Model = result.Model
+		addCacheMeta(&meta, result.CacheStatus)
+	}
+	return meta
+}
+
+// addCondenseMeta 将分块提取要点的分块数和各次调用的缓存命中情况累加到元数据
+func addCondenseMeta(meta *models.AIMeta, condensed *services.CondensedText) {
+	if condensed == nil {
+		return
+	}
+	meta.Chunks += condensed.Chunks
+	for _, result := range condensed.Results {
+		addCacheMeta(meta, result.CacheStatus)
+	}
+}
+
+// addComplianceMeta 将合规检查过滤的内容和分类调用的缓存命中情况加入元数据
+func addComplianceMeta(meta *models.AIMeta, compliance *services.ComplianceResult) {
+	if compliance == nil {
+		return
+	}
+	meta.Compliance = append(meta.Compliance, compliance.Findings...)
+	for _, result := range compliance.Results {
+		addCacheMeta(meta, result.CacheStatus)
+	}
+}
+
+// addCacheMeta 将一次模型调用的缓存命中情况累加到元数据
+func addCacheMeta(meta *models.AIMeta, status services.CacheStatus) {
+	switch status {
+	case "":
+		return
+	case services.CacheHit:
+		meta.CacheHits++
+	default:
+		meta.CacheMisses++
+	}
+	if meta.Cache == "" {
+		meta.Cache = string(status)
+	} else if meta.Cache != string(status) {
+		meta.Cache = "mixed"
+	}
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/GiantClam/ai-resume/models"
+	"github.com/GiantClam/ai-resume/services"
+	"github.com/gin-gonic/gin"
+)
+
+// paramsHeader 可信调用方覆盖生成参数的请求头，格式同 LLM_PARAMS_<TASK>，例如 temperature=0.3,maxOutputTokens=2048
+const paramsHeader = "X-Generation-Params"
+
+// errParamsForbidden 调用方无权覆盖生成参数
+var errParamsForbidden = errors.New("无权覆盖生成参数")
+
+// newAIProvider 按任务路由创建提供方，并加上响应缓存和用量记录
+func newAIProvider(c *gin.Context, task services.Task, scope services.UsageScope) services.LLMProvider {
+	return services.WithUsageTracking(services.WithCache(services.NewLLMProviderForTask(task), cacheBypassed(c)), scope)
+}
+
+// providerFactory 按提示模板创建任务的提供方，辅助调用的用量与主调用归属到同一请求
+func providerFactory(c *gin.Context, task services.Task, scope services.UsageScope) services.ProviderFactory {
+	return func(prompt services.PromptRef) services.LLMProvider {
+		s := scope
+		s.Prompt = prompt
+		return newAIProvider(c, task, s)
+	}
+}
+
+// condenseInput 输入过长时先分块提取要点，返回用于最终生成的文本；要点提取调用的用量归属到同一请求
+func condenseInput(ctx context.Context, c *gin.Context, task services.Task, scope services.UsageScope, kind, text, jobRequirements string, params models.GenerationParams) (*services.CondensedText, error) {
+	return services.CondenseText(ctx, providerFactory(c, task, scope), kind, text, jobRequirements, params)
+}
+
+// cacheBypassed 请求头 Cache-Control: no-cache 或查询参数 noCache=true 时跳过响应缓存
+func cacheBypassed(c *gin.Context) bool {
+	if strings.Contains(strings.ToLower(c.GetHeader("Cache-Control")), "no-cache") {
+		return true
+	}
+	bypass, _ := strconv.ParseBool(c.Query("noCache"))
+	return bypass
+}
+
+// generationParams 返回任务配置的生成参数，LLM_PARAMS_TRUSTED_USERS 中的用户可以通过请求头在允许范围内覆盖
+func generationParams(c *gin.Context, task services.Task) (models.GenerationParams, error) {
+	params := services.ParamsForTask(task)
+
+	spec := c.GetHeader(paramsHeader)
+	if spec == "" {
+		return params, nil
+	}
+	userID, _ := c.Get("userId")
+	if id, _ := userID.(uint); !services.CanOverrideParams(id) {
+		return params, errParamsForbidden
+	}
+
+	overrides, err := services.ParseParamOverrides(spec)
+	if err != nil {
+		return params, err
+	}
+	params = overrides.Apply(params)
+	if err := services.ValidateParams(params); err != nil {
+		return params, err
+	}
+	return params, nil
+}
+
+// respondParamsError 返回生成参数错误
+func respondParamsError(c *gin.Context, err error) {
+	status := http.StatusBadRequest
+	if errors.Is(err, errParamsForbidden) {
+		status = http.StatusForbidden
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GiantClam/ai-resume/models"
+	"github.com/gin-gonic/gin"
+)
+
+// fakeLLM 使用假提供方的内置固定响应，不读写夹具
+func fakeLLM(t *testing.T) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	t.Setenv("LLM_PROVIDER", "fake")
+	t.Setenv("LLM_FAKE_MODE", "replay")
+	t.Setenv("LLM_FAKE_STRICT", "false")
+	t.Setenv("LLM_FAKE_FIXTURES_DIR", t.TempDir())
+	for _, task := range []string{"DEFAULT", "SCREENING", "QUESTIONS", "SUMMARY", "STREAM", "CHAT", "COMPLIANCE"} {
+		t.Setenv("LLM_ROUTE_"+task, "")
+	}
+}
+
+// multipartRequest 构造 multipart 表单请求，files 为 文件名 到 内容，以 fileField 字段上传
+func multipartRequest(t *testing.T, path string, fields map[string]string, fileField string, files map[string]string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for k, v := range fields {
+		w.WriteField(k, v)
+	}
+	for name, content := range files {
+		part, err := w.CreateFormFile(fileField, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write([]byte(content))
+	}
+	w.Close()
+	req := httptest.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
+
+// serve 将请求交给单个处理函数
+func serve(handler gin.HandlerFunc, req *http.Request) *httptest.ResponseRecorder {
+	r := gin.New()
+	r.POST(req.URL.Path, handler)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// decodeData 解析响应中的 data 和 meta 字段
+func decodeData[T any](t *testing.T, w *httptest.ResponseRecorder) (T, models.AIMeta) {
+	t.Helper()
+	var resp struct {
+		Data T             `json:"data"`
+		Meta models.AIMeta `json:"meta"`
+	}
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response %s: %v", w.Body.String(), err)
+	}
+	return resp.Data, resp.Meta
+}
+
+func TestScreenResumes(t *testing.T) {
+	fakeLLM(t)
+
+	t.Run("files and pasted text", func(t *testing.T) {
+		req := multipartRequest(t, "/api/resume/screen", map[string]string{
+			"jobRequirements": "3年以上Go开发经验",
+			"industry":        "互联网",
+			"resumeText":      "张三\n5年Go后端开发经验",
+		}, "resumes", map[string]string{
+			"zhangsan.txt": "张三\n熟悉分布式系统",
+			"photo.exe":    "MZ",
+		})
+		data, meta := decodeData[models.ScreeningResponse](t, serve(ScreenResumes, req))
+		if len(data.Passed) != 2 {
+			t.Fatalf("passed = %+v", data.Passed)
+		}
+		names := map[string]bool{}
+		for _, r := range data.Passed {
+			names[r.Name] = true
+		}
+		if !names["zhangsan.txt"] || !names["resumeText"] {
+			t.Errorf("passed names = %v", names)
+		}
+		if len(data.Failed) != 1 || data.Failed[0].Name != "photo.exe" {
+			t.Errorf("failed = %+v", data.Failed)
+		}
+		if meta.Provider != "fake" {
+			t.Errorf("meta.provider = %q", meta.Provider)
+		}
+	})
+
+	t.Run("missing requirements", func(t *testing.T) {
+		req := multipartRequest(t, "/api/resume/screen", map[string]string{"industry": "互联网"}, "resumes", nil)
+		if w := serve(ScreenResumes, req); w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want 400", w.Code)
+		}
+	})
+
+	t.Run("no resumes", func(t *testing.T) {
+		req := multipartRequest(t, "/api/resume/screen", map[string]string{"jobRequirements": "Go", "industry": "互联网"}, "resumes", nil)
+		if w := serve(ScreenResumes, req); w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want 400", w.Code)
+		}
+	})
+}
+
+func TestGenerateInterviewQuestions(t *testing.T) {
+	fakeLLM(t)
+
+	req := multipartRequest(t, "/api/interview/questions", map[string]string{
+		"jobRequirements": "3年以上Go开发经验",
+		"industry":        "互联网",
+	}, "resume", map[string]string{"resume.md": "# 张三\n\n- 5年Go后端开发经验"})
+	data, meta := decodeData[models.QuestionsResponse](t, serve(GenerateInterviewQuestions, req))
+	if len(data.Questions) != 2 || data.Questions[0].Question == "" {
+		t.Fatalf("questions = %+v", data.Questions)
+	}
+	if meta.PromptName == "" || meta.Provider != "fake" {
+		t.Errorf("meta = %+v", meta)
+	}
+
+	req = multipartRequest(t, "/api/interview/questions", map[string]string{"jobRequirements": "Go", "industry": "互联网"}, "resume", nil)
+	if w := serve(GenerateInterviewQuestions, req); w.Code != http.StatusBadRequest {
+		t.Errorf("without resume: status = %d, want 400", w.Code)
+	}
+}
+
+func TestSummarizeInterview(t *testing.T) {
+	fakeLLM(t)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/interview/summary", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		return serve(SummarizeInterview, req)
+	}
+
+	w := post(`{"industry":"互联网","interviewNotes":"候选人介绍了分布式缓存的设计，回答清晰。"}`)
+	data, meta := decodeData[models.SummaryResponse](t, w)
+	if data.Overall == "" || data.Recommendation == "" || len(data.Strengths) == 0 {
+		t.Fatalf("summary = %+v", data)
+	}
+	if meta.Provider != "fake" {
+		t.Errorf("meta.provider = %q", meta.Provider)
+	}
+
+	if w := post(`{"industry":"互联网"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("without notes: status = %d, want 400", w.Code)
+	}
+	if w := post(`{"industry":"互联网","interviewNotes":"记录","language":"xx"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown language: status = %d, want 400", w.Code)
+	}
+}
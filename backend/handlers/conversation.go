@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/GiantClam/ai-resume/models"
+	"github.com/GiantClam/ai-resume/services"
+	"github.com/gin-gonic/gin"
+)
+
+// 对话输入的长度上限（字符数）
+const (
+	maxChatMessageRunes  = 4000
+	maxPriorOutputsRunes = 50000
+)
+
+// CreateConversation 创建围绕一位候选人的对话，保存简历、招聘要求和此前的AI结果作为后续问答的依据
+// 表单字段: resume（简历文件）或 resumeText（粘贴的简历文本）、industry、jobRequirements、priorOutputs（可选，此前的筛选结果或面试题等）、language
+func CreateConversation(c *gin.Context) {
+	// 解析表单数据
+	err := c.Request.ParseMultipartForm(10 << 20) // 10MB max
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无法解析表单"})
+		return
+	}
+
+	jobRequirements := c.Request.FormValue("jobRequirements")
+	industry := c.Request.FormValue("industry")
+	priorOutputs := strings.TrimSpace(c.Request.FormValue("priorOutputs"))
+
+	if jobRequirements == "" || industry == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "招聘要求和行业不能为空"})
+		return
+	}
+	if utf8.RuneCountInString(priorOutputs) > maxPriorOutputsRunes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("此前的AI结果不能超过%d个字符", maxPriorOutputsRunes)})
+		return
+	}
+
+	// 输出语言，对话中的所有回答都使用该语言
+	lang, err := requestLanguage(c, "")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 对话的每一轮都会把简历放入提示，这里只保存提取出的文本
+	resumeName, resumeText, ok := readResumeText(c, lang)
+	if !ok {
+		return
+	}
+
+	conv := &models.Conversation{
+		UserID:          requestUserID(c),
+		Industry:        industry,
+		JobRequirements: jobRequirements,
+		ResumeName:      resumeName,
+		ResumeText:      resumeText,
+		PriorOutputs:    priorOutputs,
+		Language:        lang,
+	}
+	if err := services.CreateConversation(conv); err != nil {
+		respondConversationError(c, err)
+		return
+	}
+
+	log.Printf("已创建对话 %s，简历: %s", conv.ID, resumeName)
+	c.JSON(http.StatusCreated, gin.H{"data": conv})
+}
+
+// GetConversation 返回对话信息和全部消息
+func GetConversation(c *gin.Context) {
+	conv, err := services.GetConversation(c.Param("id"), requestUserID(c))
+	if err != nil {
+		respondConversationError(c, err)
+		return
+	}
+
+	messages, err := services.ConversationHistory(conv.ID, 0)
+	if err != nil {
+		respondConversationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"conversation": conv, "messages": messages}})
+}
+
+// SendConversationMessage 向对话提问，以SSE流式返回AI的回答，回答完整生成后问题和回答一起写入对话历史
+func SendConversationMessage(c *gin.Context) {
+	var req models.ChatMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+	message := strings.TrimSpace(req.Message)
+	if message == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "问题不能为空"})
+		return
+	}
+	if utf8.RuneCountInString(message) > maxChatMessageRunes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("问题不能超过%d个字符", maxChatMessageRunes)})
+		return
+	}
+
+	conv, err := services.GetConversation(c.Param("id"), requestUserID(c))
+	if err != nil {
+		respondConversationError(c, err)
+		return
+	}
+
+	// 生成参数，可信调用方可以按请求覆盖
+	params, err := generationParams(c, services.TaskChat)
+	if err != nil {
+		respondParamsError(c, err)
+		return
+	}
+
+	history, err := services.ConversationHistory(conv.ID, services.ChatHistoryLimit())
+	if err != nil {
+		respondConversationError(c, err)
+		return
+	}
+	turns := make([]services.ChatTurn, len(history))
+	for i, m := range history {
+		turns[i] = services.ChatTurn{Role: m.Role, Content: m.Content}
+	}
+
+	rendered, err := services.ChatPrompt.RenderIn(conv.Language, services.ChatPromptInput{
+		Industry:        conv.Industry,
+		JobRequirements: conv.JobRequirements,
+		ResumeContent:   conv.ResumeText,
+		PriorOutputs:    conv.PriorOutputs,
+		History:         turns,
+		Message:         message,
+	})
+	if err != nil {
+		log.Printf("渲染提示模板失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成提示失败"})
+		return
+	}
+
+	// 设置响应头，指定为SSE
+	c.Writer.Header().Set("Content-Type", "text/event-stream")
+	c.Writer.Header().Set("Cache-Control", "no-cache")
+	c.Writer.Header().Set("Connection", "keep-alive")
+	c.Writer.Header().Set("Transfer-Encoding", "chunked")
+	c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+
+	// 客户端断开连接或超过截止时间时终止流
+	ctx, cancel := services.RequestContext(c.Request.Context())
+	defer cancel()
+
+	// 通知客户端处理开始
+	fmt.Fprintf(c.Writer, "data: %s\n\n", `{"status":"processing","message":"正在生成回答..."}`)
+	c.Writer.Flush()
+
+	scope := usageScope(c, services.UsageEndpointChat, rendered.PromptRef)
+	provider := newAIProvider(c, services.TaskChat, scope)
+	iter, err := provider.GenerateContentStream(ctx, rendered.System, rendered.Prompt, services.WithGenerationParams(params))
+	if err != nil {
+		log.Printf("%s 错误: %v", provider.Name(), err)
+		sendStreamError(c, err)
+		return
+	}
+	// 提前返回时释放连接
+	defer iter.Close()
+
+	// 累积接收到的文本，发送给客户端的内容先逐句经过合规规则检查
+	var fullResponse strings.Builder
+	filter := services.NewComplianceStreamFilter()
+
+	// 处理流式响应
+	for {
+		textStr, err := iter.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Printf("流处理错误: %v", err)
+			sendStreamError(c, err)
+			return
+		}
+
+		fullResponse.WriteString(textStr)
+
+		// 每次收到新内容时发送通过检查的部分
+		sendStreamChunk(c, filter.Write(textStr))
+	}
+	sendStreamChunk(c, filter.Flush())
+
+	answer := strings.TrimSpace(fullResponse.String())
+	if answer == "" {
+		// 空回答不写入历史，也不缓存
+		services.DiscardStream(iter)
+		sendStreamError(c, services.ClassifyLLMError(provider.Name(), services.ErrEmptyResponse))
+		return
+	}
+
+	// 删除或改写涉及个人特征的内容，写入历史和完成事件中的回答以检查后的结果为准
+	compliance := services.CheckChatCompliance(ctx, providerFactory(c, services.TaskCompliance, scope), rendered.Language, &answer)
+
+	reply, err := services.AppendExchange(conv.ID, message, answer)
+	if err != nil {
+		log.Printf("保存对话 %s 的消息失败: %v", conv.ID, err)
+		data, _ := json.Marshal(gin.H{"status": "error", "message": "保存对话记录失败"})
+		fmt.Fprintf(c.Writer, "data: %s\n\n", string(data))
+		c.Writer.Flush()
+		return
+	}
+
+	// 以流的来源和缓存命中情况作为元数据
+	result := &services.GenerateResult{CacheStatus: services.StreamCacheStatus(iter)}
+	result.Provider, result.Model = services.StreamSource(iter, provider.Name(), provider.Model())
+
+	meta := aiMeta(rendered, params, result)
+	addComplianceMeta(&meta, compliance)
+
+	// 发送完成信号和保存后的回答，truncated 表示回答因达到最大输出令牌数而不完整
+	finalData, _ := json.Marshal(gin.H{
+		"status":    "complete",
+		"message":   reply,
+		"truncated": iter.FinishReason().Truncated(),
+		"meta":      meta,
+	})
+	fmt.Fprintf(c.Writer, "data: %s\n\n", string(finalData))
+	c.Writer.Flush()
+}
+
+// respondConversationError 返回对话存储错误
+func respondConversationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrConversationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrConversationStoreDisabled):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		log.Printf("读写对话记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "读写对话记录失败"})
+	}
+}
@@ -3,19 +3,15 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
-	"path/filepath"
 	"strings"
-	"time"
-	"unicode/utf8"
 
-	"cloud.google.com/go/vertexai/genai"
 	"github.com/GiantClam/ai-resume/models"
 	"github.com/GiantClam/ai-resume/services"
-	"github.com/GiantClam/ai-resume/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -38,74 +34,80 @@ func GenerateInterviewQuestions(c *gin.Context) {
 		return
 	}
 
-	// 获取简历文件
-	file, header, err := c.Request.FormFile("resume")
+	// 输出语言
+	lang, err := requestLanguage(c, "")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "请上传简历文件"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	defer file.Close()
 
-	// 检查文件类型
-	ext := filepath.Ext(header.Filename)
-	if ext != ".pdf" && ext != ".docx" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "仅支持PDF和Word文件"})
+	// 生成参数，可信调用方可以按请求覆盖
+	params, err := generationParams(c, services.TaskQuestions)
+	if err != nil {
+		respondParamsError(c, err)
 		return
 	}
 
-	// 直接从文件流中读取内容而不保存到本地
-	content, err := io.ReadAll(file)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "无法读取文件内容"})
+	// 读取上传的简历文件或粘贴的简历文本并提取文本
+	_, resumeContent, ok := readResumeText(c, lang)
+	if !ok {
 		return
 	}
 
-	// 清理文件内容中的无效UTF-8字符
-	resumeContent := sanitizeUTF8(string(content))
-
-	// 调用Vertex AI生成面试题
-	vertexClient := services.NewVertexAIClient()
-	sysInstruction, prompt := services.BuildInterviewQuestionsPrompt(jobRequirements, industry, resumeContent, industryKeywords)
+	// 客户端断开连接或超过截止时间时取消调用
+	ctx, cancel := services.RequestContext(c.Request.Context())
+	defer cancel()
 
-	response, err := vertexClient.GenerateContent(sysInstruction, prompt)
+	// 简历过长时先分块提取要点，再基于要点出题
+	scope := usageScope(c, services.UsageEndpointQuestions, services.PromptRef{})
+	condensed, err := condenseInput(ctx, c, services.TaskQuestions, scope, services.NotesKindResume, resumeContent, jobRequirements, params)
 	if err != nil {
-		log.Printf("Vertex AI错误: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "AI生成失败"})
+		log.Printf("提取简历要点失败: %v", err)
+		respondAIError(c, err)
 		return
 	}
 
-	// 清理响应中的Markdown代码块格式
-	cleanedResponse := services.CleanMarkdownCodeBlock(response)
-	log.Printf("原始响应长度: %d字节", len(response))
-	log.Printf("清理后响应长度: %d字节", len(cleanedResponse))
-	log.Printf("清理后的前100个字符: %s", cleanedResponse[:utils.Min(100, len(cleanedResponse))])
-
-	// 添加打印末尾100个字符的日志
-	if len(cleanedResponse) > 100 {
-		startIdx := utils.Max(0, len(cleanedResponse)-100)
-		log.Printf("清理后的后100个字符: %s", cleanedResponse[startIdx:])
-	} else {
-		log.Printf("清理后的后100个字符与前100个字符相同（响应过短）")
+	// 调用大模型生成面试题
+	rendered, err := services.QuestionsPrompt.RenderIn(lang, services.QuestionsPromptInput{
+		Industry:         industry,
+		IndustryKeywords: industryKeywords,
+		JobRequirements:  jobRequirements,
+		ResumeContent:    condensed.Text,
+	})
+	if err != nil {
+		log.Printf("渲染提示模板失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成提示失败"})
+		return
 	}
 
-	// 确保JSON格式完整
-	cleanedResponse = services.EnsureCompleteJSON(cleanedResponse)
+	scope.Prompt = rendered.PromptRef
+	provider := newAIProvider(c, services.TaskQuestions, scope)
 
-	// 解析AI响应
-	var questionsResult models.QuestionsResponse
-	if err := json.Unmarshal([]byte(cleanedResponse), &questionsResult); err != nil {
-		log.Printf("解析响应失败: %v, 错误类型: %T", err, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "无法解析AI响应"})
+	schema := services.WithResponseSchema(services.SchemaFor(models.QuestionsResponse{}))
+	questionsResult, result, err := services.GenerateJSON[models.QuestionsResponse]("面试题生成", rendered.Prompt, func(p string) (*services.GenerateResult, error) {
+		return provider.GenerateContent(ctx, rendered.System, p, schema, services.WithGenerationParams(params))
+	})
+	if err != nil {
+		log.Printf("%s 错误: %v", provider.Name(), err)
+		respondAIError(c, err)
 		return
 	}
+	log.Printf("%s/%s 响应长度: %d字节", result.Provider, result.Model, len(result.Text))
 
 	// 返回完整的问题列表
+	// 删除或改写涉及年龄、婚育、籍贯、民族等个人特征的题目
+	compliance := services.CheckQuestionsCompliance(ctx, providerFactory(c, services.TaskCompliance, scope), questionsResult)
+
 	finalResponse := models.QuestionsResponse{
 		Questions: questionsResult.Questions,
 	}
 
+	meta := aiMeta(rendered, params, result)
+	addCondenseMeta(&meta, condensed)
+	addComplianceMeta(&meta, compliance)
+
 	log.Printf("返回给客户端的数据: %d个问题", len(finalResponse.Questions))
-	c.JSON(http.StatusOK, gin.H{"data": finalResponse})
+	c.JSON(http.StatusOK, gin.H{"data": finalResponse, "meta": meta})
 }
 
 // SummarizeInterview 处理面试总结请求
@@ -121,44 +123,69 @@ func SummarizeInterview(c *gin.Context) {
 		return
 	}
 
-	// 调用Vertex AI生成面试总结
-	vertexClient := services.NewVertexAIClient()
-	sysInstruction, prompt := services.BuildInterviewSummaryPrompt(req.JobRequirements, req.Industry, req.InterviewNotes, req.IndustryKeywords)
+	// 输出语言
+	lang, err := requestLanguage(c, req.Language)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	response, err := vertexClient.GenerateContent(sysInstruction, prompt)
+	// 生成参数，可信调用方可以按请求覆盖
+	params, err := generationParams(c, services.TaskSummary)
 	if err != nil {
-		log.Printf("Vertex AI错误: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "AI生成失败"})
+		respondParamsError(c, err)
 		return
 	}
 
-	// 清理响应中的Markdown代码块格式
-	cleanedResponse := services.CleanMarkdownCodeBlock(response)
-	log.Printf("原始响应长度: %d字节", len(response))
-	log.Printf("清理后响应长度: %d字节", len(cleanedResponse))
-	log.Printf("清理后的前100个字符: %s", cleanedResponse[:utils.Min(100, len(cleanedResponse))])
+	// 客户端断开连接或超过截止时间时取消调用
+	ctx, cancel := services.RequestContext(c.Request.Context())
+	defer cancel()
 
-	// 添加打印末尾100个字符的日志
-	if len(cleanedResponse) > 100 {
-		startIdx := utils.Max(0, len(cleanedResponse)-100)
-		log.Printf("清理后的后100个字符: %s", cleanedResponse[startIdx:])
-	} else {
-		log.Printf("清理后的后100个字符与前100个字符相同（响应过短）")
+	// 面试记录过长时先分块提取要点，再基于要点总结
+	scope := usageScope(c, services.UsageEndpointSummary, services.PromptRef{})
+	condensed, err := condenseInput(ctx, c, services.TaskSummary, scope, services.NotesKindInterview, req.InterviewNotes, req.JobRequirements, params)
+	if err != nil {
+		log.Printf("提取面试记录要点失败: %v", err)
+		respondAIError(c, err)
+		return
+	}
+
+	// 调用大模型生成面试总结
+	rendered, err := services.SummaryPrompt.RenderIn(lang, services.SummaryPromptInput{
+		Industry:         req.Industry,
+		IndustryKeywords: req.IndustryKeywords,
+		JobRequirements:  req.JobRequirements,
+		InterviewNotes:   condensed.Text,
+	})
+	if err != nil {
+		log.Printf("渲染提示模板失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成提示失败"})
+		return
 	}
 
-	// 确保JSON格式完整
-	cleanedResponse = services.EnsureCompleteJSON(cleanedResponse)
+	scope.Prompt = rendered.PromptRef
+	provider := newAIProvider(c, services.TaskSummary, scope)
 
-	// 解析AI响应
-	var summaryResult models.SummaryResponse
-	if err := json.Unmarshal([]byte(cleanedResponse), &summaryResult); err != nil {
-		log.Printf("解析响应失败: %v, 错误类型: %T", err, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "无法解析AI响应"})
+	schema := services.WithResponseSchema(services.SchemaFor(models.SummaryResponse{}))
+	summaryResult, result, err := services.GenerateJSON[models.SummaryResponse]("面试总结", rendered.Prompt, func(p string) (*services.GenerateResult, error) {
+		return provider.GenerateContent(ctx, rendered.System, p, schema, services.WithGenerationParams(params))
+	})
+	if err != nil {
+		log.Printf("%s 错误: %v", provider.Name(), err)
+		respondAIError(c, err)
 		return
 	}
+	log.Printf("%s/%s 响应长度: %d字节", result.Provider, result.Model, len(result.Text))
+
+	// 删除或改写依据年龄、婚育、籍贯、民族等个人特征的评价
+	compliance := services.CheckSummaryCompliance(ctx, providerFactory(c, services.TaskCompliance, scope), lang, summaryResult)
+
+	meta := aiMeta(rendered, params, result)
+	addCondenseMeta(&meta, condensed)
+	addComplianceMeta(&meta, compliance)
 
 	log.Printf("返回给客户端的数据: %+v", summaryResult)
-	c.JSON(http.StatusOK, gin.H{"data": summaryResult})
+	c.JSON(http.StatusOK, gin.H{"data": summaryResult, "meta": meta})
 }
 
 // handleStreamRequest 处理SSE流式请求
@@ -238,31 +265,26 @@ func StreamGenerateInterviewQuestions(c *gin.Context) {
 		return
 	}
 
-	// 获取简历文件
-	file, header, err := c.Request.FormFile("resume")
+	// 输出语言
+	lang, err := requestLanguage(c, "")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "请上传简历文件"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	defer file.Close()
 
-	// 检查文件类型
-	ext := filepath.Ext(header.Filename)
-	if ext != ".pdf" && ext != ".docx" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "仅支持PDF和Word文件"})
+	// 生成参数，可信调用方可以按请求覆盖
+	params, err := generationParams(c, services.TaskStream)
+	if err != nil {
+		respondParamsError(c, err)
 		return
 	}
 
-	// 直接从文件流中读取内容而不保存到本地
-	content, err := io.ReadAll(file)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "无法读取文件内容"})
+	// 读取上传的简历文件或粘贴的简历文本并提取文本
+	_, resumeContent, ok := readResumeText(c, lang)
+	if !ok {
 		return
 	}
 
-	// 清理文件内容中的无效UTF-8字符
-	resumeContent := sanitizeUTF8(string(content))
-
 	// 设置响应头，指定为SSE
 	c.Writer.Header().Set("Content-Type", "text/event-stream")
 	c.Writer.Header().Set("Cache-Control", "no-cache")
@@ -270,39 +292,56 @@ func StreamGenerateInterviewQuestions(c *gin.Context) {
 	c.Writer.Header().Set("Transfer-Encoding", "chunked")
 	c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 
-	// 创建一个上下文，允许请求取消时终止流
-	ctx, cancel := context.WithCancel(context.Background())
+	// 客户端断开连接或超过截止时间时终止流
+	ctx, cancel := services.RequestContext(c.Request.Context())
 	defer cancel()
 
-	// 处理客户端断开连接
-	go func() {
-		<-c.Request.Context().Done()
-		cancel()
-	}()
-
-	// 使用ctx在模拟延迟期间检查取消
-	ticker := time.NewTicker(100 * time.Millisecond)
-	defer ticker.Stop()
-
 	// 通知客户端处理开始
 	fmt.Fprintf(c.Writer, "data: %s\n\n", `{"status":"processing","message":"正在处理简历和生成问题..."}`)
 	c.Writer.Flush()
 
-	// 调用Vertex AI生成面试题
-	vertexClient := services.NewVertexAIClient()
-	sysInstruction, prompt := services.BuildInterviewQuestionsPrompt(jobRequirements, industry, resumeContent, industryKeywords)
+	// 简历过长时先分块提取要点，再基于要点出题
+	scope := usageScope(c, services.UsageEndpointQuestionsStream, services.PromptRef{})
+	if services.NeedsCondensing(resumeContent) {
+		fmt.Fprintf(c.Writer, "data: %s\n\n", `{"status":"condensing","message":"简历较长，正在分段提取要点..."}`)
+		c.Writer.Flush()
+	}
+	condensed, err := condenseInput(ctx, c, services.TaskStream, scope, services.NotesKindResume, resumeContent, jobRequirements, params)
+	if err != nil {
+		log.Printf("提取简历要点失败: %v", err)
+		sendStreamError(c, err)
+		return
+	}
+
+	// 调用大模型生成面试题
+	rendered, err := services.QuestionsPrompt.RenderIn(lang, services.QuestionsPromptInput{
+		Industry:         industry,
+		IndustryKeywords: industryKeywords,
+		JobRequirements:  jobRequirements,
+		ResumeContent:    condensed.Text,
+	})
+	if err != nil {
+		log.Printf("渲染提示模板失败: %v", err)
+		sendStreamError(c, err)
+		return
+	}
+	scope.Prompt = rendered.PromptRef
+	provider := newAIProvider(c, services.TaskStream, scope)
 
 	// 获取流式响应
-	iter, err := vertexClient.GenerateContentStream(ctx, sysInstruction, prompt)
+	schema := services.WithResponseSchema(services.SchemaFor(models.QuestionsResponse{}))
+	iter, err := provider.GenerateContentStream(ctx, rendered.System, rendered.Prompt, schema, services.WithGenerationParams(params))
 	if err != nil {
-		log.Printf("Vertex AI错误: %v", err)
-		fmt.Fprintf(c.Writer, "data: %s\n\n", `{"status":"error","message":"AI生成失败"}`)
-		c.Writer.Flush()
+		log.Printf("%s 错误: %v", provider.Name(), err)
+		sendStreamError(c, err)
 		return
 	}
+	// 提前返回时释放连接
+	defer iter.Close()
 
-	// 累积接收到的文本
+	// 累积接收到的文本，发送给客户端的内容先逐句经过合规规则检查
 	var fullResponse strings.Builder
+	filter := services.NewComplianceStreamFilter()
 
 	// 给客户端发送预备消息
 	fmt.Fprintf(c.Writer, "data: %s\n\n", `{"status":"generating","message":"正在生成面试问题..."}`)
@@ -310,78 +349,82 @@ func StreamGenerateInterviewQuestions(c *gin.Context) {
 
 	// 处理流式响应
 	for {
-		resp, err := iter.Next()
+		textStr, err := iter.Next()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
 			log.Printf("流处理错误: %v", err)
-			fmt.Fprintf(c.Writer, "data: %s\n\n", `{"status":"error","message":"处理AI响应流时出错"}`)
-			c.Writer.Flush()
+			sendStreamError(c, err)
 			return
 		}
 
-		// 提取响应内容
-		for _, candidate := range resp.Candidates {
-			for _, part := range candidate.Content.Parts {
-				if text, ok := part.(genai.Text); ok {
-					textStr := string(text)
-					fullResponse.WriteString(textStr)
-
-					// 每次收到新内容时发送更新
-					fmt.Fprintf(c.Writer, "data: %s\n\n", fmt.Sprintf(`{"status":"chunk","content":%q}`, textStr))
-					c.Writer.Flush()
-
-					// 给客户端一点时间处理
-					time.Sleep(10 * time.Millisecond)
-				}
-			}
-		}
+		fullResponse.WriteString(textStr)
+
+		// 每次收到新内容时发送通过检查的部分
+		sendStreamChunk(c, filter.Write(textStr))
 	}
+	sendStreamChunk(c, filter.Flush())
 
-	// 清理和处理最终响应
+	// 解析并校验最终响应，模型报告输出被截断时不再解析
 	finalResponse := fullResponse.String()
-	cleanedResponse := services.CleanMarkdownCodeBlock(finalResponse)
+	var repairResult *services.GenerateResult
+	var questionsResult *models.QuestionsResponse
+	if iter.FinishReason().Truncated() {
+		err = services.ErrTruncatedResponse
+	} else {
+		questionsResult, err = services.DecodeJSON[models.QuestionsResponse](finalResponse)
+	}
+	if err != nil {
+		// 流式输出无效时，携带错误信息以非流式方式重新请求
+		log.Printf("流式响应无效: %v，重新请求", err)
+		fmt.Fprintf(c.Writer, "data: %s\n\n", `{"status":"repairing","message":"AI响应格式有误，正在重新生成..."}`)
+		c.Writer.Flush()
 
-	// 确保JSON格式完整
-	cleanedResponse = services.EnsureCompleteJSON(cleanedResponse)
+		services.DiscardStream(iter)
+		repairPrompt := services.BuildRepairPrompt(rendered.Prompt, finalResponse, err)
+		if errors.Is(err, services.ErrTruncatedResponse) {
+			repairPrompt = services.BuildTruncationPrompt(rendered.Prompt)
+		}
+		questionsResult, repairResult, err = services.GenerateJSON[models.QuestionsResponse]("面试题流式生成修复", repairPrompt, func(p string) (*services.GenerateResult, error) {
+			return provider.GenerateContent(ctx, rendered.System, p, schema, services.WithGenerationParams(params))
+		})
+		if err != nil {
+			log.Printf("解析响应失败: %v", err)
+			sendStreamError(c, err)
+			return
+		}
+	}
 
-	// 解析JSON响应
-	var questionsResult models.QuestionsResponse
-	if err := json.Unmarshal([]byte(cleanedResponse), &questionsResult); err != nil {
-		log.Printf("解析响应失败: %v", err)
-		fmt.Fprintf(c.Writer, "data: %s\n\n", `{"status":"error","message":"无法解析AI生成的问题"}`)
-		c.Writer.Flush()
-		return
+	// 流式输出有效时以流的来源和缓存命中情况作为元数据
+	if repairResult == nil {
+		repairResult = &services.GenerateResult{CacheStatus: services.StreamCacheStatus(iter)}
+		repairResult.Provider, repairResult.Model = services.StreamSource(iter, provider.Name(), provider.Model())
 	}
 
+	// 删除或改写涉及个人特征的题目，完成事件中的问题列表以检查后的结果为准
+	compliance := services.CheckQuestionsCompliance(ctx, providerFactory(c, services.TaskCompliance, scope), questionsResult)
+
+	meta := aiMeta(rendered, params, repairResult)
+	addCondenseMeta(&meta, condensed)
+	addComplianceMeta(&meta, compliance)
+
 	// 发送完成信号和最终的问题列表
 	finalData, _ := json.Marshal(gin.H{
 		"status":    "complete",
 		"questions": questionsResult.Questions,
+		"meta":      meta,
 	})
 	fmt.Fprintf(c.Writer, "data: %s\n\n", string(finalData))
 	c.Writer.Flush()
 }
 
-// 清理UTF-8字符串
-func sanitizeUTF8(s string) string {
-	if utf8.ValidString(s) {
-		return s
-	}
-
-	// 创建一个新的字符串构建器
-	var builder strings.Builder
-	builder.Grow(len(s))
-
-	// 遍历字符串，只保留有效的UTF-8字符
-	for i := 0; i < len(s); {
-		r, size := utf8.DecodeRuneInString(s[i:])
-		if r != utf8.RuneError || size == 1 {
-			builder.WriteRune(r)
-		}
-		i += size
+// sendStreamChunk 在SSE流中发送一段输出，内容为空时不发送
+func sendStreamChunk(c *gin.Context, content string) {
+	if content == "" {
+		return
 	}
-
-	return builder.String()
+	data, _ := json.Marshal(gin.H{"status": "chunk", "content": content})
+	fmt.Fprintf(c.Writer, "data: %s\n\n", string(data))
+	c.Writer.Flush()
 }
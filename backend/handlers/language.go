@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"github.com/GiantClam/ai-resume/services"
+	"github.com/gin-gonic/gin"
+)
+
+// requestLanguage 确定AI结果的输出语言：请求体中的 language 字段优先，其次是 language 表单字段或查询参数，最后是 Accept-Language 请求头
+func requestLanguage(c *gin.Context, bodyLanguage string) (string, error) {
+	param := bodyLanguage
+	if param == "" {
+		param = c.Request.FormValue("language")
+	}
+	return services.ResolveLanguage(param, c.GetHeader("Accept-Language"))
+}
+
+// resultMessages 由服务端生成、写入AI结果的提示文案，以中文原文为键
+var resultMessages = map[string]map[string]string{
+	services.LangEn: {
+		"不支持的文件类型，支持的格式: ":       "Unsupported file type. Accepted formats: ",
+		"文件读取失败":                 "Failed to read the file",
+		"无法从简历中提取文本":             "Unable to extract text from the resume",
+		"简历解析失败":                 "Failed to parse the resume analysis",
+		"AI分析失败: ":               "AI analysis failed: ",
+		"筛选超时，未处理":               "Not processed: screening timed out",
+		"无法解析AI响应":               "Unable to parse the AI response",
+		"AI输出超过长度限制，请缩短输入后重试":    "The AI output exceeded the length limit. Please shorten the input and try again",
+		"AI服务请求过于频繁或配额不足，请稍后重试":  "The AI service is rate limited or out of quota. Please try again later",
+		"内容被AI安全策略拦截，请检查简历或输入内容": "The content was blocked by the AI safety policy. Please check the resume or input",
+		"AI无法处理该输入，请检查文件格式或内容":   "The AI could not process this input. Please check the file format or content",
+		"请求已取消":                  "The request was canceled",
+		"AI服务响应超时，请稍后重试":         "The AI service timed out. Please try again later",
+		"AI服务暂时不可用，请稍后重试":        "The AI service is temporarily unavailable. Please try again later",
+		"AI生成失败":                 "AI generation failed",
+		"PDF中没有可提取的文字（可能是扫描件），请上传带文字的PDF或Word文件": "The PDF has no extractable text (it may be a scan). Please upload a text-based PDF or a Word file",
+		"PDF已加密，请上传未加密的文件":                       "The PDF is encrypted. Please upload an unencrypted file",
+		"文档受密码保护，请上传未加密的文件":                      "The document is password protected. Please upload an unencrypted file",
+		"文档解压后的内容过大，无法提取文字":                      "The document is too large after decompression to extract text",
+	},
+	services.LangJa: {
+		"不支持的文件类型，支持的格式: ":       "サポートされていないファイル形式です。対応形式: ",
+		"文件读取失败":                 "ファイルの読み込みに失敗しました",
+		"无法从简历中提取文本":             "履歴書からテキストを抽出できませんでした",
+		"简历解析失败":                 "履歴書の分析結果を解析できませんでした",
+		"AI分析失败: ":               "AI分析に失敗しました: ",
+		"筛选超时，未处理":               "選考がタイムアウトしたため未処理です",
+		"无法解析AI响应":               "AIの応答を解析できませんでした",
+		"AI输出超过长度限制，请缩短输入后重试":    "AIの出力が長さの上限を超えました。入力を短くして再試行してください",
+		"AI服务请求过于频繁或配额不足，请稍后重试":  "AIサービスへのリクエストが多すぎるか、割り当てが不足しています。しばらくしてから再試行してください",
+		"内容被AI安全策略拦截，请检查简历或输入内容": "AIの安全ポリシーによりブロックされました。履歴書または入力内容を確認してください",
+		"AI无法处理该输入，请检查文件格式或内容":   "AIがこの入力を処理できませんでした。ファイル形式または内容を確認してください",
+		"请求已取消":                  "リクエストはキャンセルされました",
+		"AI服务响应超时，请稍后重试":         "AIサービスの応答がタイムアウトしました。しばらくしてから再試行してください",
+		"AI服务暂时不可用，请稍后重试":        "AIサービスは一時的に利用できません。しばらくしてから再試行してください",
+		"AI生成失败":                 "AI生成に失敗しました",
+		"PDF中没有可提取的文字（可能是扫描件），请上传带文字的PDF或Word文件": "PDFに抽出できるテキストがありません（スキャンの可能性があります）。テキストを含むPDFまたはWordファイルをアップロードしてください",
+		"PDF已加密，请上传未加密的文件":                       "PDFが暗号化されています。暗号化されていないファイルをアップロードしてください",
+		"文档受密码保护，请上传未加密的文件":                      "文書はパスワードで保護されています。暗号化されていないファイルをアップロードしてください",
+		"文档解压后的内容过大，无法提取文字":                      "文書の展開後のサイズが大きすぎるため、テキストを抽出できません",
+	},
+}
+
+// localize 返回提示文案在指定语言中的译文，没有译文时返回中文原文
+func localize(lang, message string) string {
+	if translated, ok := resultMessages[lang][message]; ok {
+		return translated
+	}
+	return message
+}
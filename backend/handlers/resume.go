@@ -1,10 +1,12 @@
 package handlers
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"mime/multipart"
 	"net/http"
 	"path/filepath"
 	"strings"
@@ -31,15 +33,29 @@ func ScreenResumes(c *gin.Context) {
 		return
 	}
 
-	// 处理上传的简历文件
+	// 输出语言，筛选理由和提示均使用该语言
+	lang, err := requestLanguage(c, "")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 生成参数，可信调用方可以按请求覆盖
+	params, err := generationParams(c, services.TaskScreening)
+	if err != nil {
+		respondParamsError(c, err)
+		return
+	}
+
+	// 处理上传的简历文件和粘贴的简历文本
 	form, _ := c.MultipartForm()
-	files := form.File["resumes"]
-	if len(files) == 0 {
+	inputs := screeningInputs(form)
+	if len(inputs) == 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "请上传至少一份简历"})
 		return
 	}
 
-	log.Printf("开始处理 %d 份简历", len(files))
+	log.Printf("开始处理 %d 份简历", len(inputs))
 
 	// 最终结果
 	allResults := models.ScreeningResponse{
@@ -47,155 +63,301 @@ func ScreenResumes(c *gin.Context) {
 		Failed: []models.ResumeResult{},
 	}
 
-	// 逐个处理每个简历文件
-	for i, file := range files {
-		log.Printf("处理简历 %d/%d: %s", i+1, len(files), file.Filename)
+	// 客户端断开连接或超过截止时间时取消进行中的调用，并停止处理剩余的简历
+	ctx, cancel := services.RequestContext(c.Request.Context())
+	defer cancel()
 
-		// 检查文件类型
-		ext := strings.ToLower(filepath.Ext(file.Filename))
-		if ext != ".pdf" && ext != ".docx" && ext != ".doc" {
-			log.Printf("不支持的文件类型: %s", ext)
-			allResults.Failed = append(allResults.Failed, models.ResumeResult{
-				Name:   file.Filename,
-				Reason: "不支持的文件类型，仅支持PDF和Word文件",
-			})
-			continue
+	// 同一批简历的用量归属到同一个请求ID
+	scope := usageScope(c, services.UsageEndpointScreen, services.PromptRef{})
+	var meta models.AIMeta
+
+	// 逐个处理每份简历
+	for i, input := range inputs {
+		name := input.name
+		if err := ctx.Err(); err != nil {
+			if c.Request.Context().Err() != nil {
+				log.Printf("客户端已断开连接，停止筛选，剩余 %d 份简历未处理", len(inputs)-i)
+				return
+			}
+			// 超过截止时间时返回已完成的结果，剩余简历标记为未处理
+			log.Printf("筛选超时，剩余 %d 份简历未处理", len(inputs)-i)
+			for _, rest := range inputs[i:] {
+				allResults.Failed = append(allResults.Failed, models.ResumeResult{
+					Name:   rest.name,
+					Reason: localize(lang, "筛选超时，未处理"),
+				})
+			}
+			break
 		}
+		log.Printf("处理简历 %d/%d: %s", i+1, len(inputs), name)
 
-		// 打开文件流
-		src, err := file.Open()
-		if err != nil {
-			log.Printf("无法打开文件 %s: %v", file.Filename, err)
+		// 检查文件类型
+		if input.file != nil && !services.IsSupportedResumeFile(name) {
+			log.Printf("不支持的文件类型: %s", filepath.Ext(name))
 			allResults.Failed = append(allResults.Failed, models.ResumeResult{
-				Name:   file.Filename,
-				Reason: "文件读取失败",
+				Name:   name,
+				Reason: unsupportedFileMessage(lang),
 			})
 			continue
 		}
-		defer src.Close()
 
 		// 直接从文件流读取内容
-		content, err := io.ReadAll(src)
+		content, err := input.read()
 		if err != nil {
-			log.Printf("无法读取文件 %s: %v", file.Filename, err)
+			log.Printf("无法读取文件 %s: %v", name, err)
 			allResults.Failed = append(allResults.Failed, models.ResumeResult{
-				Name:   file.Filename,
-				Reason: "文件读取失败",
+				Name:   name,
+				Reason: localize(lang, "文件读取失败"),
 			})
 			continue
 		}
 
-		// 确定MIME类型
-		mimeType := http.DetectContentType(content)
-		log.Printf("文件: %s, MIME类型: %s", file.Filename, mimeType)
+		// 按文件内容确定格式和MIME类型，无法识别时按扩展名，粘贴的文本按纯文本（或HTML）处理
+		format := services.ResolveResumeFormat(content, name)
+		mimeType := format.MimeType()
+		log.Printf("文件: %s, MIME类型: %s", name, mimeType)
 
-		// 创建系统指令和提示
-		systemInstruction := fmt.Sprintf(`
-			你是一个%s行业的高级招聘专家，精通人才筛选。请基于以下招聘要求和行业，评估简历。
-
-			招聘要求:
-			%s
-
-			请分析我提供的简历文件，判断它是否符合招聘要求，并简要说明你的判断理由。
-			
-			请以下面的JSON格式回复:
-			{
-			"passed": [
-				{"name": "%s", "reason": "通过原因"}
-			],
-			"failed": [
-				{"name": "%s", "reason": "不通过原因"}
-			]
+		// 扫描简历中试图影响评估结果的指令性文本，命中时在结果中提示人工复核
+		warning := ""
+		text, textErr := services.ExtractPlainText(ctx, content, mimeType)
+		if textErr == nil {
+			findings := services.DetectInjection(text)
+			warning = services.InjectionWarning(findings, lang)
+			if warning != "" {
+				log.Printf("[WARN] 简历 %s 疑似包含提示注入: %+v", name, findings)
 			}
+		} else {
+			log.Printf("[DEBUG] 无法提取简历 %s 的文本，跳过提示注入扫描: %v", name, textErr)
+		}
 
-			注意：简历只能出现在passed或failed其中一个数组中，不能同时出现在两个数组中。请直接返回JSON，不要使用Markdown代码块，不要添加任何额外的解释。
-		`, industry, jobRequirements, file.Filename, file.Filename)
+		// 创建系统指令和提示
+		rendered, err := services.ScreeningPrompt.RenderIn(lang, services.ScreeningPromptInput{
+			Industry:        industry,
+			JobRequirements: jobRequirements,
+			FileName:        services.PromptFileName(name),
+		})
+		if err != nil {
+			log.Printf("渲染提示模板失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "生成提示失败"})
+			return
+		}
+		meta = accumulateMeta(meta, aiMeta(rendered, params, nil))
 
-		// 文本提示
-		textPrompt := fmt.Sprintf("请分析这份简历是否满足以下职位要求：%s", jobRequirements)
+		// 只有PDF作为文件发送给模型，Word、RTF、ODT、纯文本、Markdown、HTML及粘贴的文本改为发送提取并规范化后的文本
+		inlineLabel, inlineText := "", ""
+		if format != services.FormatPDF {
+			if textErr != nil {
+				log.Printf("提取简历 %s 的文本失败: %v", name, textErr)
+				allResults.Failed = append(allResults.Failed, models.ResumeResult{
+					Name:   name,
+					Reason: localize(lang, "无法从简历中提取文本"),
+				})
+				continue
+			}
+			inlineLabel, inlineText = "简历内容", text
+		}
 
-		// 调用Vertex AI分析当前简历文件
-		vertexClient := services.NewVertexAIClient()
-		log.Printf("开始AI分析简历文件: %s", file.Filename)
+		// 简历文本过长时先分块提取要点，再基于要点筛选，不再上传原文件
+		if textErr == nil && services.NeedsCondensing(text) {
+			var condensed *services.CondensedText
+			condensed, err = condenseInput(ctx, c, services.TaskScreening, scope, services.NotesKindResume, text, jobRequirements, params)
+			addCondenseMeta(&meta, condensed)
+			if err == nil {
+				inlineLabel, inlineText = "简历要点", condensed.Text
+			}
+		}
 
-		response, err := vertexClient.GenerateContentWithBinaryFile(systemInstruction, string(content), mimeType, textPrompt)
-		if err != nil {
-			log.Printf("分析简历 %s 时出错: %v", file.Filename, err)
+		// 调用大模型分析当前简历文件
+		scope.Prompt = rendered.PromptRef
+		provider := newAIProvider(c, services.TaskScreening, scope)
+		var screeningResult *models.ScreeningResponse
+		var result *services.GenerateResult
+		if err == nil {
+			log.Printf("开始AI分析简历文件: %s (提供方: %s, 模型: %s)", name, provider.Name(), provider.Model())
+			schema := services.WithResponseSchema(services.SchemaFor(models.ScreeningResponse{}))
+			screeningResult, result, err = services.GenerateJSON[models.ScreeningResponse]("简历筛选 "+name, rendered.Prompt, func(p string) (*services.GenerateResult, error) {
+				if inlineText != "" {
+					return provider.GenerateContent(ctx, rendered.System, p+"\n\n"+services.UntrustedBlock(inlineLabel, inlineText), schema, services.WithGenerationParams(params))
+				}
+				return provider.GenerateContentWithBinaryFile(ctx, rendered.System, string(content), mimeType, p, schema, services.WithGenerationParams(params))
+			})
+		}
+		if errors.Is(err, services.ErrInvalidAIResponse) {
+			log.Printf("解析简历 %s 的响应失败: %v", name, err)
 			// 将该简历标记为失败，但继续处理其他简历
 			allResults.Failed = append(allResults.Failed, models.ResumeResult{
-				Name:   file.Filename,
-				Reason: fmt.Sprintf("AI分析失败: %v", err),
+				Name:    name,
+				Reason:  localize(lang, "简历解析失败"),
+				Warning: warning,
 			})
 			continue
 		}
-
-		// 清理响应中的Markdown代码块格式
-		cleanedResponse := services.CleanMarkdownCodeBlock(response)
-		log.Printf("简历 %s 分析完成，响应长度: %d字节", file.Filename, len(cleanedResponse))
-
-		// 确保JSON格式完整
-		cleanedResponse = services.EnsureCompleteJSON(cleanedResponse)
-
-		// 解析当前简历的AI响应
-		var screeningResult models.ScreeningResponse
-		if err := json.Unmarshal([]byte(cleanedResponse), &screeningResult); err != nil {
-			log.Printf("解析简历 %s 的响应失败: %v", file.Filename, err)
+		if err != nil {
+			log.Printf("分析简历 %s 时出错: %v", name, err)
+			// 配额耗尽或凭证无效时后续简历也无法分析，直接返回错误
+			if kind := services.LLMErrorKindOf(err); kind == services.ErrKindQuota || kind == services.ErrKindAuth {
+				respondAIError(c, err)
+				return
+			}
 			// 将该简历标记为失败，但继续处理其他简历
 			allResults.Failed = append(allResults.Failed, models.ResumeResult{
-				Name:   file.Filename,
-				Reason: "简历解析失败",
+				Name:    name,
+				Reason:  localize(lang, "AI分析失败: ") + localize(lang, aiErrorMessage(err)),
+				Warning: warning,
 			})
 			continue
 		}
+		log.Printf("简历 %s 分析完成，响应长度: %d字节", name, len(result.Text))
+		meta = accumulateMeta(meta, aiMeta(rendered, params, result))
 
-		// 确保返回的结果使用正确的文件名
+		// 每次只分析一份简历，结果统一使用原文件名（系统指令中只有不含文件名的占位名称）
 		for i := range screeningResult.Passed {
-			if screeningResult.Passed[i].Name == "简历文件名" || screeningResult.Passed[i].Name == "" {
-				screeningResult.Passed[i].Name = file.Filename
-			}
+			screeningResult.Passed[i].Name = name
 		}
-
 		for i := range screeningResult.Failed {
-			if screeningResult.Failed[i].Name == "简历文件名" || screeningResult.Failed[i].Name == "" {
-				screeningResult.Failed[i].Name = file.Filename
+			screeningResult.Failed[i].Name = name
+		}
+
+		if warning != "" {
+			for i := range screeningResult.Passed {
+				screeningResult.Passed[i].Warning = warning
+			}
+			for i := range screeningResult.Failed {
+				screeningResult.Failed[i].Warning = warning
 			}
 		}
 
-		// 如果AI没有提供任何结果，默认将该简历标记为通过
-		if len(screeningResult.Passed) == 0 && len(screeningResult.Failed) == 0 {
-			log.Printf("简历 %s 没有明确结果，默认标记为通过", file.Filename)
-			allResults.Passed = append(allResults.Passed, models.ResumeResult{
-				Name:   file.Filename,
-				Reason: "简历符合基本要求",
-			})
-		} else {
-			// 合并结果
-			passedCount := len(screeningResult.Passed)
-			failedCount := len(screeningResult.Failed)
-			log.Printf("简历 %s 分析结果: 通过 %d 条, 未通过 %d 条", file.Filename, passedCount, failedCount)
+		// 合并结果
+		passedCount := len(screeningResult.Passed)
+		failedCount := len(screeningResult.Failed)
+		log.Printf("简历 %s 分析结果: 通过 %d 条, 未通过 %d 条", name, passedCount, failedCount)
 
-			allResults.Passed = append(allResults.Passed, screeningResult.Passed...)
-			allResults.Failed = append(allResults.Failed, screeningResult.Failed...)
-		}
+		allResults.Passed = append(allResults.Passed, screeningResult.Passed...)
+		allResults.Failed = append(allResults.Failed, screeningResult.Failed...)
 	}
 
 	totalPassed := len(allResults.Passed)
 	totalFailed := len(allResults.Failed)
-	log.Printf("简历筛选完成: 共分析 %d 份简历, 通过 %d 份, 不通过 %d 份", len(files), totalPassed, totalFailed)
+	log.Printf("简历筛选完成: 共分析 %d 份简历, 通过 %d 份, 不通过 %d 份", len(inputs), totalPassed, totalFailed)
+
+	c.JSON(http.StatusOK, gin.H{"data": allResults, "meta": meta})
+}
+
+// accumulateMeta 批量筛选的缓存命中情况和分块数按所有简历累计，其余字段使用 next
+func accumulateMeta(prev, next models.AIMeta) models.AIMeta {
+	next.CacheHits += prev.CacheHits
+	next.CacheMisses += prev.CacheMisses
+	next.Chunks += prev.Chunks
+	if prev.Cache != "" && prev.Cache != next.Cache {
+		if next.Cache == "" {
+			next.Cache = prev.Cache
+		} else {
+			next.Cache = "mixed"
+		}
+	}
+	return next
+}
 
-	c.JSON(http.StatusOK, gin.H{"data": allResults})
+// resumeTextField 粘贴简历文本使用的表单字段
+const resumeTextField = "resumeText"
+
+// extractTextFromFile 从简历文件中提取文本，格式按文件内容和扩展名确定，PDF没有文本层时返回 services.ErrNoTextLayer
+func extractTextFromFile(ctx context.Context, content []byte, filename string) (string, error) {
+	return services.ExtractPlainText(ctx, content, services.ResolveResumeFormat(content, filename).MimeType())
 }
 
-// extractTextFromFile 从PDF或Word文件中提取文本
-func extractTextFromFile(file io.Reader, fileType string) (string, error) {
-	// 实际应用中，您需要使用专门的库来解析PDF和Word文件
-	// 这里只是简单地读取文件内容
-	content, err := io.ReadAll(file)
+// readResumeText 读取单份简历的文本：优先使用上传的 resume 文件，没有文件时使用 resumeText 字段中粘贴的文本
+// 返回简历名称和提取的文本，失败时已写入错误响应
+func readResumeText(c *gin.Context, lang string) (name, text string, ok bool) {
+	var content []byte
+	file, header, err := c.Request.FormFile("resume")
+	if err == nil {
+		defer file.Close()
+
+		// 检查文件类型
+		if !services.IsSupportedResumeFile(header.Filename) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": unsupportedFileMessage(lang)})
+			return "", "", false
+		}
+
+		// 直接从文件流中读取内容而不保存到本地
+		content, err = io.ReadAll(file)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "无法读取文件内容"})
+			return "", "", false
+		}
+		name = header.Filename
+	} else {
+		pasted := c.Request.FormValue(resumeTextField)
+		if strings.TrimSpace(pasted) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "请上传简历文件或填写简历文本"})
+			return "", "", false
+		}
+		content, name = []byte(pasted), resumeTextField
+	}
+
+	text, err = extractTextFromFile(c.Request.Context(), content, name)
 	if err != nil {
-		return "", err
+		log.Printf("提取简历 %s 的文本失败: %v", name, err)
+		respondExtractError(c, err)
+		return "", "", false
 	}
+	return name, text, true
+}
+
+// screeningInput 待筛选的一份简历，来自上传的文件或粘贴的文本
+type screeningInput struct {
+	name string
+	file *multipart.FileHeader
+	text string
+}
+
+// screeningInputs 收集 resumes 字段上传的文件和 resumeText 字段粘贴的文本
+// 粘贴了多份文本时依次命名为 resumeText#1、resumeText#2……
+func screeningInputs(form *multipart.Form) []screeningInput {
+	var inputs []screeningInput
+	for _, file := range form.File["resumes"] {
+		inputs = append(inputs, screeningInput{name: file.Filename, file: file})
+	}
+	var texts []string
+	for _, text := range form.Value[resumeTextField] {
+		if strings.TrimSpace(text) != "" {
+			texts = append(texts, text)
+		}
+	}
+	for i, text := range texts {
+		name := resumeTextField
+		if len(texts) > 1 {
+			name = fmt.Sprintf("%s#%d", resumeTextField, i+1)
+		}
+		inputs = append(inputs, screeningInput{name: name, text: text})
+	}
+	return inputs
+}
 
-	// 这里简化处理：返回原始内容
-	// 在实际项目中，应该使用专门的库解析PDF/Word并提取文本
-	return string(content), nil
+// read 读取简历内容
+func (in screeningInput) read() ([]byte, error) {
+	if in.file == nil {
+		return []byte(in.text), nil
+	}
+	src, err := in.file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	return io.ReadAll(src)
+}
+
+// unsupportedFileMessage 不支持的文件类型的提示，列出各接口统一接受的扩展名
+func unsupportedFileMessage(lang string) string {
+	return localize(lang, "不支持的文件类型，支持的格式: ") + strings.Join(services.SupportedResumeExtensions(), ", ")
+}
+
+// respondExtractError 返回简历文本提取失败的错误，扫描件等没有文本层的PDF单独提示
+func respondExtractError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrNoTextLayer) || errors.Is(err, services.ErrPDFEncrypted) || errors.Is(err, services.ErrDocumentEncrypted) || errors.Is(err, services.ErrDocumentTooLarge) {
+		respondAIError(c, err)
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "无法从简历中提取文本"})
 }
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GiantClam/ai-resume/services"
+	"github.com/GiantClam/ai-resume/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// GetUsage 查询大模型令牌用量和估算费用
+// 查询参数: from/to (YYYY-MM-DD，默认最近7天)、userId（仅管理员可查询其他用户）、groupBy (day,user,endpoint,request，默认 day,user)
+func GetUsage(c *gin.Context) {
+	to := time.Now()
+	if value := c.Query("to"); value != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的结束日期"})
+			return
+		}
+		to = parsed
+	}
+
+	from := to.AddDate(0, 0, -6)
+	if value := c.Query("from"); value != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的开始日期"})
+			return
+		}
+		from = parsed
+	}
+
+	filter := services.UsageFilter{
+		From:    time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.Local),
+		To:      time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.Local),
+		GroupBy: []string{"day", "user"},
+	}
+	if filter.From.After(filter.To) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "开始日期不能晚于结束日期"})
+		return
+	}
+
+	if value := c.Query("userId"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+			return
+		}
+		userID := uint(id)
+		filter.UserID = &userID
+	}
+
+	// 管理员可以查询所有用户的用量，其他用户只能查询自己的用量
+	if caller := requestUserID(c); !utils.IsAdminUser(caller) {
+		if filter.UserID != nil && *filter.UserID != caller {
+			c.JSON(http.StatusForbidden, gin.H{"error": "无权查询其他用户的用量"})
+			return
+		}
+		filter.UserID = &caller
+	}
+
+	if value := c.Query("groupBy"); value != "" {
+		filter.GroupBy = strings.Split(value, ",")
+	}
+
+	report, err := services.QueryUsage(filter)
+	if errors.Is(err, services.ErrInvalidUsageFilter) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrUsageStoreDisabled) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("查询用量失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询用量失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
+
+// usageScope 为当前请求生成用量归属信息，未登录时用户ID为0
+func usageScope(c *gin.Context, endpoint string, prompt services.PromptRef) services.UsageScope {
+	return services.UsageScope{
+		RequestID: uuid.New().String(),
+		UserID:    requestUserID(c),
+		Endpoint:  endpoint,
+		Prompt:    prompt,
+	}
+}
+
+// requestUserID 返回当前登录用户的ID，未登录时为0
+func requestUserID(c *gin.Context) uint {
+	if userID, ok := c.Get("userId"); ok {
+		if id, ok := userID.(uint); ok {
+			return id
+		}
+	}
+	return 0
+}
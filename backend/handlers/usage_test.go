@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetUsageRestrictsOtherUsers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("ADMIN_USER_IDS", "1")
+
+	tests := []struct {
+		name   string
+		caller uint
+		query  string
+		want   int
+	}{
+		{"other user", 2, "?userId=3", http.StatusForbidden},
+		{"anonymous", 0, "?userId=3", http.StatusForbidden},
+		{"own usage", 2, "?userId=2", http.StatusServiceUnavailable},
+		{"admin", 1, "?userId=3", http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/api/usage"+tt.query, nil)
+			c.Set("userId", tt.caller)
+			GetUsage(c)
+			// 未配置数据库时通过权限检查的请求返回 503
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
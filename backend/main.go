@@ -1,12 +1,19 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/GiantClam/ai-resume/models"
 	"github.com/GiantClam/ai-resume/routes"
+	"github.com/GiantClam/ai-resume/services"
 	"github.com/GiantClam/ai-resume/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -67,11 +74,31 @@ func main() {
 	}
 
 	// 自动迁移数据库模型
-	if err := db.AutoMigrate(&models.User{}); err != nil {
+	if err := db.AutoMigrate(&models.User{}, &models.LLMUsage{}, &models.LLMCacheEntry{}, &models.Conversation{}, &models.ConversationMessage{}); err != nil {
 		log.Fatalf("数据库迁移失败: %v", err)
 	}
 	log.Println("数据库迁移成功")
 
+	// 记录大模型令牌用量
+	services.InitUsageStore(db)
+
+	// 缓存大模型响应，相同的请求直接返回之前的结果
+	services.InitResponseCache(db)
+
+	// 保存候选人多轮问答的对话记录
+	services.InitConversationStore(db)
+
+	// 加载提示模板，模板错误时拒绝启动
+	if err := services.InitPrompts(); err != nil {
+		log.Fatalf("加载提示模板失败: %v", err)
+	}
+
+	// 预先创建大模型客户端，失败时在首次请求时重试
+	if err := services.InitLLMProviders(); err != nil {
+		log.Printf("警告: 初始化大模型客户端失败: %v", err)
+	}
+	defer services.CloseLLMProviders()
+
 	// 设置Gin模式
 	ginMode := os.Getenv("GIN_MODE")
 	if ginMode == "release" {
@@ -89,8 +116,28 @@ func main() {
 
 	// 启动服务器
 	serverAddr := fmt.Sprintf(":%s", port)
-	log.Printf("服务器启动在 http://localhost%s", serverAddr)
-	if err := r.Run(serverAddr); err != nil {
-		log.Fatalf("启动服务器失败: %v", err)
+	srv := &http.Server{
+		Addr:    serverAddr,
+		Handler: r,
+	}
+
+	go func() {
+		log.Printf("服务器启动在 http://localhost%s", serverAddr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("启动服务器失败: %v", err)
+		}
+	}()
+
+	// 等待退出信号，优雅关闭服务器后再释放大模型客户端
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+
+	log.Println("正在关闭服务器...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("服务器关闭失败: %v", err)
 	}
+	log.Println("服务器已关闭")
 }
@@ -42,3 +42,30 @@ func AuthMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// OptionalAuth 可选认证中间件：携带有效令牌时将用户ID存入上下文，未携带或无效时按匿名用户继续处理
+func OptionalAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
+		if len(parts) == 2 && parts[0] == "Bearer" {
+			if claims, err := utils.ParseJWT(parts[1]); err == nil {
+				c.Set("userId", claims.UserID)
+			}
+		}
+		c.Next()
+	}
+}
+
+// AdminOnly 管理员认证中间件：需在 AuthMiddleware 之后使用，用户不在 ADMIN_USER_IDS 中时返回 403
+func AdminOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("userId")
+		id, _ := userID.(uint)
+		if !utils.IsAdminUser(id) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "需要管理员权限"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
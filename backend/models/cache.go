@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// LLMCacheEntry 数据库中缓存的大模型响应
+type LLMCacheEntry struct {
+	Key       string    `gorm:"primaryKey;size:64;column:cache_key" json:"key"` // 模型、参数、提示和文件内容的 SHA-256
+	Provider  string    `gorm:"size:20" json:"provider"`
+	Model     string    `gorm:"size:100" json:"model"`
+	Text      string    `json:"text"`
+	ExpiresAt time.Time `gorm:"index" json:"expiresAt"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ComplianceFinding 生成内容中被合规检查删除或改写的一项
+type ComplianceFinding struct {
+	Field     string `json:"field"`               // 所在字段，如 questions[2]、strengths[0]、overall
+	Category  string `json:"category"`            // 涉及的类别，如 age、marital_status
+	Source    string `json:"source"`              // 识别方式: rule（规则）或 llm（模型分类）
+	Action    string `json:"action"`              // dropped（删除）或 rewritten（改写）
+	Original  string `json:"original"`            // 原文
+	Rewritten string `json:"rewritten,omitempty"` // 改写后的内容
+}
+
+// ComplianceViolation 模型识别出的一条违规内容
+type ComplianceViolation struct {
+	Index    int    `json:"index" desc:"违规条目的编号"`
+	Category string `json:"category" desc:"涉及的类别: age、marital_status、pregnancy、hometown、ethnicity 或 other"`
+	Rewrite  string `json:"rewrite" desc:"去掉违规内容后的改写，无法改写时为空字符串"`
+}
+
+// ComplianceCheckResponse 合规分类的响应
+type ComplianceCheckResponse struct {
+	Violations []ComplianceViolation `json:"violations" desc:"违规条目列表，没有违规时为空数组"`
+}
+
+// Validate 校验合规分类响应，每条违规必须给出类别
+func (r *ComplianceCheckResponse) Validate() error {
+	for i, v := range r.Violations {
+		if v.Category == "" {
+			return fmt.Errorf("violations[%d].category 不能为空", i)
+		}
+		if v.Index < 1 {
+			return errors.New("index 从 1 开始编号")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// 对话消息的角色
+const (
+	ChatRoleUser      = "user"      // 面试官
+	ChatRoleAssistant = "assistant" // AI
+)
+
+// Conversation 围绕一位候选人的多轮问答，保存回答问题所依据的简历、招聘要求和此前的AI结果
+type Conversation struct {
+	ID              string    `gorm:"primaryKey;size:36" json:"id"`
+	UserID          uint      `gorm:"index" json:"userId"` // 未登录用户为0
+	Industry        string    `gorm:"size:100" json:"industry"`
+	JobRequirements string    `gorm:"type:text" json:"jobRequirements"`
+	ResumeName      string    `gorm:"size:255" json:"resumeName"`
+	ResumeText      string    `gorm:"type:text" json:"-"`
+	PriorOutputs    string    `gorm:"type:text" json:"-"` // 此前的筛选结果、面试题等AI输出
+	Language        string    `gorm:"size:10" json:"language"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
+
+// ConversationMessage 对话中的一条消息
+type ConversationMessage struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	ConversationID string    `gorm:"size:36;index" json:"conversationId"`
+	Role           string    `gorm:"size:20" json:"role"`
+	Content        string    `gorm:"type:text" json:"content"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+// ChatMessageRequest 向对话发送消息的请求
+type ChatMessageRequest struct {
+	Message string `json:"message" binding:"required"`
+}
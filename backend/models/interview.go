@@ -1,5 +1,10 @@
 package models
 
+import (
+	"errors"
+	"fmt"
+)
+
 // 面试题生成请求
 type QuestionsRequest struct {
 	JobRequirements string `json:"jobRequirements" binding:"required"`
@@ -9,14 +14,14 @@ type QuestionsRequest struct {
 
 // Question 表示一个面试问题
 type Question struct {
-	Question string `json:"question"`
-	Answer   string `json:"answer"`
-	Category string `json:"category"`
+	Question string `json:"question" desc:"问题内容"`
+	Answer   string `json:"answer" desc:"简洁的参考答案"`
+	Category string `json:"category" desc:"问题类别"`
 }
 
 // QuestionsResponse 表示面试题生成的API响应
 type QuestionsResponse struct {
-	Questions []Question `json:"questions"`
+	Questions []Question `json:"questions" desc:"面试问题列表"`
 }
 
 // 面试总结请求
@@ -25,15 +30,43 @@ type SummaryRequest struct {
 	Industry         string `json:"industry" binding:"required"`
 	InterviewNotes   string `json:"interviewNotes" binding:"required"`
 	IndustryKeywords string `json:"industryKeywords"`
+	Language         string `json:"language"` // 输出语言 zh、en 或 ja，为空时使用 Accept-Language
 }
 
 // SummaryResponse 表示面试总结的API响应
 type SummaryResponse struct {
-	Overall          string   `json:"overall"`
-	Strengths        []string `json:"strengths"`
-	Weaknesses       []string `json:"weaknesses"`
-	Recommendation   string   `json:"recommendation"`
-	FurtherQuestions []string `json:"furtherQuestions"` // 需进一步了解的问题
-	RiskPoints       []string `json:"riskPoints"`       // 风险点
-	Suggestions      []string `json:"suggestions"`      // 建议
+	Overall          string   `json:"overall" desc:"总体评价"`
+	Strengths        []string `json:"strengths" desc:"优势"`
+	Weaknesses       []string `json:"weaknesses" desc:"不足"`
+	Recommendation   string   `json:"recommendation" desc:"是否推荐录用及简要原因"`
+	FurtherQuestions []string `json:"furtherQuestions" desc:"需要进一步了解的问题"` // 需进一步了解的问题
+	RiskPoints       []string `json:"riskPoints" desc:"风险点"`              // 风险点
+	Suggestions      []string `json:"suggestions" desc:"建议"`              // 建议
+}
+
+// Validate 校验面试题响应的必填字段
+func (r *QuestionsResponse) Validate() error {
+	if len(r.Questions) == 0 {
+		return errors.New("questions 不能为空")
+	}
+	for i, q := range r.Questions {
+		if q.Question == "" {
+			return fmt.Errorf("questions[%d].question 不能为空", i)
+		}
+		if q.Answer == "" {
+			return fmt.Errorf("questions[%d].answer 不能为空", i)
+		}
+	}
+	return nil
+}
+
+// Validate 校验面试总结响应的必填字段
+func (r *SummaryResponse) Validate() error {
+	if r.Overall == "" {
+		return errors.New("overall 不能为空")
+	}
+	if r.Recommendation == "" {
+		return errors.New("recommendation 不能为空")
+	}
+	return nil
 }
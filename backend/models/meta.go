@@ -0,0 +1,26 @@
+package models
+
+// AIMeta 随AI结果返回的元数据，记录生成结果所用的提示模板、输出语言、模型、生成参数和缓存命中情况
+type AIMeta struct {
+	PromptName    string `json:"promptName"`
+	PromptVersion int    `json:"promptVersion"`
+	Language      string `json:"language,omitempty"` // 实际使用的模板语言
+	Provider      string `json:"provider,omitempty"`
+	Model         string `json:"model,omitempty"`
+	Cache         string `json:"cache,omitempty"`       // hit、miss 或 bypass，批量请求中各次结果不一致时为 mixed
+	CacheHits     int    `json:"cacheHits,omitempty"`   // 命中缓存的模型调用次数
+	CacheMisses   int    `json:"cacheMisses,omitempty"` // 未命中或跳过缓存的模型调用次数
+	Chunks        int    `json:"chunks,omitempty"`      // 输入过长时分块提取要点的分块数
+
+	Compliance []ComplianceFinding `json:"compliance,omitempty"` // 合规检查删除或改写的内容
+
+	Params *GenerationParams `json:"params,omitempty"` // 实际生效的生成参数
+}
+
+// GenerationParams 实际生效的生成参数
+type GenerationParams struct {
+	Temperature     float32 `json:"temperature"`
+	TopP            float32 `json:"topP"`
+	TopK            int32   `json:"topK"`
+	MaxOutputTokens int32   `json:"maxOutputTokens"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "errors"
+
+// ChunkNotes 长文本分块处理时，模型从一个分块中提取的结构化要点
+type ChunkNotes struct {
+	Facts      []string `json:"facts" desc:"客观信息：经历、项目、技能、职位、时间、回答内容等，保留具体的名称和数字"`
+	Highlights []string `json:"highlights" desc:"与招聘要求相关的亮点"`
+	Concerns   []string `json:"concerns" desc:"与招聘要求相关的不足、疑点或需要核实的地方"`
+}
+
+// Validate 校验要点，至少需要提取到一条信息
+func (n *ChunkNotes) Validate() error {
+	if len(n.Facts) == 0 && len(n.Highlights) == 0 && len(n.Concerns) == 0 {
+		return errors.New("facts、highlights 和 concerns 不能同时为空")
+	}
+	return nil
+}
@@ -1,5 +1,10 @@
 package models
 
+import (
+	"errors"
+	"fmt"
+)
+
 // 简历筛选请求
 type ScreeningRequest struct {
 	JobRequirements string   `json:"jobRequirements" binding:"required"`
@@ -9,12 +14,31 @@ type ScreeningRequest struct {
 
 // ResumeResult 表示单个简历的分析结果
 type ResumeResult struct {
-	Name   string `json:"name"`
-	Reason string `json:"reason"`
+	Name    string `json:"name" desc:"简历文件名"`
+	Reason  string `json:"reason" desc:"判断理由"`
+	Warning string `json:"warning,omitempty" schema:"-"` // 简历疑似包含提示注入等需要人工复核的内容，由服务端填写
 }
 
 // ScreeningResponse 表示简历筛选的API响应
 type ScreeningResponse struct {
-	Passed []ResumeResult `json:"passed"`
-	Failed []ResumeResult `json:"failed"`
+	Passed []ResumeResult `json:"passed" desc:"符合招聘要求的简历"`
+	Failed []ResumeResult `json:"failed" desc:"不符合招聘要求的简历"`
+}
+
+// Validate 校验简历筛选响应，每份简历必须给出结论和理由
+func (r *ScreeningResponse) Validate() error {
+	if len(r.Passed) == 0 && len(r.Failed) == 0 {
+		return errors.New("passed 和 failed 不能同时为空")
+	}
+	for i, item := range r.Passed {
+		if item.Reason == "" {
+			return fmt.Errorf("passed[%d].reason 不能为空", i)
+		}
+	}
+	for i, item := range r.Failed {
+		if item.Reason == "" {
+			return fmt.Errorf("failed[%d].reason 不能为空", i)
+		}
+	}
+	return nil
 }
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// LLMUsage 单次大模型调用的令牌用量记录
+type LLMUsage struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	RequestID       string    `gorm:"size:36;index" json:"requestId"`
+	UserID          uint      `gorm:"index" json:"userId"` // 未登录用户为0
+	Endpoint        string    `gorm:"size:50;index" json:"endpoint"`
+	PromptName      string    `gorm:"size:100" json:"promptName"`
+	PromptVersion   int       `json:"promptVersion"`
+	Provider        string    `gorm:"size:20" json:"provider"`
+	Model           string    `gorm:"size:100" json:"model"`
+	PromptTokens    int       `json:"promptTokens"`
+	CandidateTokens int       `json:"candidateTokens"`
+	TotalTokens     int       `json:"totalTokens"`
+	CreatedAt       time.Time `gorm:"index" json:"createdAt"`
+}
+
+// UsageSummary 按日期、用户等维度汇总的用量和估算费用
+type UsageSummary struct {
+	Date            string  `json:"date,omitempty"`
+	UserID          *uint   `json:"userId,omitempty"`
+	Endpoint        string  `json:"endpoint,omitempty"`
+	RequestID       string  `json:"requestId,omitempty"`
+	Requests        int     `json:"requests"`
+	PromptTokens    int     `json:"promptTokens"`
+	CandidateTokens int     `json:"candidateTokens"`
+	TotalTokens     int     `json:"totalTokens"`
+	EstimatedCost   float64 `json:"estimatedCost"`
+}
+
+// UsageReport 用量查询接口的响应
+type UsageReport struct {
+	From           string         `json:"from"`
+	To             string         `json:"to"`
+	Currency       string         `json:"currency"`
+	Rows           []UsageSummary `json:"rows"`
+	Total          UsageSummary   `json:"total"`
+	UnpricedModels []string       `json:"unpricedModels,omitempty"` // 价格表中缺少的模型，费用按0计算
+}
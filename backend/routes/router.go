@@ -1,11 +1,13 @@
 package routes
 
 import (
+	"expvar"
 	"log"
 	"net/http"
 	"time"
 
 	"github.com/GiantClam/ai-resume/handlers"
+	"github.com/GiantClam/ai-resume/middleware"
 	"github.com/gin-gonic/gin"
 )
 
@@ -67,17 +69,32 @@ func SetupRouter() *gin.Engine {
 		auth.GET("/user/profile", handlers.GetUserProfile)
 	}
 
-	// 简历筛选API
-	r.POST("/api/resume/screen", handlers.ScreenResumes)
+	// 大模型用量查询API
+	r.GET("/api/usage", middleware.AuthMiddleware(), handlers.GetUsage)
 
-	// 面试题目生成API
-	r.POST("/api/interview/questions", handlers.GenerateInterviewQuestions)
+	// 调用大模型的接口允许匿名访问，登录用户的用量会归属到该用户
+	ai := r.Group("/api", middleware.OptionalAuth())
+	{
+		// 简历筛选API
+		ai.POST("/resume/screen", handlers.ScreenResumes)
+
+		// 面试题目生成API
+		ai.POST("/interview/questions", handlers.GenerateInterviewQuestions)
+
+		// 面试题目流式生成API
+		ai.POST("/interview/questions/stream", handlers.StreamGenerateInterviewQuestions)
 
-	// 面试题目流式生成API
-	r.POST("/api/interview/questions/stream", handlers.StreamGenerateInterviewQuestions)
+		// 面试总结API
+		ai.POST("/interview/summary", handlers.SummarizeInterview)
+
+		// 候选人多轮问答API：创建对话、查看历史、流式提问
+		ai.POST("/conversations", handlers.CreateConversation)
+		ai.GET("/conversations/:id", handlers.GetConversation)
+		ai.POST("/conversations/:id/messages", handlers.SendConversationMessage)
+	}
 
-	// 面试总结API
-	r.POST("/api/interview/summary", handlers.SummarizeInterview)
+	// 运行指标（包括缓存命中次数 llm_cache_hits、llm_cache_misses、llm_cache_bypass），包含命令行参数和内存统计，仅管理员可查看
+	r.GET("/debug/vars", middleware.AuthMiddleware(), middleware.AdminOnly(), gin.WrapH(expvar.Handler()))
 
 	// 添加测试API端点
 	r.GET("/api/test", func(c *gin.Context) {
@@ -0,0 +1,47 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GiantClam/ai-resume/models"
+	"github.com/GiantClam/ai-resume/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func TestDebugVarsRequiresAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("ADMIN_USER_IDS", "1")
+	r := SetupRouter()
+
+	token := func(id uint) string {
+		s, err := utils.GenerateJWT(&models.User{ID: id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "Bearer " + s
+	}
+	tests := []struct {
+		name string
+		auth string
+		want int
+	}{
+		{"anonymous", "", http.StatusUnauthorized},
+		{"user", token(2), http.StatusForbidden},
+		{"admin", token(1), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/debug/vars", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,517 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GiantClam/ai-resume/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 缓存默认参数
+const (
+	defaultCacheTTL        = 24 * time.Hour
+	defaultCacheMaxEntries = 1000
+)
+
+// 缓存后端
+const (
+	cacheBackendOff    = "off"
+	cacheBackendMemory = "memory"
+	cacheBackendDB     = "db"
+)
+
+// CacheStatus 一次调用的缓存结果
+type CacheStatus string
+
+const (
+	CacheHit    CacheStatus = "hit"    // 命中缓存，未调用模型
+	CacheMiss   CacheStatus = "miss"   // 未命中，调用模型后写入缓存
+	CacheBypass CacheStatus = "bypass" // 请求要求跳过缓存，调用模型后刷新缓存
+)
+
+// 缓存指标，通过 /debug/vars 暴露
+var (
+	cacheHits   = expvar.NewInt("llm_cache_hits")
+	cacheMisses = expvar.NewInt("llm_cache_misses")
+	cacheBypass = expvar.NewInt("llm_cache_bypass")
+)
+
+// ResponseCache 大模型响应缓存的存储后端
+type ResponseCache interface {
+	Get(key string) (*GenerateResult, bool)
+	Set(key string, result *GenerateResult, ttl time.Duration)
+	Delete(key string)
+}
+
+var (
+	responseCacheMu sync.RWMutex
+	responseCache   ResponseCache
+	cacheTTL        = defaultCacheTTL
+)
+
+// InitResponseCache 根据 LLM_CACHE 初始化缓存后端，db 后端使用传入的数据库连接，调用前需完成 models.LLMCacheEntry 的迁移
+func InitResponseCache(db *gorm.DB) {
+	if value := os.Getenv("LLM_CACHE_TTL"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			cacheTTL = d
+		} else {
+			log.Printf("[WARN] 无效的 LLM_CACHE_TTL: %s，使用默认值 %v", value, defaultCacheTTL)
+		}
+	}
+
+	var cache ResponseCache
+	switch backend := strings.ToLower(os.Getenv("LLM_CACHE")); backend {
+	case cacheBackendOff:
+		log.Printf("[INFO] 大模型响应缓存已关闭")
+	case cacheBackendDB:
+		if db == nil {
+			log.Printf("[WARN] 数据库未初始化，大模型响应缓存改用内存")
+			cache = newMemoryCache(cacheMaxEntries())
+			break
+		}
+		cache = &dbCache{db: db}
+		// 启动时清理已过期的缓存，运行期间过期的条目在读取时忽略，写入时覆盖
+		if err := db.Where("expires_at <= ?", time.Now()).Delete(&models.LLMCacheEntry{}).Error; err != nil {
+			log.Printf("[WARN] 清理过期缓存失败: %v", err)
+		}
+		log.Printf("[INFO] 大模型响应缓存使用数据库，TTL: %v", cacheTTL)
+	default:
+		if backend != "" && backend != cacheBackendMemory {
+			log.Printf("[WARN] 未知的 LLM_CACHE: %s，使用内存缓存", backend)
+		}
+		cache = newMemoryCache(cacheMaxEntries())
+		log.Printf("[INFO] 大模型响应缓存使用内存，TTL: %v", cacheTTL)
+	}
+
+	responseCacheMu.Lock()
+	responseCache = cache
+	responseCacheMu.Unlock()
+}
+
+// currentResponseCache 返回当前的缓存后端，未初始化或已关闭时返回 nil
+func currentResponseCache() ResponseCache {
+	responseCacheMu.RLock()
+	defer responseCacheMu.RUnlock()
+	return responseCache
+}
+
+// cacheMaxEntries 读取 LLM_CACHE_MAX_ENTRIES
+func cacheMaxEntries() int {
+	if value := os.Getenv("LLM_CACHE_MAX_ENTRIES"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			return n
+		}
+		log.Printf("[WARN] 无效的 LLM_CACHE_MAX_ENTRIES: %s，使用默认值 %d", value, defaultCacheMaxEntries)
+	}
+	return defaultCacheMaxEntries
+}
+
+// cachingProvider 按请求内容缓存模型响应
+type cachingProvider struct {
+	inner  LLMProvider
+	cache  ResponseCache
+	bypass bool
+}
+
+var _ LLMProvider = (*cachingProvider)(nil)
+
+// WithCache 包装提供方，相同的模型、参数、系统指令、提示和文件内容直接返回缓存的响应
+// bypass 为 true 时跳过读取缓存，但仍用新的响应刷新缓存；缓存未启用时原样返回提供方
+func WithCache(p LLMProvider, bypass bool) LLMProvider {
+	cache := currentResponseCache()
+	if cache == nil {
+		return p
+	}
+	return &cachingProvider{inner: p, cache: cache, bypass: bypass}
+}
+
+// Name 返回被包装提供方的名称
+func (p *cachingProvider) Name() string {
+	return p.inner.Name()
+}
+
+// Model 返回被包装提供方的模型名称
+func (p *cachingProvider) Model() string {
+	return p.inner.Model()
+}
+
+// GenerateContent 优先返回缓存的响应
+func (p *cachingProvider) GenerateContent(ctx context.Context, systemInstruction, prompt string, opts ...GenerateOption) (*GenerateResult, error) {
+	key := p.key(systemInstruction, prompt, "", nil, opts)
+	return p.lookup(key, func() (*GenerateResult, error) {
+		return p.inner.GenerateContent(ctx, systemInstruction, prompt, opts...)
+	})
+}
+
+// GenerateContentWithBinaryFile 优先返回缓存的响应，文件内容参与缓存键计算
+func (p *cachingProvider) GenerateContentWithBinaryFile(ctx context.Context, systemInstruction string, fileContent string, mimeType string, textPrompt string, opts ...GenerateOption) (*GenerateResult, error) {
+	key := p.key(systemInstruction, textPrompt, mimeType, []byte(fileContent), opts)
+	return p.lookup(key, func() (*GenerateResult, error) {
+		return p.inner.GenerateContentWithBinaryFile(ctx, systemInstruction, fileContent, mimeType, textPrompt, opts...)
+	})
+}
+
+// GenerateContentStream 命中缓存时将缓存的响应拆分为若干段返回，未命中时在流结束后写入缓存
+func (p *cachingProvider) GenerateContentStream(ctx context.Context, systemInstruction, prompt string, opts ...GenerateOption) (StreamIterator, error) {
+	key := p.key(systemInstruction, prompt, "", nil, opts)
+	if !p.bypass {
+		if cached, ok := p.cache.Get(key); ok {
+			cacheHits.Add(1)
+			log.Printf("[DEBUG] 流式请求命中缓存 %s (%s/%s)", key[:12], cached.Provider, cached.Model)
+			return &cachedStream{
+				fakeStream: fakeStream{chunks: splitIntoChunks(cached.Text, 40)},
+				provider:   cached.Provider,
+				model:      cached.Model,
+				discard:    func() { p.cache.Delete(key) },
+			}, nil
+		}
+	}
+
+	iter, err := p.inner.GenerateContentStream(ctx, systemInstruction, prompt, opts...)
+	if err != nil {
+		return nil, err
+	}
+	p.countMiss()
+	status := CacheMiss
+	if p.bypass {
+		status = CacheBypass
+	}
+	return &cachingStream{inner: iter, provider: p, key: key, status: status}, nil
+}
+
+// lookup 读取缓存，未命中时调用模型并写入缓存
+func (p *cachingProvider) lookup(key string, call func() (*GenerateResult, error)) (*GenerateResult, error) {
+	if !p.bypass {
+		if cached, ok := p.cache.Get(key); ok {
+			cacheHits.Add(1)
+			log.Printf("[DEBUG] 命中缓存 %s (%s/%s)", key[:12], cached.Provider, cached.Model)
+			return p.withDiscard(key, &GenerateResult{
+				Text:         cached.Text,
+				Provider:     cached.Provider,
+				Model:        cached.Model,
+				FinishReason: FinishReasonStop,
+				CacheStatus:  CacheHit,
+			}), nil
+		}
+	}
+
+	result, err := call()
+	if err != nil {
+		return nil, err
+	}
+	p.countMiss()
+
+	result.CacheStatus = CacheMiss
+	if p.bypass {
+		result.CacheStatus = CacheBypass
+	}
+	// 被截断的输出不可用，不写入缓存
+	if result.FinishReason.Truncated() {
+		return result, nil
+	}
+	p.cache.Set(key, result, cacheTTL)
+	return p.withDiscard(key, result), nil
+}
+
+// withDiscard 允许调用方在响应无效时删除对应的缓存
+func (p *cachingProvider) withDiscard(key string, result *GenerateResult) *GenerateResult {
+	result.discard = func() {
+		log.Printf("[DEBUG] 响应无效，删除缓存 %s", key[:12])
+		p.cache.Delete(key)
+	}
+	return result
+}
+
+// countMiss 记录未命中或跳过缓存的次数
+func (p *cachingProvider) countMiss() {
+	if p.bypass {
+		cacheBypass.Add(1)
+	} else {
+		cacheMisses.Add(1)
+	}
+}
+
+// key 计算缓存键：提供方、模型、生成参数、系统指令、规范化后的提示和文件内容的哈希
+func (p *cachingProvider) key(systemInstruction, prompt, mimeType string, fileData []byte, opts []GenerateOption) string {
+	h := sha256.New()
+	for _, part := range []string{
+		p.inner.Name(),
+		p.inner.Model(),
+		applyGenerateOptions(opts).cacheKey(),
+		normalizePrompt(systemInstruction),
+		normalizePrompt(prompt),
+		mimeType,
+	} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	h.Write(fileData)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// normalizePrompt 统一换行符并去除行尾和首尾空白，避免无意义的格式差异导致缓存未命中
+func normalizePrompt(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// cachedStream 将命中的缓存拆分为若干段返回
+type cachedStream struct {
+	fakeStream
+	provider string
+	model    string
+	discard  func()
+}
+
+// Source 返回生成缓存内容的提供方和模型
+func (s *cachedStream) Source() (string, string) {
+	return s.provider, s.model
+}
+
+// CacheStatus 返回缓存命中情况
+func (s *cachedStream) CacheStatus() CacheStatus {
+	return CacheHit
+}
+
+// Discard 删除命中的缓存
+func (s *cachedStream) Discard() {
+	s.discard()
+}
+
+// cachingStream 透传流式响应，正常结束时将完整文本写入缓存
+type cachingStream struct {
+	inner    StreamIterator
+	provider *cachingProvider
+	key      string
+	status   CacheStatus
+	text     strings.Builder
+	stored   bool
+}
+
+// Next 返回下一段文本
+func (s *cachingStream) Next() (string, error) {
+	chunk, err := s.inner.Next()
+	if errors.Is(err, io.EOF) {
+		if !s.stored && s.text.Len() > 0 && !s.inner.FinishReason().Truncated() {
+			s.stored = true
+			provider, model := s.Source()
+			s.provider.cache.Set(s.key, &GenerateResult{Text: s.text.String(), Provider: provider, Model: model}, cacheTTL)
+		}
+		return "", io.EOF
+	}
+	if err != nil {
+		return "", err
+	}
+	s.text.WriteString(chunk)
+	return chunk, nil
+}
+
+// Usage 返回被包装流的令牌用量
+func (s *cachingStream) Usage() TokenUsage {
+	return s.inner.Usage()
+}
+
+// FinishReason 返回被包装流的结束原因
+func (s *cachingStream) FinishReason() FinishReason {
+	return s.inner.FinishReason()
+}
+
+// Close 关闭被包装的流，提前关闭时不写入缓存
+func (s *cachingStream) Close() error {
+	return s.inner.Close()
+}
+
+// Source 返回实际处理请求的提供方和模型
+func (s *cachingStream) Source() (string, string) {
+	return StreamSource(s.inner, s.provider.Name(), s.provider.Model())
+}
+
+// CacheStatus 返回缓存命中情况
+func (s *cachingStream) CacheStatus() CacheStatus {
+	return s.status
+}
+
+// Discard 删除流结束时写入的缓存
+func (s *cachingStream) Discard() {
+	s.provider.cache.Delete(s.key)
+}
+
+// StreamSource 返回实际处理流式请求的提供方和模型，流未记录时使用传入的默认值
+func StreamSource(iter StreamIterator, provider, model string) (string, string) {
+	if source, ok := iter.(interface{ Source() (string, string) }); ok {
+		return source.Source()
+	}
+	return provider, model
+}
+
+// DiscardStream 流式响应不可用时删除对应的缓存
+func DiscardStream(iter StreamIterator) {
+	if d, ok := iter.(interface{ Discard() }); ok {
+		d.Discard()
+	}
+}
+
+// StreamCacheStatus 返回流式响应的缓存命中情况，未启用缓存时为空
+func StreamCacheStatus(iter StreamIterator) CacheStatus {
+	if cached, ok := iter.(interface{ CacheStatus() CacheStatus }); ok {
+		return cached.CacheStatus()
+	}
+	return ""
+}
+
+// memoryCache 进程内的 LRU 缓存
+type memoryCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	order      *list.List // 最近使用的在前
+	entries    map[string]*list.Element
+}
+
+// memoryCacheEntry 内存缓存条目
+type memoryCacheEntry struct {
+	key       string
+	result    GenerateResult
+	expiresAt time.Time
+}
+
+// newMemoryCache 创建内存缓存
+func newMemoryCache(maxEntries int) *memoryCache {
+	return &memoryCache{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// Get 读取未过期的缓存
+func (c *memoryCache) Get(key string) (*GenerateResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*memoryCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	result := entry.result
+	return &result, true
+}
+
+// Set 写入缓存，超过容量时淘汰最久未使用的条目
+func (c *memoryCache) Set(key string, result *GenerateResult, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &memoryCacheEntry{
+		key:       key,
+		result:    GenerateResult{Text: result.Text, Provider: result.Provider, Model: result.Model},
+		expiresAt: time.Now().Add(ttl),
+	}
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*memoryCacheEntry).key)
+	}
+}
+
+// Delete 删除缓存
+func (c *memoryCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+	}
+}
+
+// dbCache 数据库缓存，多个实例之间共享
+type dbCache struct {
+	db *gorm.DB
+}
+
+// Get 读取未过期的缓存，读取失败按未命中处理
+func (c *dbCache) Get(key string) (*GenerateResult, bool) {
+	var entry models.LLMCacheEntry
+	err := c.db.Where("cache_key = ? AND expires_at > ?", key, time.Now()).Limit(1).Find(&entry).Error
+	if err != nil {
+		log.Printf("[ERROR] 读取缓存失败: %v", err)
+		return nil, false
+	}
+	if entry.Key == "" {
+		return nil, false
+	}
+	return &GenerateResult{Text: entry.Text, Provider: entry.Provider, Model: entry.Model}, true
+}
+
+// Set 写入或覆盖缓存，写入失败只记录日志
+func (c *dbCache) Set(key string, result *GenerateResult, ttl time.Duration) {
+	entry := models.LLMCacheEntry{
+		Key:       key,
+		Provider:  result.Provider,
+		Model:     result.Model,
+		Text:      result.Text,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	err := c.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "cache_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"provider", "model", "text", "expires_at"}),
+	}).Create(&entry).Error
+	if err != nil {
+		log.Printf("[ERROR] 写入缓存失败: %v", err)
+	}
+}
+
+// Delete 删除缓存
+func (c *dbCache) Delete(key string) {
+	if err := c.db.Delete(&models.LLMCacheEntry{Key: key}).Error; err != nil {
+		log.Printf("[ERROR] 删除缓存失败: %v", err)
+	}
+}
+
+// cacheKey 返回参与缓存键计算的生成参数
+func (o generateOptions) cacheKey() string {
+	var key string
+	if o.schema != nil {
+		data, _ := json.Marshal(o.schema.JSON)
+		key = o.schema.Name + ":" + string(data)
+	} else if o.json {
+		key = "json"
+	}
+	if o.params != nil {
+		data, _ := json.Marshal(o.params)
+		key += "|" + string(data)
+	}
+	return key
+}
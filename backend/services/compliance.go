@@ -0,0 +1,488 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/GiantClam/ai-resume/models"
+)
+
+// 合规检查的类别，即面试中不应询问或作为评价依据的个人特征
+const (
+	ComplianceAge       = "age"
+	ComplianceMarital   = "marital_status"
+	CompliancePregnancy = "pregnancy"
+	ComplianceHometown  = "hometown"
+	ComplianceEthnicity = "ethnicity"
+)
+
+// 违规内容的识别方式和处理方式
+const (
+	complianceSourceRule = "rule"
+	complianceSourceLLM  = "llm"
+
+	ComplianceDropped   = "dropped"
+	ComplianceRewritten = "rewritten"
+)
+
+// 合规检查模式，由 COMPLIANCE_CHECK 配置
+const (
+	complianceModeRules = "rules" // 只使用规则（默认）
+	complianceModeLLM   = "llm"   // 规则加模型分类，模型同时给出改写
+	complianceModeOff   = "off"   // 不检查
+)
+
+// defaultComplianceRules 内置的识别规则，匹配前文本会统一为小写，可通过 COMPLIANCE_RULES_FILE 覆盖或补充
+var defaultComplianceRules = map[string][]string{
+	ComplianceAge: {
+		`年龄|多大了|几岁|属相|属什么|出生年[份月]|哪一?年出生`,
+		`\bhow old\b|\b(your|his|her|their|candidate'?s) age\b|\b(date|year) of birth\b|\bborn in (19|20)\d\d\b`,
+		`年齢|何歳|生年月日`,
+	},
+	ComplianceMarital: {
+		`婚姻|婚否|已婚|未婚|结婚|离异|离婚|单身|男朋友|女朋友|配偶|恋爱`,
+		`\bmarried\b|\bmarital\b|\bdivorced?\b|\b(husband|wife|spouse|boyfriend|girlfriend)\b`,
+		`結婚|既婚|未婚|配偶者|独身|恋人`,
+	},
+	CompliancePregnancy: {
+		`怀孕|生育|生孩子|要孩子|备孕|孕期|产假|二胎|三胎|(有没有|有无|是否有|有几个|打算要|要不要|想不想要)(孩子|小孩|子女)|子女(情况|几个|多大|年龄|由谁|谁照顾|谁带)|育儿(负担|压力|计划|安排|情况)|(照顾|照看|接送)(孩子|小孩|子女)|孩子(多大|谁带|谁照顾|还小)`,
+		`\bpregnan|\bmaternity\b|\bfamily planning\b|\b(have|having|plan to have|planning to have) (a baby|babies|kids|children)\b`,
+		`妊娠|出産|産休|育児(中|の予定|との両立|と仕事)|(子供|子ども|お子さん|お子様)(は|が)(い|お)|(子供|子ども)を(作|持|産)|(子供|子ども)の(予定|有無)`,
+	},
+	ComplianceHometown: {
+		`籍贯|祖籍|老家|户籍|户口|出生地|家乡|哪里人`,
+		`\bhometown\b|\bplace of birth\b|\bwhere (are|were) you (from|born)\b|\bnative place\b`,
+		`出身地|本籍`,
+	},
+	ComplianceEthnicity: {
+		`(什么|哪个|哪一个)民族|民族(身份|成分|背景|信仰)|(是|是不是|是否为?|属于)少数民族|汉族|种族`,
+		`\bethnic(ity)?\b|\bracial\b`,
+		`人種`,
+	},
+}
+
+// complianceRule 一条识别规则
+type complianceRule struct {
+	category string
+	pattern  *regexp.Regexp
+}
+
+var (
+	complianceRulesOnce sync.Once
+	complianceRules     []complianceRule
+)
+
+// loadComplianceRules 合并内置规则和 COMPLIANCE_RULES_FILE 指定的 JSON 规则
+// 文件格式为 {"类别": ["正则表达式", ...]}，同名类别整体替换内置规则，空列表表示停用该类别
+func loadComplianceRules() []complianceRule {
+	complianceRulesOnce.Do(func() {
+		rules := make(map[string][]string, len(defaultComplianceRules))
+		for category, patterns := range defaultComplianceRules {
+			rules[category] = patterns
+		}
+
+		if path := os.Getenv("COMPLIANCE_RULES_FILE"); path != "" {
+			var custom map[string][]string
+			data, err := os.ReadFile(path)
+			if err == nil {
+				err = json.Unmarshal(data, &custom)
+			}
+			if err != nil {
+				log.Printf("[WARN] 加载合规规则 %s 失败: %v，使用内置规则", path, err)
+			} else {
+				for category, patterns := range custom {
+					rules[category] = patterns
+				}
+				log.Printf("[INFO] 已加载合规规则 %s，共 %d 个类别", path, len(custom))
+			}
+		}
+
+		for category, patterns := range rules {
+			for _, pattern := range patterns {
+				re, err := regexp.Compile(pattern)
+				if err != nil {
+					log.Printf("[WARN] 合规规则 %s 的正则表达式无效: %v", category, err)
+					continue
+				}
+				complianceRules = append(complianceRules, complianceRule{category: category, pattern: re})
+			}
+		}
+	})
+	return complianceRules
+}
+
+// complianceMode 读取 COMPLIANCE_CHECK
+func complianceMode() string {
+	switch mode := strings.ToLower(strings.TrimSpace(os.Getenv("COMPLIANCE_CHECK"))); mode {
+	case "":
+		return complianceModeRules
+	case complianceModeRules, complianceModeLLM, complianceModeOff:
+		return mode
+	default:
+		log.Printf("[WARN] 无效的 COMPLIANCE_CHECK: %s，只使用规则检查", mode)
+		return complianceModeRules
+	}
+}
+
+// MatchComplianceRule 返回文本命中的第一个规则类别，未命中时返回空字符串
+func MatchComplianceRule(text string) string {
+	normalized := strings.ToLower(text)
+	for _, rule := range loadComplianceRules() {
+		if rule.pattern.MatchString(normalized) {
+			return rule.category
+		}
+	}
+	return ""
+}
+
+// ComplianceResult 一次合规检查的结果
+type ComplianceResult struct {
+	Findings []models.ComplianceFinding // 被删除或改写的内容
+	Results  []*GenerateResult          // 模型分类调用的结果，用于统计缓存命中情况
+}
+
+// complianceVerdict 一个条目的检查结论
+type complianceVerdict struct {
+	category string
+	source   string
+	rewrite  string // 通过规则复查的改写，为空时删除该条目
+}
+
+// questionAnswerSeparator 送检时分隔面试题和参考答案
+const questionAnswerSeparator = " / "
+
+// removedPlaceholders 必填字段的内容全部被删除时使用的占位文本
+var removedPlaceholders = map[string]string{
+	LangZh: "（已删除涉及个人特征的内容）",
+	LangEn: "(Content about protected personal characteristics was removed.)",
+	LangJa: "（個人の属性に関する内容を削除しました）",
+}
+
+// CheckQuestionsCompliance 检查生成的面试题，删除询问或依据受保护个人特征的题目；模型给出改写时用改写替换题目和参考答案
+func CheckQuestionsCompliance(ctx context.Context, newProvider ProviderFactory, resp *models.QuestionsResponse) *ComplianceResult {
+	result := &ComplianceResult{}
+	if complianceMode() == complianceModeOff || resp == nil {
+		return result
+	}
+
+	texts := make([]string, len(resp.Questions))
+	for i, q := range resp.Questions {
+		texts[i] = q.Question + questionAnswerSeparator + q.Answer
+	}
+	verdicts := result.check(ctx, newProvider, texts)
+
+	kept := resp.Questions[:0]
+	for i, q := range resp.Questions {
+		verdict, ok := verdicts[i]
+		if !ok {
+			kept = append(kept, q)
+			continue
+		}
+		finding := models.ComplianceFinding{
+			Field:    fmt.Sprintf("questions[%d]", i),
+			Category: verdict.category,
+			Source:   verdict.source,
+			Action:   ComplianceDropped,
+			Original: q.Question,
+		}
+		// 改写同样为 题目 / 参考答案 的格式，缺少参考答案时删除该题
+		if question, answer, found := strings.Cut(verdict.rewrite, questionAnswerSeparator); found && strings.TrimSpace(question) != "" && strings.TrimSpace(answer) != "" {
+			q.Question, q.Answer = strings.TrimSpace(question), strings.TrimSpace(answer)
+			finding.Action, finding.Rewritten = ComplianceRewritten, q.Question
+			kept = append(kept, q)
+		}
+		result.Findings = append(result.Findings, finding)
+	}
+	resp.Questions = kept
+	result.log("面试题")
+	return result
+}
+
+// CheckSummaryCompliance 检查生成的面试总结：列表中的违规条目被改写或删除；总体评价和录用建议中没有改写时删除违规的句子
+func CheckSummaryCompliance(ctx context.Context, newProvider ProviderFactory, lang string, resp *models.SummaryResponse) *ComplianceResult {
+	result := &ComplianceResult{}
+	if complianceMode() == complianceModeOff || resp == nil {
+		return result
+	}
+
+	type field struct {
+		name string
+		text *string
+	}
+	var fields []field
+	fields = append(fields, field{"overall", &resp.Overall}, field{"recommendation", &resp.Recommendation})
+	for _, list := range []struct {
+		name  string
+		items []string
+	}{
+		{"strengths", resp.Strengths},
+		{"weaknesses", resp.Weaknesses},
+		{"furtherQuestions", resp.FurtherQuestions},
+		{"riskPoints", resp.RiskPoints},
+		{"suggestions", resp.Suggestions},
+	} {
+		for i := range list.items {
+			fields = append(fields, field{fmt.Sprintf("%s[%d]", list.name, i), &list.items[i]})
+		}
+	}
+
+	texts := make([]string, len(fields))
+	for i, f := range fields {
+		texts[i] = *f.text
+	}
+	verdicts := result.check(ctx, newProvider, texts)
+
+	dropped := map[*string]bool{}
+	for i, f := range fields {
+		verdict, ok := verdicts[i]
+		if !ok {
+			continue
+		}
+		finding := models.ComplianceFinding{
+			Field:    f.name,
+			Category: verdict.category,
+			Source:   verdict.source,
+			Action:   ComplianceRewritten,
+			Original: *f.text,
+		}
+		switch {
+		case verdict.rewrite != "":
+			*f.text = verdict.rewrite
+		case f.name == "overall" || f.name == "recommendation":
+			*f.text = removeNoncompliantText(*f.text, lang)
+		default:
+			finding.Action = ComplianceDropped
+			dropped[f.text] = true
+		}
+		if finding.Action == ComplianceRewritten {
+			finding.Rewritten = *f.text
+		}
+		result.Findings = append(result.Findings, finding)
+	}
+
+	keep := func(items []string) []string {
+		kept := make([]string, 0, len(items))
+		for i := range items {
+			if !dropped[&items[i]] {
+				kept = append(kept, items[i])
+			}
+		}
+		return kept
+	}
+	resp.Strengths = keep(resp.Strengths)
+	resp.Weaknesses = keep(resp.Weaknesses)
+	resp.FurtherQuestions = keep(resp.FurtherQuestions)
+	resp.RiskPoints = keep(resp.RiskPoints)
+	resp.Suggestions = keep(resp.Suggestions)
+
+	result.log("面试总结")
+	return result
+}
+
+// CheckChatCompliance 检查多轮问答的回答：模型给出改写时替换整段回答，否则删除命中规则的句子
+func CheckChatCompliance(ctx context.Context, newProvider ProviderFactory, lang string, answer *string) *ComplianceResult {
+	result := &ComplianceResult{}
+	if complianceMode() == complianceModeOff || answer == nil {
+		return result
+	}
+
+	verdicts := result.check(ctx, newProvider, []string{*answer})
+	if verdict, ok := verdicts[0]; ok {
+		finding := models.ComplianceFinding{
+			Field:    "answer",
+			Category: verdict.category,
+			Source:   verdict.source,
+			Action:   ComplianceRewritten,
+			Original: *answer,
+		}
+		if verdict.rewrite != "" {
+			*answer = verdict.rewrite
+		} else {
+			*answer = removeNoncompliantText(*answer, lang)
+		}
+		finding.Rewritten = *answer
+		result.Findings = append(result.Findings, finding)
+	}
+	result.log("问答回答")
+	return result
+}
+
+// check 用规则检查每个条目，启用模型分类时再由模型检查并给出改写，返回违规条目的下标到结论
+// 模型分类失败时只使用规则的结果，不影响请求
+func (r *ComplianceResult) check(ctx context.Context, newProvider ProviderFactory, texts []string) map[int]complianceVerdict {
+	verdicts := map[int]complianceVerdict{}
+	for i, text := range texts {
+		if category := MatchComplianceRule(text); category != "" {
+			verdicts[i] = complianceVerdict{category: category, source: complianceSourceRule}
+		}
+	}
+	if complianceMode() != complianceModeLLM || newProvider == nil || len(texts) == 0 {
+		return verdicts
+	}
+
+	violations, result, err := classifyCompliance(ctx, newProvider, texts)
+	if result != nil {
+		r.Results = append(r.Results, result)
+	}
+	if err != nil {
+		log.Printf("[WARN] 合规分类失败，只使用规则检查的结果: %v", err)
+		return verdicts
+	}
+	for _, v := range violations {
+		i := v.Index - 1
+		if i < 0 || i >= len(texts) {
+			continue
+		}
+		verdict, ok := verdicts[i]
+		if !ok {
+			verdict = complianceVerdict{category: v.Category, source: complianceSourceLLM}
+		}
+		// 改写仍命中规则时不采用
+		if rewrite := strings.TrimSpace(v.Rewrite); rewrite != "" && MatchComplianceRule(rewrite) == "" {
+			verdict.rewrite = rewrite
+		}
+		verdicts[i] = verdict
+	}
+	return verdicts
+}
+
+// classifyCompliance 调用模型识别违规条目
+func classifyCompliance(ctx context.Context, newProvider ProviderFactory, texts []string) ([]models.ComplianceViolation, *GenerateResult, error) {
+	input := CompliancePromptInput{Items: make([]ComplianceItem, len(texts))}
+	for i, text := range texts {
+		input.Items[i] = ComplianceItem{Index: i + 1, Text: strings.ReplaceAll(text, "\n", " ")}
+	}
+	rendered, err := CompliancePrompt.Render(input)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	provider := newProvider(rendered.PromptRef)
+	schema := WithResponseSchema(SchemaFor(models.ComplianceCheckResponse{}))
+	params := WithGenerationParams(ParamsForTask(TaskCompliance))
+	resp, result, err := GenerateJSON[models.ComplianceCheckResponse]("合规检查", rendered.Prompt, func(p string) (*GenerateResult, error) {
+		return provider.GenerateContent(ctx, rendered.System, p, schema, params)
+	})
+	if err != nil {
+		return nil, result, err
+	}
+	return resp.Violations, result, nil
+}
+
+// sentenceRe 按句末标点和换行拆分句子，标点保留在句子中
+var sentenceRe = regexp.MustCompile(`[^。！？!?\n]+[。！？!?]*|\n`)
+
+// removeNoncompliantSentences 删除命中规则的句子
+func removeNoncompliantSentences(text string) string {
+	var b strings.Builder
+	for _, sentence := range sentenceRe.FindAllString(text, -1) {
+		if MatchComplianceRule(sentence) == "" {
+			b.WriteString(sentence)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// removeNoncompliantText 删除必填文本中命中规则的句子，没有可删除的句子或全部违规时返回占位文本
+func removeNoncompliantText(text, lang string) string {
+	cleaned := removeNoncompliantSentences(text)
+	if cleaned == strings.TrimSpace(text) {
+		cleaned = ""
+	}
+	if cleaned == "" {
+		cleaned = removedPlaceholders[lang]
+		if cleaned == "" {
+			cleaned = removedPlaceholders[DefaultLanguage]
+		}
+	}
+	return cleaned
+}
+
+// streamSentenceEnds 流式检查时句子的结束字符：句末标点、换行，以及 JSON 字符串的引号
+const streamSentenceEnds = "。！？!?\n\""
+
+// ComplianceStreamFilter 在流式输出发送给客户端之前按规则逐句检查，只放行不命中规则的句子
+// 未结束的句子留到后续输出或 Flush 时再检查；命中规则的句子只保留结尾的换行或引号，输出为 JSON 时不破坏字符串的边界。
+// 流结束后仍需对完整结果调用 CheckQuestionsCompliance 等做完整检查
+type ComplianceStreamFilter struct {
+	enabled bool
+	pending string
+}
+
+// NewComplianceStreamFilter 按 COMPLIANCE_CHECK 创建流式检查，关闭检查时原样放行
+func NewComplianceStreamFilter() *ComplianceStreamFilter {
+	return &ComplianceStreamFilter{enabled: complianceMode() != complianceModeOff}
+}
+
+// Write 加入一段流式输出，返回其中已结束并通过检查的句子
+func (f *ComplianceStreamFilter) Write(chunk string) string {
+	if !f.enabled {
+		return chunk
+	}
+	f.pending += chunk
+	end := strings.LastIndexAny(f.pending, streamSentenceEnds)
+	if end < 0 {
+		return ""
+	}
+	_, size := utf8.DecodeRuneInString(f.pending[end:])
+	complete := f.pending[:end+size]
+	f.pending = f.pending[end+size:]
+	return filterStreamSentences(complete)
+}
+
+// Flush 流结束时检查剩余的未结束句子
+func (f *ComplianceStreamFilter) Flush() string {
+	rest := f.pending
+	f.pending = ""
+	if !f.enabled {
+		return rest
+	}
+	return filterStreamSentences(rest)
+}
+
+// filterStreamSentences 删除命中规则的句子，保留其结尾的换行或引号
+func filterStreamSentences(text string) string {
+	var b strings.Builder
+	start := 0
+	for i, r := range text {
+		if !strings.ContainsRune(streamSentenceEnds, r) {
+			continue
+		}
+		end := i + utf8.RuneLen(r)
+		writeCheckedSentence(&b, text[start:end])
+		start = end
+	}
+	writeCheckedSentence(&b, text[start:])
+	return b.String()
+}
+
+// writeCheckedSentence 写入通过检查的句子，命中规则时只写入结尾的换行或引号
+func writeCheckedSentence(b *strings.Builder, sentence string) {
+	if sentence == "" {
+		return
+	}
+	if MatchComplianceRule(sentence) == "" {
+		b.WriteString(sentence)
+		return
+	}
+	if last := sentence[len(sentence)-1]; last == '\n' || last == '"' {
+		b.WriteByte(last)
+	}
+}
+
+// complianceActionLabels 处理方式在日志中的名称
+var complianceActionLabels = map[string]string{ComplianceDropped: "删除", ComplianceRewritten: "改写"}
+
+// log 记录被过滤的内容
+func (r *ComplianceResult) log(kind string) {
+	for _, f := range r.Findings {
+		log.Printf("[WARN] %s中的 %s 涉及 %s（%s），已%s: %s", kind, f.Field, f.Category, f.Source, complianceActionLabels[f.Action], f.Original)
+	}
+}
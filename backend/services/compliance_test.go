@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestMatchComplianceRule(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"你有没有孩子？", CompliancePregnancy},
+		{"请问子女由谁照顾？", CompliancePregnancy},
+		{"入职后育儿压力会不会影响工作？", CompliancePregnancy},
+		{"候选人需要接送孩子，出差可能受限", CompliancePregnancy},
+		{"お子さんはいますか？", CompliancePregnancy},
+		{"子供を作る予定はありますか？", CompliancePregnancy},
+		{"你是什么民族？", ComplianceEthnicity},
+		{"候选人是少数民族", ComplianceEthnicity},
+		{"请介绍你负责的育儿类App的增长策略", ""},
+		{"描述一次为子女教育平台设计推荐系统的经历", ""},
+		{"你如何在民族品牌的营销中平衡传统与创新？", ""},
+		{"子供向け教育サービスの開発経験を教えてください", ""},
+		{"How did you scale the payment service?", ""},
+	}
+	for _, tt := range tests {
+		if got := MatchComplianceRule(tt.text); got != tt.want {
+			t.Errorf("MatchComplianceRule(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCheckChatCompliance(t *testing.T) {
+	t.Setenv("COMPLIANCE_CHECK", "rules")
+
+	answer := "他的 Kubernetes 经验较深。建议追问他有没有孩子。"
+	result := CheckChatCompliance(context.Background(), nil, LangZh, &answer)
+	if answer != "他的 Kubernetes 经验较深。" {
+		t.Fatalf("answer = %q", answer)
+	}
+	if len(result.Findings) != 1 || result.Findings[0].Field != "answer" || result.Findings[0].Action != ComplianceRewritten {
+		t.Fatalf("findings = %+v", result.Findings)
+	}
+
+	answer = "你有没有孩子？"
+	CheckChatCompliance(context.Background(), nil, LangZh, &answer)
+	if answer != removedPlaceholders[LangZh] {
+		t.Fatalf("answer = %q, want placeholder", answer)
+	}
+
+	clean := "可以追问系统设计相关的问题。"
+	answer = clean
+	if result := CheckChatCompliance(context.Background(), nil, LangZh, &answer); len(result.Findings) != 0 || answer != clean {
+		t.Fatalf("unexpected change: %q %+v", answer, result.Findings)
+	}
+}
+
+func TestComplianceStreamFilter(t *testing.T) {
+	t.Setenv("COMPLIANCE_CHECK", "rules")
+	stream := `{"questions":[{"question":"请介绍一个你主导的Go项目。","answer":"考察项目经验"},` +
+		`{"question":"你结婚了吗？打算什么时候要孩子？","answer":"了解家庭情况"}]}`
+	want := `{"questions":[{"question":"请介绍一个你主导的Go项目。","answer":"考察项目经验"},` +
+		`{"question":"","answer":"了解家庭情况"}]}`
+
+	// 按不同的长度切分，句子跨越多段输出时也应整句检查
+	for _, size := range []int{1, 3, 7, 64, len(stream)} {
+		filter := NewComplianceStreamFilter()
+		var got strings.Builder
+		runes := []rune(stream)
+		for i := 0; i < len(runes); i += size {
+			got.WriteString(filter.Write(string(runes[i:min(i+size, len(runes))])))
+		}
+		got.WriteString(filter.Flush())
+		if got.String() != want {
+			t.Errorf("chunk size %d: got %s", size, got.String())
+		}
+		if !json.Valid([]byte(got.String())) {
+			t.Errorf("chunk size %d: output is not valid JSON", size)
+		}
+	}
+
+	filter := NewComplianceStreamFilter()
+	got := filter.Write("您好，请问你多大了") + filter.Flush()
+	if got != "" {
+		t.Errorf("unterminated sentence: got %q, want it removed", got)
+	}
+
+	t.Setenv("COMPLIANCE_CHECK", "off")
+	filter = NewComplianceStreamFilter()
+	if got := filter.Write("你多大了"); got != "你多大了" {
+		t.Errorf("off: got %q", got)
+	}
+}
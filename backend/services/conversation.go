@@ -0,0 +1,114 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/GiantClam/ai-resume/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// defaultChatHistoryMessages 每次提问时放入提示的最近消息条数
+const defaultChatHistoryMessages = 20
+
+// 对话存储错误
+var (
+	ErrConversationStoreDisabled = errors.New("对话记录未启用")
+	ErrConversationNotFound      = errors.New("对话不存在")
+)
+
+// conversationDB 对话记录使用的数据库连接
+var conversationDB *gorm.DB
+
+// InitConversationStore 设置对话记录使用的数据库连接，调用前需完成 models.Conversation 和 models.ConversationMessage 的迁移
+func InitConversationStore(db *gorm.DB) {
+	conversationDB = db
+}
+
+// CreateConversation 保存新对话并分配ID
+func CreateConversation(conv *models.Conversation) error {
+	if conversationDB == nil {
+		return ErrConversationStoreDisabled
+	}
+	conv.ID = uuid.New().String()
+	return conversationDB.Create(conv).Error
+}
+
+// GetConversation 查询对话，登录用户创建的对话只有该用户可以访问
+func GetConversation(id string, userID uint) (*models.Conversation, error) {
+	if conversationDB == nil {
+		return nil, ErrConversationStoreDisabled
+	}
+
+	var conv models.Conversation
+	err := conversationDB.Where("id = ?", id).First(&conv).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrConversationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if conv.UserID != 0 && conv.UserID != userID {
+		return nil, ErrConversationNotFound
+	}
+	return &conv, nil
+}
+
+// ConversationHistory 按时间顺序返回对话最近的 limit 条消息，limit 不大于0时返回全部消息
+func ConversationHistory(conversationID string, limit int) ([]models.ConversationMessage, error) {
+	if conversationDB == nil {
+		return nil, ErrConversationStoreDisabled
+	}
+
+	query := conversationDB.Where("conversation_id = ?", conversationID).Order("id DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	var messages []models.ConversationMessage
+	if err := query.Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages, nil
+}
+
+// AppendExchange 在同一事务中保存面试官的问题和AI的回答，返回保存后的回答
+// 只有回答成功生成后才保存，失败的提问不会留在对话历史中
+func AppendExchange(conversationID, question, answer string) (*models.ConversationMessage, error) {
+	if conversationDB == nil {
+		return nil, ErrConversationStoreDisabled
+	}
+
+	reply := &models.ConversationMessage{ConversationID: conversationID, Role: models.ChatRoleAssistant, Content: answer}
+	err := conversationDB.Transaction(func(tx *gorm.DB) error {
+		asked := &models.ConversationMessage{ConversationID: conversationID, Role: models.ChatRoleUser, Content: question}
+		if err := tx.Create(asked).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(reply).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Conversation{}).Where("id = ?", conversationID).Update("updated_at", time.Now()).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// ChatHistoryLimit 读取 CHAT_HISTORY_MAX_MESSAGES，限制每次提问时放入提示的历史消息条数，为0时不限制
+func ChatHistoryLimit() int {
+	if value := os.Getenv("CHAT_HISTORY_MAX_MESSAGES"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+			return n
+		}
+		log.Printf("[WARN] 无效的 CHAT_HISTORY_MAX_MESSAGES: %s，使用默认值 %d", value, defaultChatHistoryMessages)
+	}
+	return defaultChatHistoryMessages
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"errors"
+	"io"
+)
+
+// 解压后数据的上限，防止压缩炸弹（几十 MB 的上传解压为几十 GB）耗尽内存
+const (
+	maxDecodedPartSize     = 32 << 20  // 单个 PDF 流或压缩包部件解压后的最大字节数
+	maxDecodedDocumentSize = 128 << 20 // 一个文档所有流和部件解压后的合计最大字节数
+)
+
+// ErrDocumentTooLarge 文档解压后的内容超过上限
+var ErrDocumentTooLarge = errors.New("文档解压后的内容过大")
+
+// decodeBudget 一个文档解压数据的剩余额度
+type decodeBudget struct {
+	remaining int64
+	exceeded  bool
+}
+
+// newDecodeBudget 返回一个文档的解压额度
+func newDecodeBudget() *decodeBudget {
+	return &decodeBudget{remaining: maxDecodedDocumentSize}
+}
+
+// reader 返回计入额度的 Reader，单个部件超过 maxDecodedPartSize 或文档合计超过额度时返回 ErrDocumentTooLarge
+func (b *decodeBudget) reader(r io.Reader) io.Reader {
+	return &budgetReader{r: r, budget: b, part: maxDecodedPartSize}
+}
+
+// limit 返回下一个部件最多可以解压的字节数
+func (b *decodeBudget) limit() int {
+	return int(min(b.remaining, maxDecodedPartSize))
+}
+
+// charge 从额度中扣除 n 字节，超出时返回 ErrDocumentTooLarge
+func (b *decodeBudget) charge(n int) error {
+	if int64(n) > min(b.remaining, maxDecodedPartSize) {
+		b.exceeded = true
+		return ErrDocumentTooLarge
+	}
+	b.remaining -= int64(n)
+	return nil
+}
+
+// budgetReader 读取时扣除额度
+type budgetReader struct {
+	r      io.Reader
+	budget *decodeBudget
+	part   int64
+}
+
+// Read 在额度内读取，额度用完后只允许读到数据恰好结束
+func (r *budgetReader) Read(p []byte) (int, error) {
+	limit := min(r.part, r.budget.remaining)
+	if limit <= 0 {
+		var probe [1]byte
+		if n, err := r.r.Read(probe[:]); n == 0 && err != nil {
+			return 0, err
+		}
+		r.budget.exceeded = true
+		return 0, ErrDocumentTooLarge
+	}
+	if int64(len(p)) > limit {
+		p = p[:limit]
+	}
+	n, err := r.r.Read(p)
+	r.part -= int64(n)
+	r.budget.remaining -= int64(n)
+	return n, err
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// zeroReader 无限输出 0
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+// deflateBomb 返回解压后为 n 字节 0 的 zlib 数据
+func deflateBomb(t testing.TB, n int64) []byte {
+	var buf bytes.Buffer
+	w, _ := zlib.NewWriterLevel(&buf, zlib.BestCompression)
+	if _, err := io.CopyN(w, zeroReader{}, n); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestBudgetReader(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int64
+		wantErr bool
+	}{
+		{"small", 1024, false},
+		{"exactly part limit", maxDecodedPartSize, false},
+		{"over part limit", maxDecodedPartSize + 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newDecodeBudget()
+			n, err := io.Copy(io.Discard, b.reader(io.LimitReader(zeroReader{}, tt.size)))
+			if gotErr := errors.Is(err, ErrDocumentTooLarge); gotErr != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && n != tt.size {
+				t.Errorf("read %d bytes, want %d", n, tt.size)
+			}
+		})
+	}
+
+	// 多个部件合计超过文档额度
+	b := newDecodeBudget()
+	var err error
+	for i := 0; i < 5 && err == nil; i++ {
+		_, err = io.Copy(io.Discard, b.reader(io.LimitReader(zeroReader{}, maxDecodedPartSize)))
+	}
+	if !errors.Is(err, ErrDocumentTooLarge) {
+		t.Errorf("document budget: err = %v, want ErrDocumentTooLarge", err)
+	}
+}
+
+func TestExtractPDFTextDeflateBomb(t *testing.T) {
+	bomb := deflateBomb(t, 2*maxDecodedPartSize)
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj\n")
+	pdf.WriteString("2 0 obj << /Type /Pages /Kids [3 0 R] /Count 1 >> endobj\n")
+	pdf.WriteString("3 0 obj << /Type /Page /Parent 2 0 R /Contents 4 0 R >> endobj\n")
+	fmt.Fprintf(&pdf, "4 0 obj << /Length %d /Filter /FlateDecode >> stream\n", len(bomb))
+	pdf.Write(bomb)
+	pdf.WriteString("\nendstream endobj\ntrailer << /Root 1 0 R >>\n%%EOF\n")
+
+	if _, err := ExtractPDFText(pdf.Bytes()); !errors.Is(err, ErrDocumentTooLarge) {
+		t.Fatalf("err = %v, want ErrDocumentTooLarge", err)
+	}
+}
+
+func TestExtractDocxTextZipBomb(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create(docxDocumentPart)
+	io.WriteString(w, `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body><w:p><w:r><w:t>`)
+	io.CopyN(w, zeroReader{}, maxDecodedPartSize+1)
+	zw.Close()
+
+	if _, err := ExtractDocxText(buf.Bytes()); !errors.Is(err, ErrDocumentTooLarge) {
+		t.Fatalf("docx err = %v, want ErrDocumentTooLarge", err)
+	}
+	if _, err := extractDocument(context.Background(), buf.Bytes(), FormatDOCX); !errors.Is(err, ErrDocumentTooLarge) {
+		t.Fatalf("extractDocument err = %v, want ErrDocumentTooLarge without conversion fallback", err)
+	}
+}
+
+func TestExtractODTTextZipBomb(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create(odtManifestPart)
+	io.CopyN(w, zeroReader{}, maxDecodedPartSize+1)
+	w, _ = zw.Create(odtContentPart)
+	io.WriteString(w, `<office:document-content/>`)
+	zw.Close()
+
+	if _, err := ExtractODTText(buf.Bytes()); !errors.Is(err, ErrDocumentTooLarge) {
+		t.Fatalf("odt err = %v, want ErrDocumentTooLarge", err)
+	}
+}
@@ -0,0 +1,410 @@
+package services
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// ErrUnsupportedDocVersion Word 95 及更早版本的 .doc 文件，没有分段表，需要转换后提取
+var ErrUnsupportedDocVersion = errors.New("不支持 Word 97 之前版本的 .doc 文件")
+
+// Word 97-2003 文件信息块（FIB）中用到的字段
+const (
+	docMagic           = 0xA5EC
+	docMinFib          = 0x00C1 // Word 97
+	docFlagEncrypted   = 0x0100
+	docFlagWhichTblStm = 0x0200
+	docClxIndex        = 33 // fcClx/lcbClx 在 FibRgFcLcb97 中的序号
+)
+
+// ExtractDocText 从 Word 97-2003（.doc）文件中提取正文
+// 读取复合文档中的 WordDocument 流，按分段表（piece table）拼接正文，去掉域代码
+func ExtractDocText(data []byte) (string, error) {
+	cfb, err := openCompoundFile(data)
+	if err != nil {
+		return "", err
+	}
+	wordDoc, err := cfb.stream("WordDocument")
+	if err != nil {
+		return "", err
+	}
+	if len(wordDoc) < 34 || binary.LittleEndian.Uint16(wordDoc) != docMagic {
+		return "", fmt.Errorf("无效的Word文件: 缺少文件信息块")
+	}
+	if binary.LittleEndian.Uint16(wordDoc[2:]) < docMinFib {
+		return "", ErrUnsupportedDocVersion
+	}
+	flags := binary.LittleEndian.Uint16(wordDoc[0x0A:])
+	if flags&docFlagEncrypted != 0 {
+		return "", fmt.Errorf("%w: Word文件受密码保护", ErrDocumentEncrypted)
+	}
+
+	// FibBase 之后依次为 csw + fibRgW、cslw + fibRgLw、cbRgFcLcb + fibRgFcLcb
+	pos := 32
+	csw := int(binary.LittleEndian.Uint16(wordDoc[pos:]))
+	pos += 2 + csw*2
+	if pos+2 > len(wordDoc) {
+		return "", fmt.Errorf("无效的Word文件: 文件信息块不完整")
+	}
+	cslw := int(binary.LittleEndian.Uint16(wordDoc[pos:]))
+	rgLw := pos + 2
+	pos = rgLw + cslw*4
+	if rgLw+16 > len(wordDoc) || pos+2 > len(wordDoc) {
+		return "", fmt.Errorf("无效的Word文件: 文件信息块不完整")
+	}
+	ccpText := binary.LittleEndian.Uint32(wordDoc[rgLw+12:])
+	cbRgFcLcb := int(binary.LittleEndian.Uint16(wordDoc[pos:]))
+	rgFcLcb := pos + 2
+	if cbRgFcLcb <= docClxIndex || rgFcLcb+(docClxIndex+1)*8 > len(wordDoc) {
+		return "", fmt.Errorf("无效的Word文件: 缺少分段表位置")
+	}
+	fcClx := binary.LittleEndian.Uint32(wordDoc[rgFcLcb+docClxIndex*8:])
+	lcbClx := binary.LittleEndian.Uint32(wordDoc[rgFcLcb+docClxIndex*8+4:])
+
+	tableName := "0Table"
+	if flags&docFlagWhichTblStm != 0 {
+		tableName = "1Table"
+	}
+	table, err := cfb.stream(tableName)
+	if err != nil {
+		return "", err
+	}
+	if uint64(fcClx)+uint64(lcbClx) > uint64(len(table)) {
+		return "", fmt.Errorf("无效的Word文件: 分段表超出范围")
+	}
+
+	// 分段之间不会重叠，正文的字符数不会超过 WordDocument 流的字节数
+	raw, err := docPieceText(wordDoc, table[fcClx:fcClx+lcbClx], min(ccpText, uint32(len(wordDoc))))
+	if err != nil {
+		return "", err
+	}
+	return cleanDocText(raw), nil
+}
+
+// docPieceText 按分段表读取正文的字符，最多读取 limit 个字符
+func docPieceText(wordDoc, clx []byte, limit uint32) ([]rune, error) {
+	// Clx 由若干 Prc（0x01）和一个 Pcdt（0x02）组成
+	for len(clx) > 0 && clx[0] == 0x01 {
+		if len(clx) < 3 {
+			return nil, fmt.Errorf("无效的Word文件: 分段表损坏")
+		}
+		size := 3 + int(binary.LittleEndian.Uint16(clx[1:]))
+		if size > len(clx) {
+			return nil, fmt.Errorf("无效的Word文件: 分段表损坏")
+		}
+		clx = clx[size:]
+	}
+	if len(clx) < 5 || clx[0] != 0x02 {
+		return nil, fmt.Errorf("无效的Word文件: 缺少分段表")
+	}
+	plc := clx[5:]
+	if lcb := int(binary.LittleEndian.Uint32(clx[1:])); lcb < len(plc) {
+		plc = plc[:lcb]
+	}
+	n := (len(plc) - 4) / 12
+	if n <= 0 {
+		return nil, fmt.Errorf("无效的Word文件: 分段表为空")
+	}
+
+	var text []rune
+	for i := 0; i < n && uint32(len(text)) < limit; i++ {
+		start := binary.LittleEndian.Uint32(plc[i*4:])
+		end := binary.LittleEndian.Uint32(plc[(i+1)*4:])
+		if end <= start {
+			continue
+		}
+		count := end - start
+		if remaining := limit - uint32(len(text)); count > remaining {
+			count = remaining
+		}
+
+		fc := binary.LittleEndian.Uint32(plc[(n+1)*4+i*8+2:])
+		if fc&0x40000000 != 0 {
+			// 压缩存储：每个字符一个字节，使用 Windows-1252
+			offset := uint64(fc&0x3FFFFFFF) / 2
+			if offset+uint64(count) > uint64(len(wordDoc)) {
+				return nil, fmt.Errorf("无效的Word文件: 正文超出范围")
+			}
+			decoded, err := charmap.Windows1252.NewDecoder().Bytes(wordDoc[offset : offset+uint64(count)])
+			if err != nil {
+				return nil, err
+			}
+			text = append(text, []rune(string(decoded))...)
+			continue
+		}
+		offset := uint64(fc)
+		if offset+uint64(count)*2 > uint64(len(wordDoc)) {
+			return nil, fmt.Errorf("无效的Word文件: 正文超出范围")
+		}
+		units := make([]uint16, count)
+		for j := range units {
+			units[j] = binary.LittleEndian.Uint16(wordDoc[offset+uint64(j)*2:])
+		}
+		text = append(text, utf16.Decode(units)...)
+	}
+	return text, nil
+}
+
+// cleanDocText 处理正文中的特殊字符：段落、单元格和换行标记转为换行或制表符，去掉域代码和对象占位符
+func cleanDocText(raw []rune) string {
+	var b strings.Builder
+	// 每层域记录是否已到达域结果部分
+	var fields []bool
+	var prev rune
+	for _, r := range raw {
+		// 行结束标记紧跟在最后一个单元格标记之后
+		rowEnd := r == 0x07 && prev == 0x07
+		prev = r
+
+		switch r {
+		case 0x13: // 域开始
+			fields = append(fields, false)
+			continue
+		case 0x14: // 域分隔符，之后是域结果
+			if n := len(fields); n > 0 {
+				fields[n-1] = true
+			}
+			continue
+		case 0x15: // 域结束
+			if n := len(fields); n > 0 {
+				fields = fields[:n-1]
+			}
+			continue
+		}
+		if n := len(fields); n > 0 && !fields[n-1] {
+			continue
+		}
+
+		switch r {
+		case '\r', 0x0B, 0x0C:
+			b.WriteByte('\n')
+		case 0x07: // 单元格结束
+			if rowEnd {
+				b.WriteByte('\n')
+				prev = 0
+			} else {
+				b.WriteByte('\t')
+			}
+		case 0x1E:
+			b.WriteByte('-')
+		case 0x1F, 0x01, 0x08, 0x02, 0x05:
+			// 可选连字符、图片和对象、脚注引用、批注引用
+		case 0xA0:
+			b.WriteByte(' ')
+		default:
+			if r >= ' ' || r == '\t' {
+				b.WriteRune(r)
+			}
+		}
+	}
+
+	// 行末的单元格标记和多余的空行
+	lines := strings.Split(b.String(), "\n")
+	out := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimRight(line, "\t ")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
+
+// 复合文档（OLE2/CFB）中的特殊扇区号
+const (
+	cfbEndOfChain = 0xFFFFFFFE
+	cfbFreeSect   = 0xFFFFFFFF
+)
+
+// compoundFile Office 97-2003 使用的复合文档
+type compoundFile struct {
+	data           []byte
+	sectorSize     int
+	miniSectorSize int
+	miniCutoff     uint64
+	fat            []uint32
+	miniFAT        []uint32
+	miniStream     []byte
+	entries        []cfbEntry
+}
+
+// cfbEntry 目录项
+type cfbEntry struct {
+	name  string
+	kind  byte // 1 存储，2 流，5 根
+	start uint32
+	size  uint64
+}
+
+// openCompoundFile 解析复合文档的文件头、FAT 和目录
+func openCompoundFile(data []byte) (*compoundFile, error) {
+	if len(data) < 512 || string(data[:8]) != string(oleSignature) {
+		return nil, fmt.Errorf("无效的Word文件: 不是复合文档")
+	}
+	shift := binary.LittleEndian.Uint16(data[0x1E:])
+	miniShift := binary.LittleEndian.Uint16(data[0x20:])
+	if shift < 7 || shift > 16 || miniShift > shift {
+		return nil, fmt.Errorf("无效的Word文件: 扇区大小错误")
+	}
+	cf := &compoundFile{
+		data:           data,
+		sectorSize:     1 << shift,
+		miniSectorSize: 1 << miniShift,
+		miniCutoff:     uint64(binary.LittleEndian.Uint32(data[0x38:])),
+	}
+
+	// 文件头中的 109 个 DIFAT 项，之后是 DIFAT 扇区链
+	var fatSectors []uint32
+	for i := 0; i < 109; i++ {
+		if s := binary.LittleEndian.Uint32(data[0x4C+i*4:]); s < cfbEndOfChain-4 {
+			fatSectors = append(fatSectors, s)
+		}
+	}
+	perSector := cf.sectorSize/4 - 1
+	difat := binary.LittleEndian.Uint32(data[0x44:])
+	for seen := 0; difat < cfbEndOfChain-4 && seen < len(data)/cf.sectorSize; seen++ {
+		sector, ok := cf.sector(difat)
+		if !ok {
+			break
+		}
+		for i := 0; i < perSector; i++ {
+			if s := binary.LittleEndian.Uint32(sector[i*4:]); s < cfbEndOfChain-4 {
+				fatSectors = append(fatSectors, s)
+			}
+		}
+		difat = binary.LittleEndian.Uint32(sector[perSector*4:])
+	}
+	// 同一扇区重复出现在 DIFAT 中会使 FAT 成倍膨胀，直接视为损坏的文件
+	fatSeen := make(map[uint32]bool, len(fatSectors))
+	for _, s := range fatSectors {
+		if fatSeen[s] {
+			return nil, fmt.Errorf("无效的Word文件: FAT 扇区 %d 重复", s)
+		}
+		fatSeen[s] = true
+		sector, ok := cf.sector(s)
+		if !ok {
+			return nil, fmt.Errorf("无效的Word文件: FAT 扇区超出范围")
+		}
+		for i := 0; i+4 <= len(sector); i += 4 {
+			cf.fat = append(cf.fat, binary.LittleEndian.Uint32(sector[i:]))
+		}
+	}
+
+	limit := uint64(len(data))
+	dir, err := cf.chain(binary.LittleEndian.Uint32(data[0x30:]), cf.fat, cf.sectorSize, cf.sector, limit)
+	if err != nil {
+		return nil, fmt.Errorf("无效的Word文件: 无法读取目录: %w", err)
+	}
+	for i := 0; i+128 <= len(dir); i += 128 {
+		e := dir[i : i+128]
+		nameLen := int(binary.LittleEndian.Uint16(e[0x40:]))
+		if nameLen > 64 {
+			nameLen = 64
+		}
+		units := make([]uint16, 0, nameLen/2)
+		for j := 0; j+1 < nameLen; j += 2 {
+			if u := binary.LittleEndian.Uint16(e[j:]); u != 0 {
+				units = append(units, u)
+			}
+		}
+		size := binary.LittleEndian.Uint64(e[0x78:])
+		if cf.sectorSize == 512 {
+			// 版本 3 的文件只使用低 32 位
+			size &= 0xFFFFFFFF
+		}
+		cf.entries = append(cf.entries, cfbEntry{
+			name:  string(utf16.Decode(units)),
+			kind:  e[0x42],
+			start: binary.LittleEndian.Uint32(e[0x74:]),
+			size:  size,
+		})
+	}
+	if len(cf.entries) == 0 || cf.entries[0].kind != 5 {
+		return nil, fmt.Errorf("无效的Word文件: 缺少根目录")
+	}
+
+	// 小于 miniCutoff 的流存放在根目录的迷你流中
+	root := cf.entries[0]
+	if cf.miniStream, err = cf.chain(root.start, cf.fat, cf.sectorSize, cf.sector, min(root.size, limit)); err != nil {
+		return nil, fmt.Errorf("无效的Word文件: 无法读取迷你流: %w", err)
+	}
+	miniFAT, err := cf.chain(binary.LittleEndian.Uint32(data[0x3C:]), cf.fat, cf.sectorSize, cf.sector, limit)
+	if err != nil {
+		return nil, fmt.Errorf("无效的Word文件: 无法读取迷你 FAT: %w", err)
+	}
+	for i := 0; i+4 <= len(miniFAT); i += 4 {
+		cf.miniFAT = append(cf.miniFAT, binary.LittleEndian.Uint32(miniFAT[i:]))
+	}
+	return cf, nil
+}
+
+// sector 返回扇区内容
+func (cf *compoundFile) sector(n uint32) ([]byte, bool) {
+	offset := (uint64(n) + 1) * uint64(cf.sectorSize)
+	if offset+uint64(cf.sectorSize) > uint64(len(cf.data)) {
+		return nil, false
+	}
+	return cf.data[offset : offset+uint64(cf.sectorSize)], true
+}
+
+// miniSector 返回迷你扇区内容
+func (cf *compoundFile) miniSector(n uint32) ([]byte, bool) {
+	offset := uint64(n) * uint64(cf.miniSectorSize)
+	if offset+uint64(cf.miniSectorSize) > uint64(len(cf.miniStream)) {
+		return nil, false
+	}
+	return cf.miniStream[offset : offset+uint64(cf.miniSectorSize)], true
+}
+
+// chain 沿分配表读取扇区链，最多读取 limit 字节
+// 重复访问同一扇区视为循环，恶意构造的文件无法让读取的数据超过文件本身的大小
+func (cf *compoundFile) chain(start uint32, table []uint32, size int, read func(uint32) ([]byte, bool), limit uint64) ([]byte, error) {
+	var out []byte
+	visited := make(map[uint32]bool)
+	for n := start; n != cfbEndOfChain && n != cfbFreeSect && uint64(len(out)) < limit; n = table[n] {
+		if visited[n] {
+			return nil, fmt.Errorf("扇区链存在循环")
+		}
+		visited[n] = true
+		sector, ok := read(n)
+		if !ok || int(n) >= len(table) {
+			return nil, fmt.Errorf("扇区 %d 超出范围", n)
+		}
+		out = append(out, sector[:size]...)
+	}
+	if uint64(len(out)) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// stream 按名称读取流
+func (cf *compoundFile) stream(name string) ([]byte, error) {
+	for _, e := range cf.entries {
+		if e.kind != 2 || e.name != name {
+			continue
+		}
+		var data []byte
+		var err error
+		limit := min(e.size, uint64(len(cf.data)))
+		if e.size < cf.miniCutoff {
+			data, err = cf.chain(e.start, cf.miniFAT, cf.miniSectorSize, cf.miniSector, limit)
+		} else {
+			data, err = cf.chain(e.start, cf.fat, cf.sectorSize, cf.sector, limit)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("无法读取 %s 流: %w", name, err)
+		}
+		if uint64(len(data)) > e.size {
+			data = data[:e.size]
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("无效的Word文件: 缺少 %s 流", name)
+}
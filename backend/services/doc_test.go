@@ -0,0 +1,251 @@
+package services
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+	"unicode/utf16"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// hostileCompoundFile 构造文件头中 109 个 DIFAT 项都指向同一扇区、FAT 全为零的复合文档
+func hostileCompoundFile() []byte {
+	const shift = 16
+	data := make([]byte, 2<<shift)
+	copy(data, oleSignature)
+	binary.LittleEndian.PutUint16(data[0x1E:], shift)
+	binary.LittleEndian.PutUint16(data[0x20:], 6)
+	binary.LittleEndian.PutUint32(data[0x38:], 4096)
+	binary.LittleEndian.PutUint32(data[0x44:], cfbEndOfChain)
+	for i := 0; i < 109; i++ {
+		binary.LittleEndian.PutUint32(data[0x4C+i*4:], 0)
+	}
+	return data
+}
+
+func TestExtractDocTextRejectsDuplicateFATSectors(t *testing.T) {
+	if _, err := ExtractDocText(hostileCompoundFile()); err == nil {
+		t.Fatal("expected error for duplicate FAT sectors")
+	}
+}
+
+func TestCompoundFileChainStopsOnLoop(t *testing.T) {
+	data := hostileCompoundFile()
+	// 只保留一个 FAT 扇区，全零的 FAT 使扇区 0 指向自身
+	for i := 1; i < 109; i++ {
+		binary.LittleEndian.PutUint32(data[0x4C+i*4:], cfbFreeSect)
+	}
+	if _, err := ExtractDocText(data); err == nil {
+		t.Fatal("expected error for sector chain loop")
+	}
+}
+
+// cfbStream 复合文档中的一个流
+type cfbStream struct {
+	name string
+	data []byte
+}
+
+// buildCompoundFile 构造版本 3（512 字节扇区）的复合文档，流按顺序存放在普通扇区中
+func buildCompoundFile(streams ...cfbStream) []byte {
+	const sectorSize = 512
+	pad := func(b []byte) []byte {
+		if r := len(b) % sectorSize; r != 0 || len(b) == 0 {
+			b = append(b, make([]byte, sectorSize-r)...)
+		}
+		return b
+	}
+
+	// 扇区 0 为 FAT，扇区 1 为目录，之后依次是各个流
+	fat := make([]uint32, sectorSize/4)
+	for i := range fat {
+		fat[i] = cfbFreeSect
+	}
+	fat[0], fat[1] = 0xFFFFFFFD, cfbEndOfChain
+
+	dir := make([]byte, sectorSize)
+	entry := func(i int, name string, kind byte, start uint32, size int) {
+		e := dir[i*128 : (i+1)*128]
+		units := utf16.Encode([]rune(name))
+		for j, u := range units {
+			binary.LittleEndian.PutUint16(e[j*2:], u)
+		}
+		binary.LittleEndian.PutUint16(e[0x40:], uint16(len(units)*2+2))
+		e[0x42] = kind
+		for _, off := range []int{0x44, 0x48, 0x4C} {
+			binary.LittleEndian.PutUint32(e[off:], cfbFreeSect)
+		}
+		binary.LittleEndian.PutUint32(e[0x74:], start)
+		binary.LittleEndian.PutUint32(e[0x78:], uint32(size))
+	}
+	entry(0, "Root Entry", 5, cfbEndOfChain, 0)
+
+	var body []byte
+	next := uint32(2)
+	for i, s := range streams {
+		data := pad(append([]byte(nil), s.data...))
+		count := uint32(len(data) / sectorSize)
+		for j := uint32(0); j < count; j++ {
+			fat[next+j] = next + j + 1
+		}
+		fat[next+count-1] = cfbEndOfChain
+		entry(i+1, s.name, 2, next, len(s.data))
+		body = append(body, data...)
+		next += count
+	}
+
+	header := make([]byte, sectorSize)
+	copy(header, oleSignature)
+	binary.LittleEndian.PutUint16(header[0x18:], 0x3E)
+	binary.LittleEndian.PutUint16(header[0x1A:], 3)
+	binary.LittleEndian.PutUint16(header[0x1C:], 0xFFFE)
+	binary.LittleEndian.PutUint16(header[0x1E:], 9)
+	binary.LittleEndian.PutUint16(header[0x20:], 6)
+	binary.LittleEndian.PutUint32(header[0x2C:], 1)
+	binary.LittleEndian.PutUint32(header[0x30:], 1)
+	// 迷你流阈值为 0，所有流都存放在普通扇区中
+	binary.LittleEndian.PutUint32(header[0x38:], 0)
+	binary.LittleEndian.PutUint32(header[0x3C:], cfbEndOfChain)
+	binary.LittleEndian.PutUint32(header[0x44:], cfbEndOfChain)
+	for i := 0; i < 109; i++ {
+		binary.LittleEndian.PutUint32(header[0x4C+i*4:], cfbFreeSect)
+	}
+	binary.LittleEndian.PutUint32(header[0x4C:], 0)
+
+	fatSector := make([]byte, sectorSize)
+	for i, v := range fat {
+		binary.LittleEndian.PutUint32(fatSector[i*4:], v)
+	}
+	out := append(header, fatSector...)
+	out = append(out, dir...)
+	return append(out, body...)
+}
+
+// docPiece 分段表中的一段正文，compressed 为 true 时按单字节 Windows-1252 存储
+type docPiece struct {
+	text       string
+	compressed bool
+}
+
+// buildDoc 构造 Word 97 的 WordDocument 和 0Table 流，flags 为 FIB 中的标志位
+func buildDoc(t testing.TB, nFib, flags uint16, pieces ...docPiece) []byte {
+	return buildDocWithPrc(t, nFib, flags, 0, pieces...)
+}
+
+// buildDocWithPrc 同 buildDoc，prcSize 不为 0 时改写分段表前 Prc 声明的长度
+func buildDocWithPrc(t testing.TB, nFib, flags, prcSize uint16, pieces ...docPiece) []byte {
+	const csw, cslw, cbRgFcLcb = 14, 22, 93
+	fibSize := 32 + 2 + csw*2 + 2 + cslw*4 + 2 + cbRgFcLcb*8
+	wordDoc := make([]byte, fibSize)
+	binary.LittleEndian.PutUint16(wordDoc, docMagic)
+	binary.LittleEndian.PutUint16(wordDoc[2:], nFib)
+	binary.LittleEndian.PutUint16(wordDoc[0x0A:], flags)
+	binary.LittleEndian.PutUint16(wordDoc[32:], csw)
+	rgLw := 32 + 2 + csw*2 + 2
+	binary.LittleEndian.PutUint16(wordDoc[rgLw-2:], cslw)
+	rgFcLcb := rgLw + cslw*4 + 2
+	binary.LittleEndian.PutUint16(wordDoc[rgFcLcb-2:], cbRgFcLcb)
+
+	var cps []uint32
+	var fcs []uint32
+	cp := uint32(0)
+	for _, p := range pieces {
+		cps = append(cps, cp)
+		offset := uint32(len(wordDoc))
+		if p.compressed {
+			encoded, err := charmap.Windows1252.NewEncoder().Bytes([]byte(p.text))
+			if err != nil {
+				t.Fatal(err)
+			}
+			wordDoc = append(wordDoc, encoded...)
+			fcs = append(fcs, offset*2|0x40000000)
+			cp += uint32(len(encoded))
+			continue
+		}
+		units := utf16.Encode([]rune(p.text))
+		for _, u := range units {
+			wordDoc = binary.LittleEndian.AppendUint16(wordDoc, u)
+		}
+		fcs = append(fcs, offset)
+		cp += uint32(len(units))
+	}
+	cps = append(cps, cp)
+	binary.LittleEndian.PutUint32(wordDoc[rgLw+12:], cp)
+
+	var plc []byte
+	for _, c := range cps {
+		plc = binary.LittleEndian.AppendUint32(plc, c)
+	}
+	for _, fc := range fcs {
+		plc = binary.LittleEndian.AppendUint16(plc, 0)
+		plc = binary.LittleEndian.AppendUint32(plc, fc)
+		plc = binary.LittleEndian.AppendUint16(plc, 0)
+	}
+	// 0Table 中在分段表之前放一个 Prc，验证跳过逻辑
+	table := []byte{0x01, 0x02, 0x00, 0xAA, 0xBB, 0x02}
+	if prcSize > 0 {
+		binary.LittleEndian.PutUint16(table[1:], prcSize)
+	}
+	table = binary.LittleEndian.AppendUint32(table, uint32(len(plc)))
+	table = append(table, plc...)
+	binary.LittleEndian.PutUint32(wordDoc[rgFcLcb+docClxIndex*8:], 0)
+	binary.LittleEndian.PutUint32(wordDoc[rgFcLcb+docClxIndex*8+4:], uint32(len(table)))
+
+	return buildCompoundFile(cfbStream{"WordDocument", wordDoc}, cfbStream{"0Table", table})
+}
+
+// testDoc 包含 Unicode 和压缩存储的分段、表格和域代码的 .doc
+func testDoc(t testing.TB) []byte {
+	return buildDoc(t, docMinFib, 0,
+		docPiece{text: "张三\r工作经历\r"},
+		docPiece{text: "Company\x07Title\x07\x07Acme\x07Engineer\x07\x07", compressed: true},
+		docPiece{text: "\x13 HYPERLINK \"https://example.com\" \x14example.com\x15\x0bCafé\u00a0Go\x1edev\r", compressed: true},
+	)
+}
+
+func TestExtractDocText(t *testing.T) {
+	got, err := ExtractDocText(testDoc(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "张三\n工作经历\nCompany\tTitle\nAcme\tEngineer\nexample.com\nCafé Go-dev"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestExtractDocTextErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{"encrypted", buildDoc(t, docMinFib, docFlagEncrypted, docPiece{text: "x\r"}), ErrDocumentEncrypted},
+		{"word 95", buildDoc(t, 0x0065, 0, docPiece{text: "x\r"}), ErrUnsupportedDocVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ExtractDocText(tt.data); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+	if _, err := ExtractDocText(buildCompoundFile(cfbStream{"Workbook", []byte("x")})); err == nil {
+		t.Error("expected error for compound file without WordDocument stream")
+	}
+	if _, err := ExtractDocText(buildDocWithPrc(t, docMinFib, 0, 0xFFFF, docPiece{text: "x\r"})); err == nil {
+		t.Error("expected error for Prc larger than the piece table")
+	}
+	if _, err := ExtractDocText([]byte("not a compound file")); err == nil {
+		t.Error("expected error for non-compound data")
+	}
+}
+
+func FuzzExtractDocText(f *testing.F) {
+	fuzzExtract(f, ExtractDocText,
+		testDoc(f),
+		hostileCompoundFile(),
+		buildDocWithPrc(f, docMinFib, 0, 0xFFFF, docPiece{text: "x\r"}),
+	)
+}
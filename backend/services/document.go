@@ -1,17 +1,73 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 )
 
+// defaultConvertTimeout 一次文档转换的默认截止时间
+const defaultConvertTimeout = 60 * time.Second
+
+// convertSlots 限制同时运行的转换进程数，由 DOCUMENT_CONVERT_CONCURRENCY 配置（默认2）
+var (
+	convertSlots     chan struct{}
+	convertSlotsOnce sync.Once
+)
+
+// ConvertContext 返回文档转换使用的上下文，超时由 DOCUMENT_CONVERT_TIMEOUT 配置（默认60秒）
+func ConvertContext(parent context.Context) (context.Context, context.CancelFunc) {
+	timeout := defaultConvertTimeout
+	if value := os.Getenv("DOCUMENT_CONVERT_TIMEOUT"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			timeout = d
+		} else {
+			log.Printf("[WARN] 无效的 DOCUMENT_CONVERT_TIMEOUT: %s，使用默认值 %v", value, defaultConvertTimeout)
+		}
+	}
+	return context.WithTimeout(parent, timeout)
+}
+
+// acquireConvertSlot 等待空闲的转换名额，上下文结束时返回错误
+func acquireConvertSlot(ctx context.Context) (func(), error) {
+	convertSlotsOnce.Do(func() {
+		convertSlots = make(chan struct{}, max(envInt("DOCUMENT_CONVERT_CONCURRENCY", 2), 1))
+	})
+	select {
+	case convertSlots <- struct{}{}:
+		return func() { <-convertSlots }, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("等待文档转换超时: %w", ctx.Err())
+	}
+}
+
+// runConverter 运行转换命令，上下文结束时终止进程
+func runConverter(ctx context.Context, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	// 进程被终止后，子进程可能仍占用输出管道，最多再等待5秒
+	cmd.WaitDelay = 5 * time.Second
+	output, err := cmd.CombinedOutput()
+	tool := filepath.Base(name)
+	if ctx.Err() != nil {
+		return fmt.Errorf("%s转换超时: %w", tool, ctx.Err())
+	}
+	if err != nil {
+		return fmt.Errorf("%s转换失败: %v, 输出: %s", tool, err, string(output))
+	}
+	return nil
+}
+
 // ConvertDocxToPdf 将Word文档转换为PDF文件
-// 需要系统安装LibreOffice或其他命令行工具
-func ConvertDocxToPdf(inputFile string) (string, error) {
+// 需要系统安装LibreOffice或Pandoc；转换在 ctx 结束时终止，同时运行的转换数受 DOCUMENT_CONVERT_CONCURRENCY 限制
+// 每次调用LibreOffice都使用单独的临时用户配置目录，避免并发转换争用同一配置
+func ConvertDocxToPdf(ctx context.Context, inputFile string) (string, error) {
 	// 检查输入文件是否存在
 	if _, err := os.Stat(inputFile); os.IsNotExist(err) {
 		return "", fmt.Errorf("输入文件不存在: %s", inputFile)
@@ -21,23 +77,32 @@ func ConvertDocxToPdf(inputFile string) (string, error) {
 	fileExt := filepath.Ext(inputFile)
 	pdfFile := strings.TrimSuffix(inputFile, fileExt) + ".pdf"
 
+	release, err := acquireConvertSlot(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
 	log.Printf("尝试将 %s 转换为 %s", inputFile, pdfFile)
 
 	// 检查系统是否安装了LibreOffice
-	libreOfficeCmd := exec.Command("libreoffice", "--version")
-	if err := libreOfficeCmd.Run(); err == nil {
+	if libreOffice := lookPathAny("libreoffice", "soffice"); libreOffice != "" {
+		profile, err := os.MkdirTemp("", "resume-lo-profile-")
+		if err != nil {
+			return "", fmt.Errorf("创建LibreOffice配置目录失败: %w", err)
+		}
+		defer os.RemoveAll(profile)
+
 		// 使用LibreOffice转换
-		cmd := exec.Command(
-			"libreoffice",
+		err = runConverter(ctx, libreOffice,
+			"-env:UserInstallation="+(&url.URL{Scheme: "file", Path: filepath.ToSlash(profile)}).String(),
 			"--headless",
 			"--convert-to", "pdf",
 			"--outdir", filepath.Dir(inputFile),
 			inputFile,
 		)
-
-		output, err := cmd.CombinedOutput()
 		if err != nil {
-			return "", fmt.Errorf("LibreOffice转换失败: %v, 输出: %s", err, string(output))
+			return "", err
 		}
 
 		log.Printf("文件转换成功: %s", pdfFile)
@@ -45,17 +110,9 @@ func ConvertDocxToPdf(inputFile string) (string, error) {
 	}
 
 	// 如果没有LibreOffice，尝试使用其他工具（如pandoc）
-	pandocCmd := exec.Command("pandoc", "--version")
-	if err := pandocCmd.Run(); err == nil {
-		cmd := exec.Command(
-			"pandoc",
-			inputFile,
-			"-o", pdfFile,
-		)
-
-		output, err := cmd.CombinedOutput()
-		if err != nil {
-			return "", fmt.Errorf("Pandoc转换失败: %v, 输出: %s", err, string(output))
+	if pandoc := lookPathAny("pandoc"); pandoc != "" {
+		if err := runConverter(ctx, pandoc, inputFile, "-o", pdfFile); err != nil {
+			return "", err
 		}
 
 		log.Printf("文件转换成功: %s", pdfFile)
@@ -65,17 +122,51 @@ func ConvertDocxToPdf(inputFile string) (string, error) {
 	return "", fmt.Errorf("无法找到文件转换工具（LibreOffice或Pandoc）")
 }
 
+// lookPathAny 返回第一个能在 PATH 中找到的命令的路径，都找不到时返回空字符串
+func lookPathAny(names ...string) string {
+	for _, name := range names {
+		if path, err := exec.LookPath(name); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
 // 检测MIME类型
 func GetMimeType(filePath string) string {
-	ext := strings.ToLower(filepath.Ext(filePath))
-	switch ext {
-	case ".pdf":
-		return "application/pdf"
-	case ".docx":
-		return "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
-	case ".doc":
-		return "application/msword"
-	default:
-		return "application/octet-stream"
+	return ResumeFormatForFile(filePath).MimeType()
+}
+
+// maxExtractedTextSize 提取出的纯文本的最大字节数，约八万汉字，远超正常简历的篇幅；
+// 防止异常文档生成的巨量文本被逐段送去压缩，产生成千上万次模型调用
+const maxExtractedTextSize = 256 << 10
+
+// ExtractPlainText 尽力从简历文件中提取纯文本，供无法接收二进制文件的模型使用
+// 格式优先按文件内容判断，其次按 mimeType；Word、RTF 和 ODT 优先使用纯 Go 解析，失败时尝试通过 LibreOffice 转为 PDF 后提取；
+// 纯文本、Markdown 和 HTML 会识别编码并去掉标记，结果统一规范化空白和换行；ctx 结束时终止进行中的转换
+func ExtractPlainText(ctx context.Context, data []byte, mimeType string) (string, error) {
+	format := DetectResumeFormat(data)
+	if format == FormatUnknown {
+		format = resumeFormatForMimeType(mimeType)
+	}
+	if format == FormatUnknown && (strings.HasPrefix(mimeType, "text/") || looksLikeText(data)) {
+		format = FormatText
+	}
+	if format == FormatUnknown {
+		return "", fmt.Errorf("无法从该文件类型中提取文本: %s", mimeType)
+	}
+
+	text, err := extractDocument(ctx, data, format)
+	if err != nil {
+		return "", err
+	}
+
+	text = NormalizeResumeText(sanitizeUTF8(text))
+	if len(text) > maxExtractedTextSize {
+		return "", fmt.Errorf("%w: 提取的文本超过 %d KB", ErrDocumentTooLarge, maxExtractedTextSize>>10)
+	}
+	if text == "" {
+		return "", fmt.Errorf("未能从文件中提取到文本: %s", mimeType)
 	}
+	return text, nil
 }
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubConverter 在临时目录中放置名为 libreoffice 的脚本，并将该目录放在 PATH 最前面
+func stubConverter(t *testing.T, script string) string {
+	t.Helper()
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "libreoffice"), []byte("#!/bin/sh\n"+script), 0700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	input := filepath.Join(t.TempDir(), "resume.doc")
+	if err := os.WriteFile(input, []byte("x"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return input
+}
+
+func TestConvertDocxToPdfUsesPrivateProfile(t *testing.T) {
+	input := stubConverter(t, `echo "$1" > "$6/args"`+"\n")
+	if _, err := ConvertDocxToPdf(context.Background(), input); err != nil {
+		t.Fatal(err)
+	}
+	args, err := os.ReadFile(filepath.Join(filepath.Dir(input), "args"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(args), "-env:UserInstallation=file:///") {
+		t.Fatalf("first argument = %q, want a per-invocation profile", args)
+	}
+}
+
+func TestConvertDocxToPdfTimeout(t *testing.T) {
+	input := stubConverter(t, "exec sleep 30\n")
+	t.Setenv("DOCUMENT_CONVERT_TIMEOUT", "200ms")
+	ctx, cancel := ConvertContext(context.Background())
+	defer cancel()
+
+	start := time.Now()
+	_, err := ConvertDocxToPdf(ctx, input)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("conversion took %v after the deadline", elapsed)
+	}
+}
+
+func TestExtractDocumentStopsConversionWithContext(t *testing.T) {
+	stubConverter(t, "exec sleep 30\n")
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := extractDocument(ctx, []byte("{\\rtf1 }"), FormatRTF); err == nil {
+		t.Fatal("expected error")
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("conversion took %v after the request ended", elapsed)
+	}
+
+	// 转换结束后名额已释放
+	for i := 0; i < cap(convertSlots); i++ {
+		slotCtx, slotCancel := context.WithTimeout(context.Background(), time.Second)
+		release, err := acquireConvertSlot(slotCtx)
+		slotCancel()
+		if err != nil {
+			t.Fatalf("slot %d still held: %v", i, err)
+		}
+		defer release()
+	}
+}
+
+func TestAcquireConvertSlotHonoursContext(t *testing.T) {
+	var releases []func()
+	defer func() {
+		for _, release := range releases {
+			release()
+		}
+	}()
+	// 占满所有名额后，新的转换应在上下文结束时放弃等待
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		release, err := acquireConvertSlot(ctx)
+		cancel()
+		if err != nil {
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("err = %v, want deadline exceeded", err)
+			}
+			break
+		}
+		releases = append(releases, release)
+	}
+	if len(releases) != cap(convertSlots) {
+		t.Fatalf("acquired %d slots, want %d", len(releases), cap(convertSlots))
+	}
+}
+
+func TestExtractPlainTextTooLarge(t *testing.T) {
+	line := strings.Repeat("工作经历", 20) + "\n"
+	text := strings.Repeat(line, maxExtractedTextSize/len(line)+1)
+	if _, err := ExtractPlainText(context.Background(), []byte(text), "text/plain"); !errors.Is(err, ErrDocumentTooLarge) {
+		t.Fatalf("err = %v, want ErrDocumentTooLarge", err)
+	}
+	if _, err := ExtractPlainText(context.Background(), []byte(line), "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+}
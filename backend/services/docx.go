@@ -0,0 +1,582 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DOCX 中与文本相关的部件
+const (
+	docxDocumentPart  = "word/document.xml"
+	docxNumberingPart = "word/numbering.xml"
+	docxStylesPart    = "word/styles.xml"
+)
+
+// ExtractDocxText 从 docx（WordprocessingML）中提取纯文本
+// 依次输出页眉、正文和页脚；表格的每一行占一行，单元格以制表符分隔；列表段落带上编号或项目符号
+func ExtractDocxText(data []byte) (string, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("无法解析Word文件: %w", err)
+	}
+	parts := make(map[string]*zip.File, len(reader.File))
+	for _, f := range reader.File {
+		parts[f.Name] = f
+	}
+	if parts[docxDocumentPart] == nil {
+		return "", fmt.Errorf("Word文件中缺少 %s", docxDocumentPart)
+	}
+
+	budget := newDecodeBudget()
+	numbering := newDocxNumbering()
+	if f := parts[docxNumberingPart]; f != nil {
+		if err := parseDocxXML(f, budget, numbering.parseNumbering); err != nil {
+			return "", fmt.Errorf("解析Word列表编号失败: %w", err)
+		}
+	}
+	if f := parts[docxStylesPart]; f != nil {
+		if err := parseDocxXML(f, budget, numbering.parseStyles); err != nil {
+			return "", fmt.Errorf("解析Word样式失败: %w", err)
+		}
+	}
+
+	var sections []string
+	seen := map[string]bool{}
+	addSection := func(text string) {
+		text = strings.TrimSpace(text)
+		// 各节的页眉页脚通常相同，只保留一份
+		if text == "" || seen[text] {
+			return
+		}
+		seen[text] = true
+		sections = append(sections, text)
+	}
+	readPart := func(name string) error {
+		w := &docxWriter{numbering: numbering}
+		if err := parseDocxXML(parts[name], budget, w.parse); err != nil {
+			return fmt.Errorf("解析Word文档内容失败(%s): %w", name, err)
+		}
+		addSection(w.String())
+		return nil
+	}
+
+	headers, footers := docxPartsWithPrefix(parts, "word/header"), docxPartsWithPrefix(parts, "word/footer")
+	for _, name := range headers {
+		if err := readPart(name); err != nil {
+			return "", err
+		}
+	}
+	if err := readPart(docxDocumentPart); err != nil {
+		return "", err
+	}
+	for _, name := range footers {
+		if err := readPart(name); err != nil {
+			return "", err
+		}
+	}
+	return strings.Join(sections, "\n\n"), nil
+}
+
+// docxPartsWithPrefix 返回 word/ 目录下以指定前缀开头的 XML 部件，按序号排序
+func docxPartsWithPrefix(parts map[string]*zip.File, prefix string) []string {
+	var names []string
+	for name := range parts {
+		if strings.HasPrefix(name, prefix) && path.Ext(name) == ".xml" {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ni, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(names[i], prefix), ".xml"))
+		nj, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(names[j], prefix), ".xml"))
+		if ni != nj {
+			return ni < nj
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// parseDocxXML 打开压缩包中的部件并交给 parse 逐个处理 XML 标记，解压的数据计入 budget
+func parseDocxXML(f *zip.File, budget *decodeBudget, parse func(*xml.Decoder) error) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := parse(xml.NewDecoder(budget.reader(rc))); err != nil {
+		if budget.exceeded {
+			return fmt.Errorf("%w: %s 解压后超过上限", ErrDocumentTooLarge, f.Name)
+		}
+		return err
+	}
+	return nil
+}
+
+// docxAttr 按本地名读取属性（w:val 等）
+func docxAttr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// docxLevel 列表某一级的编号格式
+type docxLevel struct {
+	format string // numFmt，如 decimal、bullet、lowerLetter
+	text   string // lvlText，如 "%1."
+	start  int
+}
+
+// docxNumbering 列表编号定义和编号计数
+type docxNumbering struct {
+	abstract map[string]map[int]*docxLevel // abstractNumId -> 级别
+	nums     map[string]string             // numId -> abstractNumId
+	styles   map[string]docxNumRef         // 段落样式自带的编号
+	counters map[string][]int              // abstractNumId -> 各级当前序号
+}
+
+// docxNumRef 段落引用的列表和级别
+type docxNumRef struct {
+	numID string
+	level int
+}
+
+func newDocxNumbering() *docxNumbering {
+	return &docxNumbering{
+		abstract: map[string]map[int]*docxLevel{},
+		nums:     map[string]string{},
+		styles:   map[string]docxNumRef{},
+		counters: map[string][]int{},
+	}
+}
+
+// docxMaxLevel 列表的最大级别，OOXML 只允许 0-8 共九级
+const docxMaxLevel = 8
+
+// parseDocxLevel 解析 ilvl，超出 0-8 的值归到最近的合法级别，避免按级别分配计数器时耗尽内存
+func parseDocxLevel(s string) int {
+	level, _ := strconv.Atoi(s)
+	return min(max(level, 0), docxMaxLevel)
+}
+
+// parseNumbering 解析 numbering.xml 中的 abstractNum 和 num
+func (n *docxNumbering) parseNumbering(decoder *xml.Decoder) error {
+	var abstractID, numID string
+	var level *docxLevel
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "abstractNum":
+				abstractID = docxAttr(t, "abstractNumId")
+				n.abstract[abstractID] = map[int]*docxLevel{}
+			case "lvl":
+				if abstractID == "" {
+					continue
+				}
+				level = &docxLevel{format: "decimal", start: 1}
+				n.abstract[abstractID][parseDocxLevel(docxAttr(t, "ilvl"))] = level
+			case "numFmt":
+				if level != nil {
+					level.format = docxAttr(t, "val")
+				}
+			case "lvlText":
+				if level != nil {
+					level.text = docxAttr(t, "val")
+				}
+			case "start":
+				if level != nil {
+					level.start, _ = strconv.Atoi(docxAttr(t, "val"))
+				}
+			case "num":
+				numID = docxAttr(t, "numId")
+			case "abstractNumId":
+				if numID != "" {
+					n.nums[numID] = docxAttr(t, "val")
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "abstractNum":
+				abstractID = ""
+			case "lvl":
+				level = nil
+			case "num":
+				numID = ""
+			}
+		}
+	}
+}
+
+// parseStyles 解析 styles.xml 中带编号的段落样式（如“列表项目符号”）
+func (n *docxNumbering) parseStyles(decoder *xml.Decoder) error {
+	var styleID string
+	var ref docxNumRef
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "style":
+				styleID, ref = docxAttr(t, "styleId"), docxNumRef{}
+			case "numId":
+				ref.numID = docxAttr(t, "val")
+			case "ilvl":
+				ref.level = parseDocxLevel(docxAttr(t, "val"))
+			}
+		case xml.EndElement:
+			if t.Name.Local == "style" {
+				if styleID != "" && ref.numID != "" && ref.numID != "0" {
+					n.styles[styleID] = ref
+				}
+				styleID = ""
+			}
+		}
+	}
+}
+
+// label 返回段落的编号文本并推进计数，不是列表段落时返回空字符串
+func (n *docxNumbering) label(ref docxNumRef) string {
+	abstractID, ok := n.nums[ref.numID]
+	if !ok {
+		return ""
+	}
+	levels := n.abstract[abstractID]
+	level := levels[ref.level]
+	if level == nil {
+		return ""
+	}
+
+	counters := n.counters[abstractID]
+	for len(counters) <= ref.level {
+		counters = append(counters, 0)
+	}
+	counters[ref.level]++
+	// 上一级的新条目会让下级重新编号
+	for i := ref.level + 1; i < len(counters); i++ {
+		counters[i] = 0
+	}
+	n.counters[abstractID] = counters
+
+	if level.format == "bullet" {
+		return docxBullet(level.text)
+	}
+	if level.format == "none" {
+		return ""
+	}
+	label := level.text
+	for i := 0; i <= ref.level; i++ {
+		placeholder := "%" + strconv.Itoa(i+1)
+		if !strings.Contains(label, placeholder) {
+			continue
+		}
+		l := levels[i]
+		if l == nil {
+			l = &docxLevel{format: "decimal", start: 1}
+		}
+		value := l.start - 1 + counters[i]
+		if counters[i] == 0 {
+			value = l.start
+		}
+		label = strings.ReplaceAll(label, placeholder, formatDocxNumber(value, l.format))
+	}
+	return label
+}
+
+// docxBullet 项目符号通常使用 Symbol/Wingdings 字体的私用区字符，统一显示为圆点
+func docxBullet(text string) string {
+	for _, r := range text {
+		if unicode.Is(unicode.Co, r) || r < ' ' {
+			return "•"
+		}
+	}
+	if strings.TrimSpace(text) == "" {
+		return "•"
+	}
+	return text
+}
+
+// formatDocxNumber 按编号格式输出序号
+func formatDocxNumber(value int, format string) string {
+	switch format {
+	case "lowerLetter", "upperLetter":
+		if value < 1 {
+			return strconv.Itoa(value)
+		}
+		var b []byte
+		for v := value; v > 0; v = (v - 1) / 26 {
+			b = append([]byte{byte('a' + (v-1)%26)}, b...)
+		}
+		if format == "upperLetter" {
+			return strings.ToUpper(string(b))
+		}
+		return string(b)
+	case "lowerRoman", "upperRoman":
+		roman := toRoman(value)
+		if format == "lowerRoman" {
+			return strings.ToLower(roman)
+		}
+		return roman
+	case "chineseCounting", "chineseCountingThousand", "ideographTraditional", "japaneseCounting", "taiwaneseCounting":
+		return chineseNumber(value)
+	case "decimalEnclosedCircle", "decimalEnclosedCircleChinese":
+		if value >= 1 && value <= 20 {
+			return string(rune('①' + value - 1))
+		}
+		return strconv.Itoa(value)
+	case "decimalZero":
+		return fmt.Sprintf("%02d", value)
+	default:
+		return strconv.Itoa(value)
+	}
+}
+
+// toRoman 将正整数转为罗马数字
+func toRoman(value int) string {
+	if value < 1 || value >= 4000 {
+		return strconv.Itoa(value)
+	}
+	numerals := []struct {
+		value  int
+		symbol string
+	}{
+		{1000, "M"}, {900, "CM"}, {500, "D"}, {400, "CD"}, {100, "C"}, {90, "XC"},
+		{50, "L"}, {40, "XL"}, {10, "X"}, {9, "IX"}, {5, "V"}, {4, "IV"}, {1, "I"},
+	}
+	var b strings.Builder
+	for _, n := range numerals {
+		for value >= n.value {
+			b.WriteString(n.symbol)
+			value -= n.value
+		}
+	}
+	return b.String()
+}
+
+// chineseNumber 将 1-99 转为中文数字，其他值使用阿拉伯数字
+func chineseNumber(value int) string {
+	digits := []string{"零", "一", "二", "三", "四", "五", "六", "七", "八", "九"}
+	switch {
+	case value >= 1 && value < 10:
+		return digits[value]
+	case value >= 10 && value < 100:
+		s := "十"
+		if value >= 20 {
+			s = digits[value/10] + s
+		}
+		if value%10 != 0 {
+			s += digits[value%10]
+		}
+		return s
+	default:
+		return strconv.Itoa(value)
+	}
+}
+
+// docxWriter 将 WordprocessingML 的段落和表格输出为纯文本
+type docxWriter struct {
+	numbering *docxNumbering
+	lines     []string
+	inPPr     bool
+
+	// 文本框中的段落嵌套在外层段落中
+	paras []*docxParagraph
+	// 表格嵌套时每层记录当前行的单元格和单元格内的段落
+	tables []*docxTable
+}
+
+// docxParagraph 正在读取的段落
+type docxParagraph struct {
+	text     strings.Builder
+	numRef   docxNumRef
+	hasNum   bool
+	styleNum *docxNumRef
+}
+
+// docxTable 正在读取的表格
+type docxTable struct {
+	cells []string
+	cell  []string
+}
+
+// parse 读取 document.xml、header*.xml 或 footer*.xml
+func (w *docxWriter) parse(decoder *xml.Decoder) error {
+	// 跳过已删除的修订、域代码和兼容内容的备用版本
+	skipDepth := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if skipDepth > 0 {
+				skipDepth++
+				continue
+			}
+			switch t.Name.Local {
+			case "del", "instrText", "Fallback":
+				skipDepth = 1
+			case "p":
+				w.paras = append(w.paras, &docxParagraph{})
+			case "pPr":
+				w.inPPr = true
+			case "pStyle":
+				if ref, ok := w.numbering.styles[docxAttr(t, "val")]; ok && w.inPPr {
+					w.para().styleNum = &ref
+				}
+			case "ilvl":
+				if w.inPPr {
+					w.para().numRef.level = parseDocxLevel(docxAttr(t, "val"))
+				}
+			case "numId":
+				if w.inPPr {
+					w.para().numRef.numID = docxAttr(t, "val")
+					w.para().hasNum = true
+				}
+			case "tab":
+				if !w.inPPr {
+					w.write("\t")
+				}
+			case "br", "cr":
+				w.write("\n")
+			case "noBreakHyphen":
+				w.write("-")
+			case "tbl":
+				w.tables = append(w.tables, &docxTable{})
+			case "tr":
+				if table := w.table(); table != nil {
+					table.cells = nil
+				}
+			case "tc":
+				if table := w.table(); table != nil {
+					table.cell = nil
+				}
+			case "t":
+				var text string
+				if err := decoder.DecodeElement(&text, &t); err != nil {
+					return err
+				}
+				w.write(text)
+			}
+
+		case xml.EndElement:
+			if skipDepth > 0 {
+				skipDepth--
+				continue
+			}
+			switch t.Name.Local {
+			case "pPr":
+				w.inPPr = false
+			case "p":
+				w.endParagraph()
+			case "tc":
+				if table := w.table(); table != nil {
+					table.cells = append(table.cells, strings.Join(table.cell, " "))
+				}
+			case "tr":
+				if table := w.table(); table != nil {
+					row := strings.TrimRight(strings.Join(table.cells, "\t"), "\t")
+					w.emitRow(row)
+				}
+			case "tbl":
+				if n := len(w.tables); n > 0 {
+					w.tables = w.tables[:n-1]
+				}
+			}
+		}
+	}
+}
+
+// table 返回当前所在的表格
+func (w *docxWriter) table() *docxTable {
+	if n := len(w.tables); n > 0 {
+		return w.tables[n-1]
+	}
+	return nil
+}
+
+// para 返回当前所在的段落，段落之外的内容写入一个临时段落
+func (w *docxWriter) para() *docxParagraph {
+	if len(w.paras) == 0 {
+		w.paras = append(w.paras, &docxParagraph{})
+	}
+	return w.paras[len(w.paras)-1]
+}
+
+// write 将文字追加到当前段落
+func (w *docxWriter) write(text string) {
+	w.para().text.WriteString(text)
+}
+
+// endParagraph 为列表段落加上编号，输出到当前单元格或正文
+func (w *docxWriter) endParagraph() {
+	p := w.para()
+	w.paras = w.paras[:len(w.paras)-1]
+	text := strings.TrimSpace(p.text.String())
+
+	ref, numbered := p.numRef, p.hasNum
+	if !numbered && p.styleNum != nil {
+		ref, numbered = *p.styleNum, true
+	}
+	if text == "" {
+		return
+	}
+	// numId 为 0 表示取消样式带来的编号
+	if numbered && ref.numID != "0" {
+		if label := w.numbering.label(ref); label != "" {
+			text = strings.Repeat("  ", ref.level) + label + " " + text
+		}
+	}
+	w.emit(text, len(w.tables))
+}
+
+// emitRow 输出表格的一行；嵌套表格的行并入外层单元格
+func (w *docxWriter) emitRow(row string) {
+	if strings.TrimSpace(row) == "" {
+		return
+	}
+	w.emit(row, len(w.tables)-1)
+}
+
+// emit 输出一段文本，depth 为所在表格的层数，在表格中时写入对应层的当前单元格
+func (w *docxWriter) emit(text string, depth int) {
+	if depth > 0 {
+		table := w.tables[depth-1]
+		table.cell = append(table.cell, strings.ReplaceAll(text, "\n", " "))
+		return
+	}
+	w.lines = append(w.lines, text)
+}
+
+// String 返回提取的文本
+func (w *docxWriter) String() string {
+	return strings.Join(w.lines, "\n")
+}
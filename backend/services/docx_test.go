@@ -0,0 +1,200 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// zipPart 压缩包中的一个部件
+type zipPart struct {
+	name, body string
+}
+
+// buildZip 按顺序写入部件，构造 docx、odt 等基于 zip 的文档
+func buildZip(t testing.TB, parts ...zipPart) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, p := range parts {
+		w, err := zw.Create(p.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(p.body))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// docxXML 为 WordprocessingML 片段加上根元素和命名空间
+func docxXML(root, body string) string {
+	return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:` + root + ` xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">` + body + `</w:` + root + `>`
+}
+
+// docxPara 一个只有文字的段落，pPr 为段落属性
+func docxPara(pPr, text string) string {
+	if pPr != "" {
+		pPr = "<w:pPr>" + pPr + "</w:pPr>"
+	}
+	return "<w:p>" + pPr + "<w:r><w:t xml:space=\"preserve\">" + text + "</w:t></w:r></w:p>"
+}
+
+// docxNum 段落的列表编号属性
+func docxNum(numID, level string) string {
+	return `<w:numPr><w:ilvl w:val="` + level + `"/><w:numId w:val="` + numID + `"/></w:numPr>`
+}
+
+// docxCell 一个表格单元格
+func docxCell(content string) string {
+	return "<w:tc>" + content + "</w:tc>"
+}
+
+const testDocxNumbering = `<w:abstractNum w:abstractNumId="0">` +
+	`<w:lvl w:ilvl="0"><w:start w:val="1"/><w:numFmt w:val="decimal"/><w:lvlText w:val="%1."/></w:lvl>` +
+	`<w:lvl w:ilvl="1"><w:start w:val="1"/><w:numFmt w:val="lowerLetter"/><w:lvlText w:val="%1.%2)"/></w:lvl>` +
+	`</w:abstractNum>` +
+	`<w:abstractNum w:abstractNumId="1">` +
+	`<w:lvl w:ilvl="0"><w:numFmt w:val="bullet"/><w:lvlText w:val="` + "\uf0b7" + `"/></w:lvl>` +
+	`</w:abstractNum>` +
+	`<w:abstractNum w:abstractNumId="2">` +
+	`<w:lvl w:ilvl="0"><w:start w:val="1"/><w:numFmt w:val="chineseCounting"/><w:lvlText w:val="%1、"/></w:lvl>` +
+	`</w:abstractNum>` +
+	`<w:num w:numId="1"><w:abstractNumId w:val="0"/></w:num>` +
+	`<w:num w:numId="2"><w:abstractNumId w:val="1"/></w:num>` +
+	`<w:num w:numId="3"><w:abstractNumId w:val="2"/></w:num>`
+
+const testDocxStyles = `<w:style w:type="paragraph" w:styleId="ListBullet"><w:pPr><w:numPr><w:numId w:val="2"/></w:numPr></w:pPr></w:style>`
+
+// testDocxDocument 包含编号和项目符号列表、嵌套表格、修订和域代码的 document.xml
+func testDocxDocument() string {
+	body := docxPara("", "个人简介") +
+		`<w:p><w:r><w:t>姓名</w:t><w:tab/><w:t>张三</w:t><w:br/><w:t>电话</w:t></w:r>` +
+		`<w:del><w:r><w:delText>已删除</w:delText><w:t>已删除</w:t></w:r></w:del>` +
+		`<w:r><w:fldChar w:fldCharType="begin"/><w:instrText> PAGE </w:instrText></w:r></w:p>` +
+		docxPara(docxNum("3", "0"), "工作经历") +
+		docxPara(docxNum("1", "0"), "负责支付系统") +
+		docxPara(docxNum("1", "1"), "设计对账服务") +
+		docxPara(docxNum("1", "1"), "优化结算性能") +
+		docxPara(docxNum("1", "0"), "负责风控系统") +
+		docxPara(`<w:pStyle w:val="ListBullet"/>`, "Go") +
+		docxPara(docxNum("2", "0"), "Kubernetes") +
+		docxPara(docxNum("3", "0"), "技能") +
+		"<w:tbl>" +
+		"<w:tr>" + docxCell(docxPara("", "公司")) + docxCell(docxPara("", "职位")) + "</w:tr>" +
+		"<w:tr>" + docxCell(docxPara("", "某科技")+docxPara("", "（北京）")) +
+		docxCell("<w:tbl><w:tr>"+docxCell(docxPara("", "高级工程师"))+docxCell(docxPara("", "2020-2024"))+"</w:tr></w:tbl>") + "</w:tr>" +
+		"</w:tbl>" +
+		docxPara("", "")
+	return docxXML("document", "<w:body>"+body+"</w:body>")
+}
+
+// testDocx 在 testDocxDocument 的基础上加入编号定义、样式和页眉页脚
+func testDocx(t testing.TB) []byte {
+	return buildZip(t,
+		zipPart{docxDocumentPart, testDocxDocument()},
+		zipPart{docxNumberingPart, docxXML("numbering", testDocxNumbering)},
+		zipPart{docxStylesPart, docxXML("styles", testDocxStyles)},
+		zipPart{"word/header1.xml", docxXML("hdr", docxPara("", "张三 - 简历"))},
+		zipPart{"word/footer1.xml", docxXML("ftr", docxPara("", "机密"))},
+		zipPart{"word/footer2.xml", docxXML("ftr", docxPara("", "机密"))},
+	)
+}
+
+func TestExtractDocxText(t *testing.T) {
+	got, err := ExtractDocxText(testDocx(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "张三 - 简历\n\n" +
+		"个人简介\n" +
+		"姓名\t张三\n电话\n" +
+		"一、 工作经历\n" +
+		"1. 负责支付系统\n" +
+		"  1.a) 设计对账服务\n" +
+		"  1.b) 优化结算性能\n" +
+		"2. 负责风控系统\n" +
+		"• Go\n" +
+		"• Kubernetes\n" +
+		"二、 技能\n" +
+		"公司\t职位\n" +
+		"某科技 （北京）\t高级工程师\t2020-2024\n\n" +
+		"机密"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestExtractDocxTextInvalid(t *testing.T) {
+	if _, err := ExtractDocxText([]byte("not a zip")); err == nil {
+		t.Error("expected error for non-zip data")
+	}
+	if _, err := ExtractDocxText(buildZip(t, zipPart{"word/other.xml", "<x/>"})); err == nil {
+		t.Error("expected error for missing document part")
+	}
+	broken := buildZip(t, zipPart{docxDocumentPart, docxXML("document", "<w:body><w:p>")[:60]})
+	if _, err := ExtractDocxText(broken); err == nil {
+		t.Error("expected error for truncated XML")
+	}
+}
+
+// 编号定义和段落中超出 0-8 的列表级别
+var (
+	outOfRangeLevelNumbering = docxXML("numbering", `<w:abstractNum w:abstractNumId="0">`+
+		`<w:lvl w:ilvl="2000000000"><w:numFmt w:val="decimal"/><w:lvlText w:val="%9."/></w:lvl>`+
+		`<w:lvl w:ilvl="-1"><w:numFmt w:val="decimal"/><w:lvlText w:val="%1)"/></w:lvl>`+
+		`</w:abstractNum><w:num w:numId="1"><w:abstractNumId w:val="0"/></w:num>`)
+	outOfRangeLevelDocument = docxXML("document", "<w:body>"+docxPara("", "技能")+
+		docxPara(docxNum("1", "2000000000"), "深层条目")+docxPara(docxNum("1", "-5"), "负数级别")+"</w:body>")
+)
+
+func TestExtractDocxTextOutOfRangeLevel(t *testing.T) {
+	data := buildZip(t,
+		zipPart{docxDocumentPart, outOfRangeLevelDocument},
+		zipPart{docxNumberingPart, outOfRangeLevelNumbering},
+	)
+	got, err := ExtractDocxText(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 超出范围的级别按第 9 级和第 1 级处理
+	want := "技能\n" + strings.Repeat("  ", docxMaxLevel) + "1. 深层条目\n1) 负数级别"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFormatDocxNumber(t *testing.T) {
+	tests := []struct {
+		value  int
+		format string
+		want   string
+	}{
+		{3, "decimal", "3"},
+		{28, "lowerLetter", "ab"},
+		{2, "upperLetter", "B"},
+		{14, "lowerRoman", "xiv"},
+		{1994, "upperRoman", "MCMXCIV"},
+		{21, "chineseCounting", "二十一"},
+		{10, "chineseCountingThousand", "十"},
+		{3, "decimalEnclosedCircle", "③"},
+		{7, "decimalZero", "07"},
+	}
+	for _, tt := range tests {
+		if got := formatDocxNumber(tt.value, tt.format); got != tt.want {
+			t.Errorf("formatDocxNumber(%d, %s) = %q, want %q", tt.value, tt.format, got, tt.want)
+		}
+	}
+}
+
+func FuzzExtractDocxText(f *testing.F) {
+	fuzzZipParts(f, ExtractDocxText, [2]string{docxDocumentPart, docxNumberingPart},
+		zipSeed{testDocxDocument(), docxXML("numbering", testDocxNumbering)},
+		zipSeed{outOfRangeLevelDocument, outOfRangeLevelNumbering},
+		zipSeed{docxXML("document", "<w:body>"+docxPara(docxNum("9", "8"), "x")+"</w:body>"), ""},
+	)
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// 模糊测试中单个输入的资源上限
+const (
+	fuzzMaxInput   = 1 << 20
+	fuzzTimeBudget = 5 * time.Second
+	fuzzMemBudget  = 8 * maxDecodedDocumentSize
+)
+
+// fuzzExtract 以 seeds 为种子语料对 extract 做模糊测试，每个输入都检查耗时和内存分配
+// 种子应包含格式中长度、级别、计数等字段取极端值的文档；单核环境下新语料的最小化会占用大部分时间，
+// 可加 -fuzzminimizetime=100x 限制最小化的次数
+func fuzzExtract(f *testing.F, extract func([]byte) (string, error), seeds ...[]byte) {
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+	silenceLog(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if len(data) > fuzzMaxInput {
+			t.Skip()
+		}
+		checkExtractBudget(t, data, extract)
+	})
+}
+
+// zipSeed 压缩包格式的一组种子，依次为两个被变异部件的内容
+type zipSeed [2]string
+
+// fuzzZipParts 对 docx、odt 等压缩包格式中 names 指定的两个部件做模糊测试
+// 压缩包中的数据经过压缩和 CRC 校验，直接变异文件几乎只会得到无法打开的压缩包，因此变异部件的 XML 后再打包
+func fuzzZipParts(f *testing.F, extract func([]byte) (string, error), names [2]string, seeds ...zipSeed) {
+	for _, seed := range seeds {
+		f.Add([]byte(seed[0]), []byte(seed[1]))
+	}
+	silenceLog(f)
+	f.Fuzz(func(t *testing.T, first, second []byte) {
+		if len(first)+len(second) > fuzzMaxInput {
+			t.Skip()
+		}
+		data := buildZip(t, zipPart{names[0], string(first)}, zipPart{names[1], string(second)})
+		checkExtractBudget(t, data, extract)
+	})
+}
+
+// silenceLog 在模糊测试期间丢弃日志，解析过程的调试日志会拖慢模糊测试
+func silenceLog(f *testing.F) {
+	log.SetOutput(io.Discard)
+	f.Cleanup(func() { log.SetOutput(os.Stderr) })
+}
+
+// checkExtractBudget 调用 extract，超过时间或内存分配上限时测试失败
+func checkExtractBudget(t *testing.T, data []byte, extract func([]byte) (string, error)) {
+	t.Helper()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		extract(data)
+	}()
+	select {
+	case <-done:
+	case <-time.After(fuzzTimeBudget):
+		t.Fatalf("extraction of %d bytes took longer than %v", len(data), fuzzTimeBudget)
+	}
+
+	runtime.ReadMemStats(&after)
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > fuzzMemBudget {
+		t.Fatalf("extraction of %d bytes allocated %d MB", len(data), allocated>>20)
+	}
+}
@@ -0,0 +1,310 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// FakeProvider 确定性的假提供方，用于离线开发和CI
+// replay 模式按提示哈希读取录制的响应，未命中时返回内置的固定响应；
+// record 模式调用真实提供方并将响应写入夹具文件
+type FakeProvider struct {
+	mode     string
+	dir      string
+	strict   bool
+	upstream LLMProvider
+}
+
+var _ LLMProvider = (*FakeProvider)(nil)
+
+// 假提供方运行模式
+const (
+	fakeModeReplay = "replay"
+	fakeModeRecord = "record"
+)
+
+// cannedResponse 未命中夹具时返回的固定响应
+// 同时包含面试题、筛选结果、面试总结、分块要点和合规检查的字段，可被任意一种响应模型解析并通过校验
+const cannedResponse = `{
+  "questions": [
+    {"category": "专业技能", "question": "请介绍一个你主导完成的项目及其中的关键技术决策。", "answer": "关注项目背景、个人职责、技术选型依据和最终成果。"},
+    {"category": "综合素质", "question": "遇到需求频繁变更时你如何推进工作？", "answer": "关注沟通方式、优先级管理和风险控制。"}
+  ],
+  "passed": [
+    {"name": "", "reason": "离线测试固定响应：简历符合基本要求"}
+  ],
+  "failed": [],
+  "overall": "离线测试固定响应：候选人整体表现良好",
+  "strengths": ["沟通清晰"],
+  "weaknesses": ["项目深度有待考察"],
+  "recommendation": "建议进入下一轮",
+  "furtherQuestions": ["请进一步了解其团队协作经历"],
+  "riskPoints": ["暂无明显风险"],
+  "suggestions": ["安排技术面试"],
+  "facts": ["离线测试固定响应：5年后端开发经验"],
+  "highlights": ["离线测试固定响应：熟悉分布式系统"],
+  "concerns": ["离线测试固定响应：管理经验有待核实"],
+  "violations": []
+}`
+
+// FakeFixture 录制的夹具文件内容
+type FakeFixture struct {
+	Key           string    `json:"key"`
+	Provider      string    `json:"provider"`
+	Model         string    `json:"model"`
+	PromptPreview string    `json:"promptPreview"`
+	Text          string    `json:"text"`
+	RecordedAt    time.Time `json:"recordedAt"`
+}
+
+// NewFakeProvider 根据环境变量创建假提供方
+func NewFakeProvider() *FakeProvider {
+	dir := os.Getenv("LLM_FAKE_FIXTURES_DIR")
+	if dir == "" {
+		dir = "testdata/llm_fixtures"
+	}
+
+	p := &FakeProvider{
+		mode:   fakeModeReplay,
+		dir:    dir,
+		strict: os.Getenv("LLM_FAKE_STRICT") == "true",
+	}
+
+	if strings.ToLower(os.Getenv("LLM_FAKE_MODE")) == fakeModeRecord {
+		upstreamName := os.Getenv("LLM_FAKE_UPSTREAM")
+		if upstreamName == "" || upstreamName == ProviderFake {
+			upstreamName = ProviderVertex
+		}
+		p.mode = fakeModeRecord
+		p.upstream = newProviderByName(upstreamName)
+		log.Printf("[INFO] 假提供方处于录制模式，上游: %s, 夹具目录: %s", p.upstream.Name(), dir)
+	}
+
+	return p
+}
+
+// Name 返回提供方名称
+func (p *FakeProvider) Name() string {
+	return ProviderFake
+}
+
+// Model 返回当前使用的模型名称
+func (p *FakeProvider) Model() string {
+	if p.upstream != nil {
+		return p.upstream.Model()
+	}
+	return "fake"
+}
+
+// GenerateContent 返回录制或固定的响应
+func (p *FakeProvider) GenerateContent(ctx context.Context, systemInstruction, prompt string, opts ...GenerateOption) (*GenerateResult, error) {
+	key := fixtureKey(systemInstruction, prompt, "", nil)
+	if p.mode == fakeModeRecord {
+		result, err := p.upstream.GenerateContent(ctx, systemInstruction, prompt, opts...)
+		if err != nil {
+			return nil, err
+		}
+		p.save(key, prompt, result)
+		return result, nil
+	}
+	return p.replay(key)
+}
+
+// GenerateContentWithBinaryFile 返回录制或固定的响应，文件内容参与哈希计算
+func (p *FakeProvider) GenerateContentWithBinaryFile(ctx context.Context, systemInstruction string, fileContent string, mimeType string, textPrompt string, opts ...GenerateOption) (*GenerateResult, error) {
+	key := fixtureKey(systemInstruction, textPrompt, mimeType, []byte(fileContent))
+	if p.mode == fakeModeRecord {
+		result, err := p.upstream.GenerateContentWithBinaryFile(ctx, systemInstruction, fileContent, mimeType, textPrompt, opts...)
+		if err != nil {
+			return nil, err
+		}
+		p.save(key, textPrompt, result)
+		return result, nil
+	}
+	return p.replay(key)
+}
+
+// GenerateContentStream 将录制或固定的响应拆分为若干段模拟流式输出
+func (p *FakeProvider) GenerateContentStream(ctx context.Context, systemInstruction, prompt string, opts ...GenerateOption) (StreamIterator, error) {
+	key := fixtureKey(systemInstruction, prompt, "", nil)
+	if p.mode == fakeModeRecord {
+		iter, err := p.upstream.GenerateContentStream(ctx, systemInstruction, prompt, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return &recordingStream{inner: iter, provider: p, key: key, prompt: prompt}, nil
+	}
+
+	result, err := p.replay(key)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeStream{chunks: splitIntoChunks(result.Text, 40)}, nil
+}
+
+// replay 读取夹具，未命中时返回固定响应
+func (p *FakeProvider) replay(key string) (*GenerateResult, error) {
+	data, err := os.ReadFile(p.fixturePath(key))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("读取夹具失败: %w", err)
+		}
+		if p.strict {
+			return nil, fmt.Errorf("未找到夹具: %s", key)
+		}
+		log.Printf("[DEBUG] 未找到夹具 %s，返回固定响应", key)
+		return &GenerateResult{Text: cannedResponse, Provider: p.Name(), Model: p.Model(), FinishReason: FinishReasonStop}, nil
+	}
+
+	var fixture FakeFixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("解析夹具失败: %w", err)
+	}
+	log.Printf("[DEBUG] 命中夹具 %s (录制自 %s/%s)", key, fixture.Provider, fixture.Model)
+	return &GenerateResult{Text: fixture.Text, Provider: p.Name(), Model: fixture.Model, FinishReason: FinishReasonStop}, nil
+}
+
+// save 将上游响应写入夹具文件，写入失败只记录日志
+func (p *FakeProvider) save(key, prompt string, result *GenerateResult) {
+	if err := os.MkdirAll(p.dir, 0755); err != nil {
+		log.Printf("[ERROR] 创建夹具目录失败: %v", err)
+		return
+	}
+
+	fixture := FakeFixture{
+		Key:           key,
+		Provider:      result.Provider,
+		Model:         result.Model,
+		PromptPreview: truncateRunes(prompt, 200),
+		Text:          result.Text,
+		RecordedAt:    time.Now(),
+	}
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		log.Printf("[ERROR] 序列化夹具失败: %v", err)
+		return
+	}
+	if err := os.WriteFile(p.fixturePath(key), data, 0644); err != nil {
+		log.Printf("[ERROR] 写入夹具失败: %v", err)
+		return
+	}
+	log.Printf("[INFO] 已录制夹具 %s", key)
+}
+
+// fixturePath 返回夹具文件路径
+func (p *FakeProvider) fixturePath(key string) string {
+	return filepath.Join(p.dir, key+".json")
+}
+
+// fixtureKey 计算系统指令、提示和文件内容的哈希
+func fixtureKey(systemInstruction, prompt, mimeType string, fileData []byte) string {
+	h := sha256.New()
+	for _, part := range []string{systemInstruction, prompt, mimeType} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	h.Write(fileData)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// fakeStream 按预先拆分好的片段依次返回文本
+type fakeStream struct {
+	chunks []string
+	pos    int
+}
+
+// Next 返回下一段文本，片段耗尽时返回 io.EOF
+func (s *fakeStream) Next() (string, error) {
+	if s.pos >= len(s.chunks) {
+		return "", io.EOF
+	}
+	chunk := s.chunks[s.pos]
+	s.pos++
+	return chunk, nil
+}
+
+// Usage 假提供方不消耗令牌，始终返回零值
+func (s *fakeStream) Usage() TokenUsage {
+	return TokenUsage{}
+}
+
+// FinishReason 假提供方的输出总是完整的
+func (s *fakeStream) FinishReason() FinishReason {
+	return FinishReasonStop
+}
+
+// Close 假提供方没有需要释放的连接
+func (s *fakeStream) Close() error {
+	return nil
+}
+
+// recordingStream 透传上游流式响应，结束时将完整文本写入夹具
+type recordingStream struct {
+	inner    StreamIterator
+	provider *FakeProvider
+	key      string
+	prompt   string
+	text     strings.Builder
+}
+
+// Next 返回上游的下一段文本
+func (s *recordingStream) Next() (string, error) {
+	chunk, err := s.inner.Next()
+	if err == io.EOF {
+		p := s.provider
+		p.save(s.key, s.prompt, &GenerateResult{Text: s.text.String(), Provider: p.upstream.Name(), Model: p.upstream.Model()})
+		return "", io.EOF
+	}
+	if err != nil {
+		return "", err
+	}
+	s.text.WriteString(chunk)
+	return chunk, nil
+}
+
+// Usage 返回上游的令牌用量
+func (s *recordingStream) Usage() TokenUsage {
+	return s.inner.Usage()
+}
+
+// FinishReason 返回上游的结束原因
+func (s *recordingStream) FinishReason() FinishReason {
+	return s.inner.FinishReason()
+}
+
+// Close 关闭上游的流
+func (s *recordingStream) Close() error {
+	return s.inner.Close()
+}
+
+// splitIntoChunks 按字符数拆分文本
+func splitIntoChunks(text string, size int) []string {
+	var chunks []string
+	runes := []rune(text)
+	for start := 0; start < len(runes); start += size {
+		end := start + size
+		if end > len(runes) {
+			end = len(runes)
+		}
+		chunks = append(chunks, string(runes[start:end]))
+	}
+	return chunks
+}
+
+// truncateRunes 按字符数截断文本
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "..."
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GiantClam/ai-resume/models"
+)
+
+// TestCannedResponseDecodes 固定响应必须能被每一种响应模型解析并通过校验，否则离线模式下对应的接口会失败
+func TestCannedResponseDecodes(t *testing.T) {
+	decoders := map[string]func(string) error{
+		"ScreeningResponse":       func(s string) error { _, err := DecodeJSON[models.ScreeningResponse](s); return err },
+		"QuestionsResponse":       func(s string) error { _, err := DecodeJSON[models.QuestionsResponse](s); return err },
+		"SummaryResponse":         func(s string) error { _, err := DecodeJSON[models.SummaryResponse](s); return err },
+		"ChunkNotes":              func(s string) error { _, err := DecodeJSON[models.ChunkNotes](s); return err },
+		"ComplianceCheckResponse": func(s string) error { _, err := DecodeJSON[models.ComplianceCheckResponse](s); return err },
+	}
+	for name, decode := range decoders {
+		t.Run(name, func(t *testing.T) {
+			if err := decode(cannedResponse); err != nil {
+				t.Errorf("canned response does not decode as %s: %v", name, err)
+			}
+		})
+	}
+}
+
+func TestFakeProviderReplaysCannedResponse(t *testing.T) {
+	t.Setenv("LLM_FAKE_FIXTURES_DIR", t.TempDir())
+	p := NewFakeProvider()
+	result, err := p.GenerateContent(context.Background(), "sys", "prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	notes, err := DecodeJSON[models.ChunkNotes](result.Text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes.Facts) == 0 {
+		t.Error("expected facts in canned chunk notes")
+	}
+}
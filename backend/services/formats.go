@@ -0,0 +1,300 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+// ResumeFormat 简历文件格式
+type ResumeFormat string
+
+// 支持的简历文件格式
+const (
+	FormatUnknown  ResumeFormat = ""
+	FormatPDF      ResumeFormat = "pdf"
+	FormatDOCX     ResumeFormat = "docx"
+	FormatDOC      ResumeFormat = "doc"
+	FormatRTF      ResumeFormat = "rtf"
+	FormatODT      ResumeFormat = "odt"
+	FormatText     ResumeFormat = "txt"
+	FormatMarkdown ResumeFormat = "md"
+	FormatHTML     ResumeFormat = "html"
+)
+
+// ErrDocumentEncrypted 文档已加密（受密码保护），无法提取文字
+var ErrDocumentEncrypted = errors.New("文档已加密，无法提取文字")
+
+// resumeFormats 各接口统一接受的简历格式，按扩展名列出
+var resumeFormats = []struct {
+	ext      string
+	format   ResumeFormat
+	mimeType string
+}{
+	{".pdf", FormatPDF, "application/pdf"},
+	{".docx", FormatDOCX, "application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+	{".doc", FormatDOC, "application/msword"},
+	{".rtf", FormatRTF, "application/rtf"},
+	{".odt", FormatODT, "application/vnd.oasis.opendocument.text"},
+	{".txt", FormatText, "text/plain"},
+	{".md", FormatMarkdown, "text/markdown"},
+	{".markdown", FormatMarkdown, "text/markdown"},
+	{".html", FormatHTML, "text/html"},
+	{".htm", FormatHTML, "text/html"},
+}
+
+// SupportedResumeExtensions 返回接受的简历文件扩展名
+func SupportedResumeExtensions() []string {
+	exts := make([]string, 0, len(resumeFormats))
+	for _, f := range resumeFormats {
+		exts = append(exts, f.ext)
+	}
+	return exts
+}
+
+// ResumeFormatForFile 按扩展名（不区分大小写）返回简历格式，不支持时返回 FormatUnknown
+func ResumeFormatForFile(filename string) ResumeFormat {
+	ext := strings.ToLower(filepath.Ext(filename))
+	for _, f := range resumeFormats {
+		if f.ext == ext {
+			return f.format
+		}
+	}
+	return FormatUnknown
+}
+
+// IsSupportedResumeFile 文件扩展名是否在接受的格式中
+func IsSupportedResumeFile(filename string) bool {
+	return ResumeFormatForFile(filename) != FormatUnknown
+}
+
+// MimeType 返回格式对应的MIME类型
+func (f ResumeFormat) MimeType() string {
+	for _, rf := range resumeFormats {
+		if rf.format == f {
+			return rf.mimeType
+		}
+	}
+	return "application/octet-stream"
+}
+
+// resumeFormatForMimeType 按 MIME 类型（忽略参数）返回简历格式，不支持时返回 FormatUnknown
+func resumeFormatForMimeType(mimeType string) ResumeFormat {
+	mimeType = strings.ToLower(strings.TrimSpace(strings.Split(mimeType, ";")[0]))
+	if mimeType == "text/x-markdown" {
+		return FormatMarkdown
+	}
+	for _, rf := range resumeFormats {
+		if rf.mimeType == mimeType {
+			return rf.format
+		}
+	}
+	return FormatUnknown
+}
+
+// ResolveResumeFormat 确定简历格式：优先按文件内容判断，其次按扩展名；
+// 都无法判断但内容是文本时按纯文本处理
+func ResolveResumeFormat(data []byte, filename string) ResumeFormat {
+	if format := DetectResumeFormat(data); format != FormatUnknown {
+		return format
+	}
+	if format := ResumeFormatForFile(filename); format != FormatUnknown {
+		return format
+	}
+	if looksLikeText(data) {
+		return FormatText
+	}
+	return FormatUnknown
+}
+
+// looksLikeText 内容是否为文本：带 UTF-16 BOM，或为不含 NUL 字节的有效 UTF-8
+func looksLikeText(data []byte) bool {
+	if bytes.HasPrefix(data, []byte{0xFF, 0xFE}) || bytes.HasPrefix(data, []byte{0xFE, 0xFF}) {
+		return true
+	}
+	return utf8.Valid(data) && !bytes.ContainsRune(data, 0)
+}
+
+// 文件头特征
+var (
+	oleSignature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+	rtfSignature = []byte(`{\rtf`)
+	zipSignature = []byte("PK\x03\x04")
+	// htmlSignatures 去掉 BOM 和前导空白、转为小写后 HTML 文件的开头
+	htmlSignatures = [][]byte{[]byte("<!doctype html"), []byte("<html"), []byte("<head"), []byte("<body"), []byte("<meta")}
+)
+
+// DetectResumeFormat 按文件内容判断简历格式，不依赖扩展名
+func DetectResumeFormat(data []byte) ResumeFormat {
+	head := data
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	switch {
+	case bytes.Contains(head, []byte("%PDF-")):
+		return FormatPDF
+	case bytes.HasPrefix(data, rtfSignature):
+		return FormatRTF
+	case bytes.HasPrefix(data, oleSignature):
+		return FormatDOC
+	case bytes.HasPrefix(data, zipSignature):
+		reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return FormatUnknown
+		}
+		for _, f := range reader.File {
+			switch f.Name {
+			case docxDocumentPart:
+				return FormatDOCX
+			case odtContentPart:
+				return FormatODT
+			}
+		}
+	default:
+		trimmed := bytes.ToLower(bytes.TrimLeft(bytes.TrimPrefix(head, []byte{0xEF, 0xBB, 0xBF}), " \t\r\n"))
+		for bytes.HasPrefix(trimmed, []byte("<!--")) {
+			end := bytes.Index(trimmed, []byte("-->"))
+			if end < 0 {
+				break
+			}
+			trimmed = bytes.TrimLeft(trimmed[end+3:], " \t\r\n")
+		}
+		for _, sig := range htmlSignatures {
+			if bytes.HasPrefix(trimmed, sig) {
+				return FormatHTML
+			}
+		}
+	}
+	return FormatUnknown
+}
+
+// needsConversionFallback 纯 Go 解析失败时是否尝试用 LibreOffice 转换
+func needsConversionFallback(format ResumeFormat, err error) bool {
+	if errors.Is(err, ErrDocumentEncrypted) || errors.Is(err, ErrDocumentTooLarge) {
+		return false
+	}
+	switch format {
+	case FormatDOC, FormatRTF, FormatODT, FormatDOCX:
+		return true
+	}
+	return false
+}
+
+// extractViaPDFConversion 将文档写入临时目录，通过 ConvertDocxToPdf（LibreOffice 或 Pandoc）转为 PDF 后提取文字
+// ctx 结束时（如客户端断开连接）终止转换进程并释放转换名额
+func extractViaPDFConversion(ctx context.Context, data []byte, format ResumeFormat) (string, error) {
+	dir, err := os.MkdirTemp("", "resume-convert-")
+	if err != nil {
+		return "", fmt.Errorf("创建临时目录失败: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "resume."+string(format))
+	if err := os.WriteFile(input, data, 0600); err != nil {
+		return "", fmt.Errorf("写入临时文件失败: %w", err)
+	}
+	ctx, cancel := ConvertContext(ctx)
+	defer cancel()
+	pdfFile, err := ConvertDocxToPdf(ctx, input)
+	if err != nil {
+		return "", err
+	}
+	pdf, err := os.ReadFile(pdfFile)
+	if err != nil {
+		return "", fmt.Errorf("读取转换后的PDF失败: %w", err)
+	}
+	return ExtractPDFText(pdf)
+}
+
+// extractDocument 按格式提取文字，纯 Go 解析失败时退回到转换为 PDF
+func extractDocument(ctx context.Context, data []byte, format ResumeFormat) (string, error) {
+	var text string
+	var err error
+	switch format {
+	case FormatPDF:
+		return ExtractPDFText(data)
+	case FormatDOCX:
+		text, err = ExtractDocxText(data)
+	case FormatDOC:
+		text, err = ExtractDocText(data)
+	case FormatRTF:
+		text, err = ExtractRTFText(data)
+	case FormatODT:
+		text, err = ExtractODTText(data)
+	case FormatText:
+		return decodeTextBytes(data), nil
+	case FormatMarkdown:
+		return ExtractMarkdownText(decodeTextBytes(data)), nil
+	case FormatHTML:
+		return ExtractHTMLText(data, "")
+	default:
+		return "", fmt.Errorf("不支持的文件格式: %s", format)
+	}
+	if err == nil && strings.TrimSpace(text) != "" {
+		return text, nil
+	}
+	if err == nil {
+		err = fmt.Errorf("未能从%s文件中提取到文本", format)
+	}
+	if !needsConversionFallback(format, err) {
+		return "", err
+	}
+
+	log.Printf("[WARN] 解析%s文件失败，尝试转换为PDF: %v", format, err)
+	converted, convErr := extractViaPDFConversion(ctx, data, format)
+	if convErr != nil {
+		log.Printf("[WARN] 转换%s文件失败: %v", format, convErr)
+		return "", err
+	}
+	return converted, nil
+}
+
+// windowsCodePage 返回 Windows 代码页对应的编码，未知代码页使用 Windows-1252
+func windowsCodePage(cp int) encoding.Encoding {
+	switch cp {
+	case 936:
+		return simplifiedchinese.GBK
+	case 950:
+		return traditionalchinese.Big5
+	case 932:
+		return japanese.ShiftJIS
+	case 949:
+		return korean.EUCKR
+	case 874:
+		return charmap.Windows874
+	case 1250:
+		return charmap.Windows1250
+	case 1251:
+		return charmap.Windows1251
+	case 1253:
+		return charmap.Windows1253
+	case 1254:
+		return charmap.Windows1254
+	case 1255:
+		return charmap.Windows1255
+	case 1256:
+		return charmap.Windows1256
+	case 1257:
+		return charmap.Windows1257
+	case 1258:
+		return charmap.Windows1258
+	case 10000:
+		return charmap.Macintosh
+	default:
+		return charmap.Windows1252
+	}
+}
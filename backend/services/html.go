@@ -0,0 +1,298 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+// htmlSkipElements 不含正文的元素，连同子节点一起丢弃
+var htmlSkipElements = map[atom.Atom]bool{
+	atom.Head: true, atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Iframe: true, atom.Object: true, atom.Embed: true, atom.Svg: true, atom.Math: true,
+	atom.Canvas: true, atom.Video: true, atom.Audio: true, atom.Nav: true, atom.Aside: true,
+	atom.Footer: true, atom.Button: true, atom.Select: true, atom.Input: true,
+	atom.Textarea: true, atom.Dialog: true,
+}
+
+// htmlBlockElements 块级元素，前后换行
+var htmlBlockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true, atom.Main: true,
+	atom.Header: true, atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true,
+	atom.H6: true, atom.Ul: true, atom.Ol: true, atom.Dl: true, atom.Dt: true, atom.Dd: true,
+	atom.Table: true, atom.Tr: true, atom.Blockquote: true, atom.Pre: true, atom.Address: true,
+	atom.Figure: true, atom.Figcaption: true, atom.Fieldset: true, atom.Details: true,
+	atom.Summary: true, atom.Hr: true, atom.Body: true, atom.Caption: true,
+}
+
+// htmlBoilerplatePattern class、id 中表示导航、广告、分享等页面模板内容的词
+var htmlBoilerplatePattern = regexp.MustCompile(`(?i)(^|[\s_-])(nav|navbar|menu|breadcrumbs?|footer|sidebar|cookies?|banner|ads?|advert\w*|share|social|comments?|subscribe|newsletter|popup|modal|login|signup|toolbar)($|[\s_-])`)
+
+// htmlBoilerplateRoles 表示页面模板内容的 ARIA role
+var htmlBoilerplateRoles = map[string]bool{
+	"navigation": true, "banner": true, "contentinfo": true, "complementary": true,
+	"search": true, "dialog": true, "alert": true, "menu": true, "menubar": true,
+}
+
+// htmlHiddenStyle 行内样式中隐藏元素的声明
+var htmlHiddenStyle = regexp.MustCompile(`(?i)(display\s*:\s*none|visibility\s*:\s*hidden|font-size\s*:\s*0(px|pt|em|rem)?\s*(;|$)|opacity\s*:\s*0(\.0*)?\s*(;|$))`)
+
+// ExtractHTMLText 从招聘网站保存的网页等 HTML 中提取简历正文
+// 按 Content-Type 和 <meta charset> 解码；丢弃脚本、样式、表单控件和隐藏元素（隐藏文字常被用来夹带给模型的指令），
+// 去掉导航、页脚、侧栏、广告等页面模板内容；页面有 <main> 或 <article> 时只取其中的内容
+func ExtractHTMLText(data []byte, contentType string) (string, error) {
+	var content string
+	if enc, name, certain := charset.DetermineEncoding(data, contentType); certain || name != "windows-1252" {
+		decoded, err := enc.NewDecoder().Bytes(data)
+		if err != nil {
+			return "", fmt.Errorf("无法解码HTML文件: %w", err)
+		}
+		content = string(decoded)
+	} else {
+		// 没有声明编码时按纯文本的规则猜测，兼容 GBK 编码的中文网页
+		content = decodeTextBytes(data)
+	}
+
+	if htmlTooDeep(content) {
+		return "", fmt.Errorf("无法解析HTML文件: 元素嵌套超过 %d 层", htmlMaxDepth)
+	}
+	doc, err := html.Parse(strings.NewReader(content))
+	if err != nil {
+		return "", fmt.Errorf("无法解析HTML文件: %w", err)
+	}
+
+	root := htmlMainContent(doc)
+	if root == nil {
+		root = doc
+	}
+	w := &htmlWriter{}
+	w.walk(root)
+	return NormalizeResumeText(w.String()), nil
+}
+
+// htmlMaxDepth 元素嵌套的最大层数；html.Parse 处理深层嵌套的块级元素时耗时随层数平方增长，几十 KB 的页面就能耗时数秒
+const htmlMaxDepth = 256
+
+// htmlMaxListIndent 列表项最多缩进的层数
+const htmlMaxListIndent = 8
+
+// htmlVoidElements 没有结束标记的元素
+var htmlVoidElements = map[atom.Atom]bool{
+	atom.Area: true, atom.Base: true, atom.Br: true, atom.Col: true, atom.Embed: true, atom.Hr: true,
+	atom.Img: true, atom.Input: true, atom.Link: true, atom.Meta: true, atom.Param: true,
+	atom.Source: true, atom.Track: true, atom.Wbr: true,
+}
+
+// htmlOptionalEndElements 可以省略结束标记的元素，解析时会被后续元素自动关闭
+var htmlOptionalEndElements = map[atom.Atom]bool{
+	atom.Html: true, atom.Head: true, atom.Body: true, atom.P: true, atom.Li: true, atom.Dt: true,
+	atom.Dd: true, atom.Option: true, atom.Optgroup: true, atom.Thead: true, atom.Tbody: true,
+	atom.Tfoot: true, atom.Tr: true, atom.Td: true, atom.Th: true, atom.Colgroup: true,
+	atom.Caption: true, atom.Rb: true, atom.Rt: true, atom.Rtc: true, atom.Rp: true,
+}
+
+// htmlTooDeep 在解析前用分词器估计元素的嵌套层数，超过 htmlMaxDepth 时返回 true
+// 结束标记关闭栈中最近的同名元素及其内部未关闭的元素，不计空元素和可以省略结束标记的元素
+func htmlTooDeep(content string) bool {
+	z := html.NewTokenizer(strings.NewReader(content))
+	var open []string
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return false
+		case html.StartTagToken:
+			name, _ := z.TagName()
+			if a := atom.Lookup(name); htmlVoidElements[a] || htmlOptionalEndElements[a] {
+				continue
+			}
+			open = append(open, string(name))
+			if len(open) > htmlMaxDepth {
+				return true
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] == string(name) {
+					open = open[:i]
+					break
+				}
+			}
+		}
+	}
+}
+
+// htmlMainContent 返回文字最多的 <main>、<article> 或 role="main" 元素，没有时返回 nil
+func htmlMainContent(doc *html.Node) *html.Node {
+	var best *html.Node
+	bestLen := 0
+	var visit func(n *html.Node)
+	visit = func(n *html.Node) {
+		if n.Type == html.ElementNode && htmlSkipped(n) {
+			return
+		}
+		if n.Type == html.ElementNode && (n.DataAtom == atom.Main || n.DataAtom == atom.Article || htmlAttr(n, "role") == "main") {
+			w := &htmlWriter{}
+			w.walk(n)
+			if l := len(strings.TrimSpace(w.String())); l > bestLen {
+				best, bestLen = n, l
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			visit(c)
+		}
+	}
+	visit(doc)
+	return best
+}
+
+// htmlAttr 返回元素的属性值
+func htmlAttr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// htmlSkipped 元素是否为不含正文、隐藏或页面模板的内容
+func htmlSkipped(n *html.Node) bool {
+	if htmlSkipElements[n.DataAtom] {
+		return true
+	}
+	for _, a := range n.Attr {
+		switch a.Key {
+		case "hidden":
+			return true
+		case "aria-hidden":
+			if a.Val == "true" {
+				return true
+			}
+		case "style":
+			if htmlHiddenStyle.MatchString(a.Val) {
+				return true
+			}
+		case "role":
+			if htmlBoilerplateRoles[strings.ToLower(a.Val)] {
+				return true
+			}
+		case "class", "id":
+			if htmlBoilerplatePattern.MatchString(a.Val) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// htmlWriter 将 HTML 节点输出为纯文本
+type htmlWriter struct {
+	b     bytes.Buffer
+	pre   int // 所在 <pre> 的层数，其中保留空白
+	lists []int
+}
+
+// walk 递归输出节点的文字
+func (w *htmlWriter) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		w.text(n.Data)
+		return
+	case html.ElementNode:
+		if htmlSkipped(n) {
+			return
+		}
+	case html.DocumentNode:
+	default:
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Br:
+		w.b.WriteByte('\n')
+		return
+	case atom.Img:
+		return
+	case atom.Td, atom.Th:
+		if w.b.Len() > 0 && !bytes.HasSuffix(w.b.Bytes(), []byte("\n")) {
+			w.b.WriteByte('\t')
+		}
+	case atom.Li:
+		w.newline()
+		w.b.WriteString(strings.Repeat("  ", min(max(len(w.lists)-1, 0), htmlMaxListIndent)))
+		if n := len(w.lists); n > 0 && w.lists[n-1] > 0 {
+			w.b.WriteString(fmt.Sprintf("%d. ", w.lists[n-1]))
+			w.lists[n-1]++
+		} else {
+			w.b.WriteString("• ")
+		}
+	case atom.Ul:
+		w.lists = append(w.lists, 0)
+		defer func() { w.lists = w.lists[:len(w.lists)-1] }()
+	case atom.Ol:
+		w.lists = append(w.lists, 1)
+		defer func() { w.lists = w.lists[:len(w.lists)-1] }()
+	case atom.Pre:
+		w.pre++
+		defer func() { w.pre-- }()
+	}
+
+	block := htmlBlockElements[n.DataAtom]
+	if block {
+		w.newline()
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.walk(c)
+	}
+	if block || n.DataAtom == atom.Li {
+		w.newline()
+	}
+}
+
+// text 输出文本节点，<pre> 之外的连续空白合并为一个空格
+func (w *htmlWriter) text(s string) {
+	if w.pre > 0 {
+		w.b.WriteString(s)
+		return
+	}
+	fields := strings.Fields(s)
+	if len(fields) == 0 || strings.IndexFunc(s, isHTMLSpace) == 0 {
+		w.space()
+	}
+	if len(fields) == 0 {
+		return
+	}
+	w.b.WriteString(strings.Join(fields, " "))
+	if strings.LastIndexFunc(s, isHTMLSpace) == len(s)-1 {
+		w.space()
+	}
+}
+
+// space 输出单词间的空格，行首和已有空白之后不再输出
+func (w *htmlWriter) space() {
+	if b := w.b.Bytes(); len(b) > 0 && !isHTMLSpace(rune(b[len(b)-1])) {
+		w.b.WriteByte(' ')
+	}
+}
+
+// newline 在块级元素前后换行，避免重复的换行
+func (w *htmlWriter) newline() {
+	if w.b.Len() > 0 && !bytes.HasSuffix(w.b.Bytes(), []byte("\n")) {
+		w.b.WriteByte('\n')
+	}
+}
+
+// String 返回输出的文本
+func (w *htmlWriter) String() string {
+	return w.b.String()
+}
+
+func isHTMLSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f'
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 支持的输出语言
+const (
+	LangZh = "zh" // 中文，内置模板的默认语言
+	LangEn = "en" // 英文
+	LangJa = "ja" // 日文
+)
+
+// DefaultLanguage 未指定语言且 Accept-Language 中没有支持的语言时使用的语言
+const DefaultLanguage = LangZh
+
+// SupportedLanguages 支持的输出语言
+var SupportedLanguages = []string{LangZh, LangEn, LangJa}
+
+// ErrUnsupportedLanguage 请求指定了不支持的语言
+var ErrUnsupportedLanguage = errors.New("不支持的语言")
+
+// NormalizeLanguage 将 zh-CN、en_US、JA 等语言标签规范化为支持的语言代码
+func NormalizeLanguage(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	primary, _, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+	for _, lang := range SupportedLanguages {
+		if primary == lang {
+			return lang, true
+		}
+	}
+	return "", false
+}
+
+// ResolveLanguage 确定输出语言：优先使用请求参数，未指定时按 Accept-Language 的权重选择第一个支持的语言，都没有时使用默认语言
+func ResolveLanguage(param, acceptLanguage string) (string, error) {
+	if strings.TrimSpace(param) != "" {
+		lang, ok := NormalizeLanguage(param)
+		if !ok {
+			return "", fmt.Errorf("%w: %s，可选 %s", ErrUnsupportedLanguage, param, strings.Join(SupportedLanguages, "、"))
+		}
+		return lang, nil
+	}
+
+	for _, tag := range parseAcceptLanguage(acceptLanguage) {
+		if lang, ok := NormalizeLanguage(tag); ok {
+			return lang, nil
+		}
+	}
+	return DefaultLanguage, nil
+}
+
+// parseAcceptLanguage 解析 Accept-Language 请求头，按 q 值从高到低返回语言标签
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	var items []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > 0 {
+			items = append(items, weighted{tag, q})
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].q > items[j].q })
+
+	tags := make([]string, len(items))
+	for i, item := range items {
+		tags[i] = item.tag
+	}
+	return tags
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/GiantClam/ai-resume/models"
+)
+
+// LLMProvider 大语言模型后端的统一抽象，处理器只依赖该接口而不依赖具体实现
+type LLMProvider interface {
+	// Name 返回提供方名称，例如 vertex
+	Name() string
+	// Model 返回当前使用的模型名称
+	Model() string
+	// GenerateContent 根据系统指令和文本提示生成内容
+	GenerateContent(ctx context.Context, systemInstruction, prompt string, opts ...GenerateOption) (*GenerateResult, error)
+	// GenerateContentStream 流式生成内容
+	GenerateContentStream(ctx context.Context, systemInstruction, prompt string, opts ...GenerateOption) (StreamIterator, error)
+	// GenerateContentWithBinaryFile 携带二进制文件（如PDF简历）生成内容
+	GenerateContentWithBinaryFile(ctx context.Context, systemInstruction string, fileContent string, mimeType string, textPrompt string, opts ...GenerateOption) (*GenerateResult, error)
+}
+
+// GenerateOption 生成调用的可选参数
+type GenerateOption func(*generateOptions)
+
+// generateOptions 汇总后的可选参数
+type generateOptions struct {
+	schema *ResponseSchema
+	json   bool
+	params *models.GenerationParams
+}
+
+// WithJSONOutput 要求模型输出 JSON，但不限定结构
+func WithJSONOutput() GenerateOption {
+	return func(o *generateOptions) {
+		o.json = true
+	}
+}
+
+// wantsJSON 调用方是否要求 JSON 输出；未指定时按自由文本生成，如候选人问答的 Markdown 回答
+func (o generateOptions) wantsJSON() bool {
+	return o.schema != nil || o.json
+}
+
+// WithResponseSchema 要求模型按指定结构输出 JSON
+func WithResponseSchema(schema *ResponseSchema) GenerateOption {
+	return func(o *generateOptions) {
+		o.schema = schema
+	}
+}
+
+// WithGenerationParams 指定温度、TopP、TopK 和最大输出令牌数，未指定时使用提供方的默认值
+func WithGenerationParams(params models.GenerationParams) GenerateOption {
+	return func(o *generateOptions) {
+		o.params = &params
+	}
+}
+
+// generationParams 返回指定的生成参数，未指定时返回 def
+func (o generateOptions) generationParams(def models.GenerationParams) models.GenerationParams {
+	if o.params != nil {
+		return *o.params
+	}
+	return def
+}
+
+// applyGenerateOptions 合并可选参数
+func applyGenerateOptions(opts []GenerateOption) generateOptions {
+	var o generateOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// FinishReason 模型结束生成的原因，提供方未返回时为空
+type FinishReason string
+
+const (
+	FinishReasonStop      FinishReason = "stop"       // 正常结束
+	FinishReasonMaxTokens FinishReason = "max_tokens" // 达到最大输出令牌数
+	FinishReasonSafety    FinishReason = "safety"     // 被安全策略终止
+	FinishReasonOther     FinishReason = "other"      // 其他原因
+)
+
+// Truncated 输出是否因达到最大输出令牌数而被截断
+func (r FinishReason) Truncated() bool {
+	return r == FinishReasonMaxTokens
+}
+
+// GenerateResult 一次生成调用的结果
+type GenerateResult struct {
+	Text         string       // 模型返回的文本
+	Provider     string       // 实际处理请求的提供方
+	Model        string       // 实际使用的模型
+	Usage        TokenUsage   // 本次调用消耗的令牌数，提供方未返回时为零值
+	FinishReason FinishReason // 模型结束生成的原因
+
+	CacheStatus CacheStatus // 响应缓存的命中情况，未启用缓存时为空
+	discard     func()      // 删除该响应对应的缓存
+}
+
+// Discard 响应不可用（如JSON解析失败）时删除对应的缓存，避免重复返回同一个无效响应
+func (r *GenerateResult) Discard() {
+	if r != nil && r.discard != nil {
+		r.discard()
+	}
+}
+
+// TokenUsage 一次调用的令牌用量
+type TokenUsage struct {
+	PromptTokens    int `json:"promptTokens"`
+	CandidateTokens int `json:"candidateTokens"`
+	TotalTokens     int `json:"totalTokens"`
+}
+
+// StreamIterator 流式生成结果的迭代器
+type StreamIterator interface {
+	// Next 返回下一段文本增量，流结束时返回 io.EOF
+	Next() (string, error)
+	// Usage 返回流结束后的令牌用量，提供方未返回时为零值
+	Usage() TokenUsage
+	// FinishReason 返回流结束后模型结束生成的原因，提供方未返回时为空
+	FinishReason() FinishReason
+	// Close 释放流占用的连接，流结束或出错后会自动释放，提前停止读取时由调用方调用，可重复调用
+	Close() error
+}
+
+// defaultRequestTimeout 一次请求中所有大模型调用的默认截止时间
+const defaultRequestTimeout = 5 * time.Minute
+
+// RequestContext 基于请求的上下文设置截止时间，超时由 LLM_REQUEST_TIMEOUT 配置（默认5分钟）
+// 客户端断开连接或超过截止时间时，进行中的调用和后续的重试都会被取消
+func RequestContext(parent context.Context) (context.Context, context.CancelFunc) {
+	timeout := defaultRequestTimeout
+	if value := os.Getenv("LLM_REQUEST_TIMEOUT"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			timeout = d
+		} else {
+			log.Printf("[WARN] 无效的 LLM_REQUEST_TIMEOUT: %s，使用默认值 %v", value, defaultRequestTimeout)
+		}
+	}
+	return context.WithTimeout(parent, timeout)
+}
+
+// 支持的提供方名称
+const (
+	ProviderVertex = "vertex"
+	ProviderOpenAI = "openai"
+	ProviderLocal  = "local"
+	ProviderFake   = "fake"
+)
+
+// NewLLMProvider 根据环境变量 LLM_PROVIDER 创建大模型提供方，默认使用 Vertex AI
+// 返回的提供方会对错误分类，并自动重试临时错误
+func NewLLMProvider() LLMProvider {
+	return WithRetry(newProviderByName(os.Getenv("LLM_PROVIDER")))
+}
+
+// newProviderByName 按名称创建提供方，未知名称回退到 Vertex AI
+func newProviderByName(name string) LLMProvider {
+	name = strings.ToLower(strings.TrimSpace(name))
+	switch name {
+	case "", ProviderVertex:
+		return NewVertexAIClient()
+	case ProviderOpenAI:
+		return NewOpenAIProvider()
+	case ProviderLocal:
+		return NewLocalProvider()
+	case ProviderFake:
+		return NewFakeProvider()
+	default:
+		log.Printf("[WARN] 未知的 LLM_PROVIDER: %s，回退到 %s", name, ProviderVertex)
+		return NewVertexAIClient()
+	}
+}
+
+// InitLLMProviders 在启动时初始化 LLM_PROVIDER 和任务路由中的提供方需要的长连接客户端
+func InitLLMProviders() error {
+	for _, name := range configuredProviders() {
+		if providerUsesVertex(name) {
+			return InitVertexAI()
+		}
+	}
+	return nil
+}
+
+// CloseLLMProviders 释放提供方持有的长连接客户端
+func CloseLLMProviders() {
+	CloseVertexAI()
+}
+
+// providerUsesVertex 判断指定提供方是否会调用 Vertex AI
+func providerUsesVertex(name string) bool {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case ProviderOpenAI, ProviderLocal:
+		return false
+	case ProviderFake:
+		if strings.ToLower(os.Getenv("LLM_FAKE_MODE")) != fakeModeRecord {
+			return false
+		}
+		upstream := os.Getenv("LLM_FAKE_UPSTREAM")
+		return upstream != ProviderFake && providerUsesVertex(upstream)
+	default:
+		return true
+	}
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"cloud.google.com/go/vertexai/genai"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LLMErrorKind 大模型调用错误的分类
+type LLMErrorKind string
+
+const (
+	ErrKindQuota       LLMErrorKind = "quota"       // 配额不足或触发限流
+	ErrKindUnavailable LLMErrorKind = "unavailable" // 服务暂时不可用
+	ErrKindTimeout     LLMErrorKind = "timeout"     // 请求超时
+	ErrKindEmpty       LLMErrorKind = "empty"       // 模型未返回候选内容
+	ErrKindSafety      LLMErrorKind = "safety"      // 被安全策略拦截
+	ErrKindBadInput    LLMErrorKind = "bad_input"   // 输入无法被模型处理
+	ErrKindAuth        LLMErrorKind = "auth"        // 凭证缺失或无权限
+	ErrKindCanceled    LLMErrorKind = "canceled"    // 调用方取消
+	ErrKindUnknown     LLMErrorKind = "unknown"     // 其他错误
+)
+
+// 提供方内部使用的哨兵错误，由 ClassifyLLMError 映射为对应分类
+var (
+	ErrEmptyResponse = errors.New("AI未返回有效内容")
+	ErrBadInput      = errors.New("输入内容无法被AI处理")
+	ErrCredentials   = errors.New("AI服务凭证无效")
+)
+
+// LLMError 带分类的大模型调用错误
+type LLMError struct {
+	Kind     LLMErrorKind
+	Provider string
+	Err      error
+}
+
+// Error 实现 error 接口
+func (e *LLMError) Error() string {
+	return fmt.Sprintf("%s 调用失败 (%s): %v", e.Provider, e.Kind, e.Err)
+}
+
+// Unwrap 返回原始错误
+func (e *LLMError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable 判断该错误是否为可重试的临时错误
+func (e *LLMError) Retryable() bool {
+	switch e.Kind {
+	case ErrKindQuota, ErrKindUnavailable, ErrKindTimeout, ErrKindEmpty:
+		return true
+	default:
+		return false
+	}
+}
+
+// HTTPStatusError OpenAI 兼容接口和本地模型返回的非 2xx 响应
+type HTTPStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+// Error 实现 error 接口
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("AI内容生成失败: 状态码 %d, 响应: %s", e.StatusCode, e.Body)
+}
+
+// ClassifyLLMError 将提供方返回的错误归类为 *LLMError，已分类的错误原样返回
+func ClassifyLLMError(provider string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var llmErr *LLMError
+	if errors.As(err, &llmErr) {
+		return err
+	}
+
+	return &LLMError{Kind: classify(err), Provider: provider, Err: err}
+}
+
+// LLMErrorKindOf 返回错误的分类，未分类的错误返回 ErrKindUnknown
+func LLMErrorKindOf(err error) LLMErrorKind {
+	var llmErr *LLMError
+	if errors.As(err, &llmErr) {
+		return llmErr.Kind
+	}
+	return ErrKindUnknown
+}
+
+// classify 根据错误类型、gRPC 状态码和 HTTP 状态码判断分类
+func classify(err error) LLMErrorKind {
+	switch {
+	case errors.Is(err, ErrStreamIdleTimeout):
+		return ErrKindTimeout
+	case errors.Is(err, context.Canceled):
+		return ErrKindCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrKindTimeout
+	case errors.Is(err, ErrEmptyResponse):
+		return ErrKindEmpty
+	case errors.Is(err, ErrBadInput):
+		return ErrKindBadInput
+	case errors.Is(err, ErrCredentials):
+		return ErrKindAuth
+	}
+
+	var blocked *genai.BlockedError
+	if errors.As(err, &blocked) {
+		return ErrKindSafety
+	}
+
+	var httpErr *HTTPStatusError
+	if errors.As(err, &httpErr) {
+		switch {
+		case httpErr.StatusCode == http.StatusTooManyRequests:
+			return ErrKindQuota
+		case httpErr.StatusCode == http.StatusUnauthorized || httpErr.StatusCode == http.StatusForbidden:
+			return ErrKindAuth
+		case httpErr.StatusCode == http.StatusRequestTimeout || httpErr.StatusCode == http.StatusGatewayTimeout:
+			return ErrKindTimeout
+		case httpErr.StatusCode >= 500:
+			return ErrKindUnavailable
+		case httpErr.StatusCode >= 400:
+			return ErrKindBadInput
+		}
+	}
+
+	if s, ok := status.FromError(err); ok {
+		switch s.Code() {
+		case codes.ResourceExhausted:
+			return ErrKindQuota
+		case codes.Unavailable, codes.Internal, codes.Aborted:
+			return ErrKindUnavailable
+		case codes.DeadlineExceeded:
+			return ErrKindTimeout
+		case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+			return ErrKindBadInput
+		case codes.Unauthenticated, codes.PermissionDenied:
+			return ErrKindAuth
+		case codes.Canceled:
+			return ErrKindCanceled
+		}
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return ErrKindTimeout
+		}
+		return ErrKindUnavailable
+	}
+
+	return ErrKindUnknown
+}
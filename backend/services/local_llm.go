@@ -0,0 +1,376 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/GiantClam/ai-resume/models"
+)
+
+// LocalProvider 调用本地部署模型的提供方，支持 Ollama (/api/chat) 和 llama.cpp server
+// 本地模型通常无法直接读取PDF等二进制文件，简历文件会先提取为纯文本再发送
+type LocalProvider struct {
+	flavor     string
+	baseURL    string
+	model      string
+	httpClient *http.Client
+	// llama.cpp server 提供 OpenAI 兼容接口，直接复用 OpenAIProvider 的协议实现
+	openAI *OpenAIProvider
+}
+
+var _ LLMProvider = (*LocalProvider)(nil)
+
+// 本地模型服务类型
+const (
+	localFlavorOllama   = "ollama"
+	localFlavorLlamaCpp = "llamacpp"
+)
+
+// NewLocalProvider 根据环境变量创建本地模型提供方
+func NewLocalProvider() *LocalProvider {
+	flavor := strings.ToLower(os.Getenv("LOCAL_LLM_FLAVOR"))
+	if flavor != localFlavorLlamaCpp {
+		flavor = localFlavorOllama
+	}
+
+	baseURL := strings.TrimRight(os.Getenv("LOCAL_LLM_BASE_URL"), "/")
+	if baseURL == "" {
+		if flavor == localFlavorOllama {
+			baseURL = "http://localhost:11434"
+		} else {
+			baseURL = "http://localhost:8081"
+		}
+	}
+
+	model := os.Getenv("LOCAL_LLM_MODEL")
+	if model == "" {
+		model = "qwen2.5:7b"
+	}
+
+	p := &LocalProvider{
+		flavor:     flavor,
+		baseURL:    baseURL,
+		model:      model,
+		httpClient: &http.Client{},
+	}
+
+	if flavor == localFlavorLlamaCpp {
+		p.openAI = &OpenAIProvider{
+			baseURL:    baseURL + "/v1",
+			model:      model,
+			jsonMode:   openAIJSONModeSchema,
+			fileMode:   openAIFileModeText,
+			httpClient: p.httpClient,
+		}
+	}
+
+	return p
+}
+
+// Name 返回提供方名称
+func (p *LocalProvider) Name() string {
+	return ProviderLocal
+}
+
+// Model 返回当前使用的模型名称
+func (p *LocalProvider) Model() string {
+	return p.model
+}
+
+// ollamaMessage Ollama 对话消息
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ollamaOptions Ollama 生成参数
+type ollamaOptions struct {
+	Temperature float32 `json:"temperature"`
+	TopP        float32 `json:"top_p"`
+	TopK        int32   `json:"top_k"`
+	NumPredict  int32   `json:"num_predict"`
+}
+
+// ollamaChatRequest /api/chat 请求体
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Format   interface{}     `json:"format,omitempty"`
+	Options  ollamaOptions   `json:"options"`
+}
+
+// ollamaChatResponse /api/chat 响应体，流式时每行一个对象
+type ollamaChatResponse struct {
+	Message    ollamaMessage `json:"message"`
+	Done       bool          `json:"done"`
+	DoneReason string        `json:"done_reason"`
+	Error      string        `json:"error"`
+	// 用量仅在最后一个响应中给出
+	PromptEvalCount int `json:"prompt_eval_count"`
+	EvalCount       int `json:"eval_count"`
+}
+
+// tokenUsage 转换为统一的用量结构
+func (r *ollamaChatResponse) tokenUsage() TokenUsage {
+	return TokenUsage{
+		PromptTokens:    r.PromptEvalCount,
+		CandidateTokens: r.EvalCount,
+		TotalTokens:     r.PromptEvalCount + r.EvalCount,
+	}
+}
+
+// GenerateContent 根据系统指令和文本提示生成内容
+func (p *LocalProvider) GenerateContent(ctx context.Context, systemInstruction, prompt string, opts ...GenerateOption) (*GenerateResult, error) {
+	if p.openAI != nil {
+		return p.relabel(p.openAI.GenerateContent(ctx, systemInstruction, prompt, opts...))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 120*time.Second)
+	defer cancel()
+
+	req := p.newOllamaRequest(systemInstruction, sanitizeUTF8(prompt), defaultTextParams, applyGenerateOptions(opts))
+	return p.ollamaComplete(ctx, req)
+}
+
+// GenerateContentWithBinaryFile 将简历文件提取为纯文本后生成内容
+func (p *LocalProvider) GenerateContentWithBinaryFile(ctx context.Context, systemInstruction string, fileContent string, mimeType string, textPrompt string, opts ...GenerateOption) (*GenerateResult, error) {
+	if p.openAI != nil {
+		return p.relabel(p.openAI.GenerateContentWithBinaryFile(ctx, systemInstruction, fileContent, mimeType, textPrompt, opts...))
+	}
+
+	resumeText, err := ExtractPlainText(ctx, []byte(fileContent), mimeType)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[DEBUG] 本地模型使用纯文本模式，提取文本长度: %d 字符", len(resumeText))
+
+	combinedPrompt := "请分析以下简历内容："
+	if textPrompt != "" {
+		combinedPrompt += "\n\n" + sanitizeUTF8(strings.TrimSpace(textPrompt))
+	}
+	combinedPrompt += "\n\n" + UntrustedBlock("简历内容", resumeText)
+
+	ctx, cancel := context.WithTimeout(ctx, 120*time.Second)
+	defer cancel()
+
+	req := p.newOllamaRequest(systemInstruction, combinedPrompt, defaultFileParams, applyGenerateOptions(opts))
+	return p.ollamaComplete(ctx, req)
+}
+
+// GenerateContentStream 流式生成内容
+func (p *LocalProvider) GenerateContentStream(ctx context.Context, systemInstruction, prompt string, opts ...GenerateOption) (StreamIterator, error) {
+	if p.openAI != nil {
+		return p.openAI.GenerateContentStream(ctx, systemInstruction, prompt, opts...)
+	}
+
+	req := p.newOllamaRequest(systemInstruction, sanitizeUTF8(prompt), defaultTextParams, applyGenerateOptions(opts))
+	req.Stream = true
+
+	// 响应体的读取受流的上下文控制，流结束、空闲超时或关闭时连接随之释放
+	return openStream(ctx, func(ctx context.Context) (StreamIterator, error) {
+		resp, err := p.do(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		return &ollamaStream{body: resp.Body, scanner: scanner}, nil
+	})
+}
+
+// relabel 将 llama.cpp 复用 OpenAI 协议得到的结果标记为本地提供方
+func (p *LocalProvider) relabel(result *GenerateResult, err error) (*GenerateResult, error) {
+	if err != nil {
+		return nil, err
+	}
+	result.Provider = p.Name()
+	return result, nil
+}
+
+// ollamaFinishReason 转换 Ollama 返回的 done_reason
+func ollamaFinishReason(reason string) FinishReason {
+	switch reason {
+	case "":
+		return ""
+	case "stop":
+		return FinishReasonStop
+	case "length":
+		return FinishReasonMaxTokens
+	default:
+		return FinishReasonOther
+	}
+}
+
+// newOllamaRequest 构建 Ollama 请求，调用方要求时输出 JSON，提供结构约束时按 JSON Schema 输出，未指定生成参数时使用 def
+func (p *LocalProvider) newOllamaRequest(systemInstruction, prompt string, def models.GenerationParams, o generateOptions) *ollamaChatRequest {
+	params := o.generationParams(def)
+	var format interface{}
+	switch {
+	case o.schema != nil:
+		format = o.schema.JSON
+	case o.json:
+		format = "json"
+	}
+
+	return &ollamaChatRequest{
+		Model: p.model,
+		Messages: []ollamaMessage{
+			{Role: "system", Content: systemInstruction},
+			{Role: "user", Content: prompt},
+		},
+		Format: format,
+		Options: ollamaOptions{
+			Temperature: params.Temperature,
+			TopP:        params.TopP,
+			TopK:        params.TopK,
+			NumPredict:  params.MaxOutputTokens,
+		},
+	}
+}
+
+// ollamaComplete 发送非流式请求并解析结果
+func (p *LocalProvider) ollamaComplete(ctx context.Context, req *ollamaChatRequest) (*GenerateResult, error) {
+	resp, err := p.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var chatResp ollamaChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		return nil, fmt.Errorf("解析AI响应失败: %w", err)
+	}
+	if chatResp.Error != "" {
+		return nil, fmt.Errorf("AI内容生成失败: %s", chatResp.Error)
+	}
+	if chatResp.Message.Content == "" {
+		return nil, fmt.Errorf("%w: 响应中没有文本", ErrEmptyResponse)
+	}
+
+	log.Printf("[DEBUG] 本地模型 %s 响应接收成功，长度: %d 字符", p.model, len(chatResp.Message.Content))
+
+	return &GenerateResult{
+		Text:         sanitizeUTF8(chatResp.Message.Content),
+		Provider:     p.Name(),
+		Model:        p.model,
+		Usage:        chatResp.tokenUsage(),
+		FinishReason: ollamaFinishReason(chatResp.DoneReason),
+	}, nil
+}
+
+// do 发送请求到 Ollama /api/chat
+func (p *LocalProvider) do(ctx context.Context, chatReq *ollamaChatRequest) (*http.Response, error) {
+	body, err := json.Marshal(chatReq)
+	if err != nil {
+		return nil, fmt.Errorf("序列化请求失败: %w", err)
+	}
+
+	url := p.baseURL + "/api/chat"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	log.Printf("[DEBUG] 开始向本地模型 %s 发送请求, 模型: %s, 流式: %v", url, p.model, chatReq.Stream)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("AI内容生成失败: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, &HTTPStatusError{StatusCode: resp.StatusCode, Body: string(errBody)}
+	}
+
+	return resp, nil
+}
+
+// ollamaStream 解析 Ollama 按行分隔的 JSON 流
+type ollamaStream struct {
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+	done    bool
+	usage   TokenUsage
+	reason  FinishReason
+}
+
+// Next 返回下一段文本增量，收到 done=true 或连接关闭时返回 io.EOF
+func (s *ollamaStream) Next() (string, error) {
+	if s.done {
+		return "", io.EOF
+	}
+
+	for s.scanner.Scan() {
+		line := bytes.TrimSpace(s.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var chunk ollamaChatResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			s.finish()
+			return "", fmt.Errorf("解析流式响应失败: %w", err)
+		}
+		if chunk.Error != "" {
+			s.finish()
+			return "", fmt.Errorf("AI内容生成失败: %s", chunk.Error)
+		}
+		if chunk.Done {
+			s.usage = chunk.tokenUsage()
+			s.reason = ollamaFinishReason(chunk.DoneReason)
+			s.finish()
+			if chunk.Message.Content != "" {
+				return chunk.Message.Content, nil
+			}
+			return "", io.EOF
+		}
+		if chunk.Message.Content != "" {
+			return chunk.Message.Content, nil
+		}
+	}
+
+	err := s.scanner.Err()
+	s.finish()
+	if err != nil {
+		return "", err
+	}
+	return "", io.EOF
+}
+
+// Usage 返回流结束后的令牌用量
+func (s *ollamaStream) Usage() TokenUsage {
+	return s.usage
+}
+
+// FinishReason 返回流结束后模型结束生成的原因
+func (s *ollamaStream) FinishReason() FinishReason {
+	return s.reason
+}
+
+// Close 关闭响应体
+func (s *ollamaStream) Close() error {
+	s.finish()
+	return nil
+}
+
+// finish 关闭响应体
+func (s *ollamaStream) finish() {
+	if !s.done {
+		s.done = true
+		s.body.Close()
+	}
+}
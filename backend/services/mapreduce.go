@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/GiantClam/ai-resume/models"
+)
+
+// 长文本分块处理的默认参数（按字符数）
+const (
+	defaultMapReduceThreshold = 20000 // 超过该长度的输入先分块提取要点
+	defaultMapChunkSize       = 8000  // 每个分块的最大长度
+	maxReduceRounds           = 3     // 汇总后的要点仍然过长时最多再分块处理的轮数
+)
+
+// 分块处理的输入类型，用于提示模型当前处理的内容
+const (
+	NotesKindResume    = "简历"
+	NotesKindInterview = "面试记录"
+)
+
+// CondensedText 分块处理后用于最终生成的文本
+type CondensedText struct {
+	Text    string            // 未超过阈值时为原文，否则为汇总后的要点
+	Chunks  int               // 第一轮的分块数，未分块时为0
+	Results []*GenerateResult // 各次要点提取调用的结果，用于统计缓存命中情况
+}
+
+// ProviderFactory 按提示模板创建提供方，使要点提取调用的用量归属到对应的模板
+type ProviderFactory func(prompt PromptRef) LLMProvider
+
+// CondenseText 对超过 LLM_MAP_REDUCE_THRESHOLD 的输入执行 map 阶段：按 LLM_MAP_CHUNK_SIZE 分块，
+// 逐块提取结构化要点并合并；合并后的要点仍然过长时再次分块处理。调用方用返回的文本完成最终的筛选、出题或总结（reduce 阶段）
+func CondenseText(ctx context.Context, newProvider ProviderFactory, kind, text, jobRequirements string, params models.GenerationParams) (*CondensedText, error) {
+	threshold, chunkSize := mapReduceConfig()
+	condensed := &CondensedText{Text: text}
+	if !NeedsCondensing(text) {
+		return condensed, nil
+	}
+
+	for round := 1; round <= maxReduceRounds; round++ {
+		chunks := ChunkText(condensed.Text, chunkSize)
+		if round == 1 {
+			condensed.Chunks = len(chunks)
+		}
+		log.Printf("[INFO] %s长度 %d 字符超过 %d，第 %d 轮分为 %d 块提取要点", kind, utf8.RuneCountInString(condensed.Text), threshold, round, len(chunks))
+
+		var notes strings.Builder
+		for i, chunk := range chunks {
+			if err := ctx.Err(); err != nil {
+				return nil, ClassifyLLMError("", err)
+			}
+
+			part, result, err := extractNotes(ctx, newProvider, NotesPromptInput{
+				Kind:            kind,
+				JobRequirements: jobRequirements,
+				Part:            i + 1,
+				Total:           len(chunks),
+				Content:         chunk,
+			}, params)
+			if err != nil {
+				return nil, err
+			}
+			condensed.Results = append(condensed.Results, result)
+			writeNotes(&notes, i+1, len(chunks), part)
+		}
+
+		condensed.Text = strings.TrimSpace(notes.String())
+		if utf8.RuneCountInString(condensed.Text) <= threshold {
+			return condensed, nil
+		}
+	}
+
+	log.Printf("[WARN] %s经过 %d 轮要点提取后仍有 %d 字符，直接使用", kind, maxReduceRounds, utf8.RuneCountInString(condensed.Text))
+	return condensed, nil
+}
+
+// NeedsCondensing 输入是否超过 LLM_MAP_REDUCE_THRESHOLD，需要先分块提取要点
+func NeedsCondensing(text string) bool {
+	threshold, _ := mapReduceConfig()
+	return threshold > 0 && utf8.RuneCountInString(text) > threshold
+}
+
+// extractNotes 提取一个分块的要点
+func extractNotes(ctx context.Context, newProvider ProviderFactory, input NotesPromptInput, params models.GenerationParams) (*models.ChunkNotes, *GenerateResult, error) {
+	rendered, err := NotesPrompt.Render(input)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	provider := newProvider(rendered.PromptRef)
+	schema := WithResponseSchema(SchemaFor(models.ChunkNotes{}))
+	label := fmt.Sprintf("%s要点提取 %d/%d", input.Kind, input.Part, input.Total)
+	return GenerateJSON[models.ChunkNotes](label, rendered.Prompt, func(p string) (*GenerateResult, error) {
+		return provider.GenerateContent(ctx, rendered.System, p, schema, WithGenerationParams(params))
+	})
+}
+
+// writeNotes 将一个分块的要点写成供最终生成使用的文本
+func writeNotes(b *strings.Builder, part, total int, notes *models.ChunkNotes) {
+	fmt.Fprintf(b, "第 %d/%d 部分\n", part, total)
+	for _, section := range []struct {
+		title string
+		items []string
+	}{
+		{"客观信息", notes.Facts},
+		{"亮点", notes.Highlights},
+		{"不足与疑点", notes.Concerns},
+	} {
+		if len(section.items) == 0 {
+			continue
+		}
+		fmt.Fprintf(b, "%s:\n", section.title)
+		for _, item := range section.items {
+			fmt.Fprintf(b, "- %s\n", strings.TrimSpace(item))
+		}
+	}
+	b.WriteString("\n")
+}
+
+// ChunkText 按行将文本拆分为不超过 size 个字符的分块，单行过长时按字符截断
+func ChunkText(text string, size int) []string {
+	if size <= 0 || utf8.RuneCountInString(text) <= size {
+		return []string{text}
+	}
+
+	var chunks []string
+	var current strings.Builder
+	currentLen := 0
+	flush := func() {
+		if chunk := strings.TrimSpace(current.String()); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		current.Reset()
+		currentLen = 0
+	}
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		lineLen := utf8.RuneCountInString(line)
+		if currentLen+lineLen > size {
+			flush()
+		}
+		for lineLen > size {
+			runes := []rune(line)
+			chunks = append(chunks, string(runes[:size]))
+			line = string(runes[size:])
+			lineLen -= size
+		}
+		current.WriteString(line)
+		currentLen += lineLen
+	}
+	flush()
+	return chunks
+}
+
+// mapReduceConfig 读取 LLM_MAP_REDUCE_THRESHOLD 和 LLM_MAP_CHUNK_SIZE，阈值为0时不分块
+func mapReduceConfig() (threshold, chunkSize int) {
+	threshold, chunkSize = defaultMapReduceThreshold, defaultMapChunkSize
+	if value := os.Getenv("LLM_MAP_REDUCE_THRESHOLD"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+			threshold = n
+		} else {
+			log.Printf("[WARN] 无效的 LLM_MAP_REDUCE_THRESHOLD: %s，使用默认值 %d", value, defaultMapReduceThreshold)
+		}
+	}
+	if value := os.Getenv("LLM_MAP_CHUNK_SIZE"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			chunkSize = n
+		} else {
+			log.Printf("[WARN] 无效的 LLM_MAP_CHUNK_SIZE: %s，使用默认值 %d", value, defaultMapChunkSize)
+		}
+	}
+	// 分块不超过阈值，超过阈值的输入至少拆分为两块
+	if threshold > 0 && chunkSize > threshold {
+		chunkSize = threshold
+	}
+	return threshold, chunkSize
+}
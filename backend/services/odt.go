@@ -0,0 +1,265 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ODT（OpenDocument 文本）中与文本相关的部件
+const (
+	odtContentPart  = "content.xml"
+	odtStylesPart   = "styles.xml"
+	odtManifestPart = "META-INF/manifest.xml"
+)
+
+// ExtractODTText 从 ODT 文件中提取纯文本
+// 依次输出页眉、正文和页脚；表格的每一行占一行，单元格以制表符分隔；列表项带上项目符号，下级列表缩进
+func ExtractODTText(data []byte) (string, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("无法解析ODT文件: %w", err)
+	}
+	parts := make(map[string]*zip.File, len(reader.File))
+	for _, f := range reader.File {
+		parts[f.Name] = f
+	}
+	if parts[odtContentPart] == nil {
+		return "", fmt.Errorf("ODT文件中缺少 %s", odtContentPart)
+	}
+	budget := newDecodeBudget()
+	// 加密的文档在清单中带有 encryption-data
+	if f := parts[odtManifestPart]; f != nil {
+		manifest, err := readZipFile(f, budget)
+		if errors.Is(err, ErrDocumentTooLarge) {
+			return "", err
+		}
+		if err == nil && bytes.Contains(manifest, []byte("encryption-data")) {
+			return "", fmt.Errorf("%w: ODT文件受密码保护", ErrDocumentEncrypted)
+		}
+	}
+
+	// 页眉页脚定义在 styles.xml 的母版页中
+	var header, footer string
+	if f := parts[odtStylesPart]; f != nil {
+		w := &odtWriter{masterOnly: true}
+		if err := parseDocxXML(f, budget, w.parse); err != nil {
+			return "", fmt.Errorf("解析ODT样式失败: %w", err)
+		}
+		header, footer = strings.Join(w.headers, "\n"), strings.Join(w.footers, "\n")
+	}
+
+	w := &odtWriter{}
+	if err := parseDocxXML(parts[odtContentPart], budget, w.parse); err != nil {
+		return "", fmt.Errorf("解析ODT文档内容失败: %w", err)
+	}
+
+	var sections []string
+	for _, text := range []string{header, strings.Join(w.lines, "\n"), footer} {
+		if text = strings.TrimSpace(text); text != "" {
+			sections = append(sections, text)
+		}
+	}
+	return strings.Join(sections, "\n\n"), nil
+}
+
+// readZipFile 读取压缩包中的文件，解压的数据计入 budget
+func readZipFile(f *zip.File, budget *decodeBudget) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(budget.reader(rc))
+	if errors.Is(err, ErrDocumentTooLarge) {
+		return nil, fmt.Errorf("%w: %s 解压后超过上限", ErrDocumentTooLarge, f.Name)
+	}
+	return data, err
+}
+
+// odtWriter 将 OpenDocument 的段落、列表和表格输出为纯文本
+type odtWriter struct {
+	// masterOnly 只读取 styles.xml 母版页中的页眉页脚
+	masterOnly bool
+	// 所在页眉页脚的类型和嵌套深度
+	region      string
+	regionDepth int
+	headers     []string
+	footers     []string
+
+	lines      []string
+	paras      []*strings.Builder
+	listDepth  int
+	itemStarts []bool // 每层列表项是否还未输出首段
+	tables     []*docxTable
+}
+
+// odtMaxSpaces <text:s> 最多输出的空格数，排版用的连续空格不会更多，c 属性可以任意大
+const odtMaxSpaces = 64
+
+// parse 读取 content.xml 或 styles.xml
+func (w *odtWriter) parse(decoder *xml.Decoder) error {
+	// 跳过批注、修订记录、脚注正文等不属于正文的内容
+	skipDepth := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if skipDepth > 0 {
+				skipDepth++
+				continue
+			}
+			if w.regionDepth > 0 {
+				w.regionDepth++
+			}
+			switch t.Name.Local {
+			case "header", "footer":
+				if t.Name.Space == odfStyleNS && w.regionDepth == 0 {
+					w.region, w.regionDepth = t.Name.Local, 1
+				}
+			case "annotation", "tracked-changes", "note-body", "header-left", "footer-left", "header-first", "footer-first":
+				// 跳过的元素的结束标记不会再经过下面的深度计数
+				skipDepth = 1
+				if w.regionDepth > 0 {
+					w.regionDepth--
+				}
+			case "p", "h":
+				w.paras = append(w.paras, &strings.Builder{})
+			case "s":
+				count, err := strconv.Atoi(docxAttr(t, "c"))
+				if err != nil || count < 1 {
+					count = 1
+				}
+				count = min(count, odtMaxSpaces)
+				w.write(strings.Repeat(" ", count))
+			case "tab":
+				w.write("\t")
+			case "line-break":
+				w.write("\n")
+			case "list":
+				w.listDepth++
+				w.itemStarts = append(w.itemStarts, false)
+			case "list-item", "list-header":
+				if n := len(w.itemStarts); n > 0 {
+					w.itemStarts[n-1] = t.Name.Local == "list-item"
+				}
+			case "table":
+				w.tables = append(w.tables, &docxTable{})
+			case "table-row":
+				if table := w.table(); table != nil {
+					table.cells = nil
+				}
+			case "table-cell":
+				if table := w.table(); table != nil {
+					table.cell = nil
+				}
+			}
+
+		case xml.CharData:
+			if skipDepth == 0 && len(w.paras) > 0 {
+				w.write(string(t))
+			}
+
+		case xml.EndElement:
+			if skipDepth > 0 {
+				skipDepth--
+				continue
+			}
+			switch t.Name.Local {
+			case "p", "h":
+				w.endParagraph()
+			case "list":
+				w.listDepth--
+				if n := len(w.itemStarts); n > 0 {
+					w.itemStarts = w.itemStarts[:n-1]
+				}
+			case "table-cell":
+				if table := w.table(); table != nil {
+					table.cells = append(table.cells, strings.Join(table.cell, " "))
+				}
+			case "table-row":
+				if table := w.table(); table != nil {
+					row := strings.TrimRight(strings.Join(table.cells, "\t"), "\t")
+					if strings.TrimSpace(row) != "" {
+						w.emit(row, len(w.tables)-1)
+					}
+				}
+			case "table":
+				if n := len(w.tables); n > 0 {
+					w.tables = w.tables[:n-1]
+				}
+			}
+			if w.regionDepth > 0 {
+				w.regionDepth--
+			}
+		}
+	}
+}
+
+// odfStyleNS OpenDocument 样式命名空间
+const odfStyleNS = "urn:oasis:names:tc:opendocument:xmlns:style:1.0"
+
+// table 返回当前所在的表格
+func (w *odtWriter) table() *docxTable {
+	if n := len(w.tables); n > 0 {
+		return w.tables[n-1]
+	}
+	return nil
+}
+
+// write 将文字追加到当前段落
+func (w *odtWriter) write(text string) {
+	if n := len(w.paras); n > 0 {
+		w.paras[n-1].WriteString(text)
+	}
+}
+
+// endParagraph 为列表项的首段加上项目符号，输出到当前单元格或正文
+func (w *odtWriter) endParagraph() {
+	n := len(w.paras)
+	if n == 0 {
+		return
+	}
+	text := strings.TrimSpace(w.paras[n-1].String())
+	w.paras = w.paras[:n-1]
+	if text == "" {
+		return
+	}
+	if d := len(w.itemStarts); d > 0 && w.itemStarts[d-1] {
+		text = strings.Repeat("  ", w.listDepth-1) + "• " + text
+		w.itemStarts[d-1] = false
+	}
+	w.emit(text, len(w.tables))
+}
+
+// emit 输出一段文本，depth 为所在表格的层数，在表格中时写入对应层的当前单元格
+func (w *odtWriter) emit(text string, depth int) {
+	if depth > 0 {
+		table := w.tables[depth-1]
+		table.cell = append(table.cell, strings.ReplaceAll(text, "\n", " "))
+		return
+	}
+	if w.masterOnly {
+		switch {
+		case w.regionDepth == 0:
+		case w.region == "header":
+			w.headers = append(w.headers, text)
+		default:
+			w.footers = append(w.footers, text)
+		}
+		return
+	}
+	w.lines = append(w.lines, text)
+}
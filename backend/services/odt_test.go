@@ -0,0 +1,103 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// odtXML 为 OpenDocument 片段加上根元素和命名空间
+func odtXML(root, body string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<office:` + root + ` xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0"` +
+		` xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0"` +
+		` xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0"` +
+		` xmlns:style="` + odfStyleNS + `">` + body + `</office:` + root + `>`
+}
+
+const testODTContent = `<office:body><office:text>` +
+	`<text:tracked-changes><text:changed-region><text:deletion><text:p>已删除</text:p></text:deletion></text:changed-region></text:tracked-changes>` +
+	`<text:h text:outline-level="1">张三</text:h>` +
+	`<text:p>电话<text:s text:c="3"/>138<text:tab/>邮箱<text:line-break/>zhangsan@example.com</text:p>` +
+	`<text:p>简介<office:annotation><text:p>批注</text:p></office:annotation></text:p>` +
+	`<text:list><text:list-item><text:p>Go</text:p><text:p>五年经验</text:p>` +
+	`<text:list><text:list-item><text:p>微服务</text:p></text:list-item></text:list></text:list-item>` +
+	`<text:list-header><text:p>说明</text:p></text:list-header>` +
+	`<text:list-item><text:p>Kubernetes</text:p></text:list-item></text:list>` +
+	`<table:table><table:table-row><table:table-cell><text:p>公司</text:p></table:table-cell><table:table-cell><text:p>职位</text:p></table:table-cell></table:table-row>` +
+	`<table:table-row><table:table-cell><text:p>某科技</text:p><text:p>（北京）</text:p></table:table-cell>` +
+	`<table:table-cell><table:table><table:table-row><table:table-cell><text:p>工程师</text:p></table:table-cell><table:table-cell><text:p>2020</text:p></table:table-cell></table:table-row></table:table></table:table-cell></table:table-row></table:table>` +
+	`</office:text></office:body>`
+
+const testODTStyles = `<office:master-styles><style:master-page style:name="Standard">` +
+	`<style:header><text:p>张三 - 简历</text:p></style:header>` +
+	`<style:header-left><text:p>左页页眉</text:p></style:header-left>` +
+	`<style:footer><text:p>机密</text:p></style:footer>` +
+	`</style:master-page></office:master-styles>`
+
+// testODT 包含页眉页脚、嵌套列表、嵌套表格、批注和修订记录的 ODT
+func testODT(t testing.TB) []byte {
+	return buildZip(t,
+		zipPart{"mimetype", "application/vnd.oasis.opendocument.text"},
+		zipPart{odtContentPart, odtXML("document-content", testODTContent)},
+		zipPart{odtStylesPart, odtXML("document-styles", testODTStyles)},
+		zipPart{odtManifestPart, `<manifest:manifest xmlns:manifest="urn:oasis:names:tc:opendocument:xmlns:manifest:1.0"/>`},
+	)
+}
+
+func TestExtractODTText(t *testing.T) {
+	got, err := ExtractODTText(testODT(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "张三 - 简历\n\n" +
+		"张三\n" +
+		"电话   138\t邮箱\nzhangsan@example.com\n" +
+		"简介\n" +
+		"• Go\n五年经验\n" +
+		"  • 微服务\n" +
+		"说明\n" +
+		"• Kubernetes\n" +
+		"公司\t职位\n" +
+		"某科技 （北京）\t工程师\t2020\n\n" +
+		"机密"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// hugeSpaceCount <text:s> 的 c 属性取极大值的 content.xml
+var hugeSpaceCount = odtXML("document-content", `<office:body><office:text><text:p>电话<text:s text:c="1000000000"/>138</text:p></office:text></office:body>`)
+
+func TestExtractODTTextSpaceCount(t *testing.T) {
+	got, err := ExtractODTText(buildZip(t, zipPart{odtContentPart, hugeSpaceCount}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "电话" + strings.Repeat(" ", odtMaxSpaces) + "138"; got != want {
+		t.Errorf("got %d bytes, want %q", len(got), want)
+	}
+}
+
+func TestExtractODTTextErrors(t *testing.T) {
+	encrypted := buildZip(t,
+		zipPart{odtContentPart, "encrypted"},
+		zipPart{odtManifestPart, `<manifest:manifest><manifest:file-entry><manifest:encryption-data/></manifest:file-entry></manifest:manifest>`},
+	)
+	if _, err := ExtractODTText(encrypted); !errors.Is(err, ErrDocumentEncrypted) {
+		t.Errorf("encrypted: err = %v, want ErrDocumentEncrypted", err)
+	}
+	if _, err := ExtractODTText(buildZip(t, zipPart{odtStylesPart, "<x/>"})); err == nil {
+		t.Error("expected error for missing content part")
+	}
+	if _, err := ExtractODTText([]byte("not a zip")); err == nil {
+		t.Error("expected error for non-zip data")
+	}
+}
+
+func FuzzExtractODTText(f *testing.F) {
+	fuzzZipParts(f, ExtractODTText, [2]string{odtContentPart, odtStylesPart},
+		zipSeed{odtXML("document-content", testODTContent), odtXML("document-styles", testODTStyles)},
+		zipSeed{hugeSpaceCount, ""},
+	)
+}
@@ -0,0 +1,471 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/GiantClam/ai-resume/models"
+)
+
+// OpenAIProvider 兼容 OpenAI /v1/chat/completions 协议的提供方
+// 可用于 OpenAI、Azure OpenAI、DeepSeek、通义千问以及 vLLM 等服务
+type OpenAIProvider struct {
+	baseURL    string
+	apiKey     string
+	apiVersion string // 仅 Azure OpenAI 使用
+	model      string
+	jsonMode   string
+	fileMode   string
+	httpClient *http.Client
+}
+
+var _ LLMProvider = (*OpenAIProvider)(nil)
+
+// JSON 输出模式
+const (
+	openAIJSONModeSchema = "schema"      // 提供结构约束时使用 json_schema，否则使用 json_object
+	openAIJSONModeObject = "json_object" // 始终使用 json_object
+	openAIJSONModeOff    = "off"         // 不设置 response_format
+)
+
+// 文件传递方式
+const (
+	openAIFileModeFile = "file" // 以 file 内容块发送原始文件
+	openAIFileModeText = "text" // 将文件作为文本内联到提示中
+)
+
+// NewOpenAIProvider 根据环境变量创建 OpenAI 兼容提供方
+func NewOpenAIProvider() *OpenAIProvider {
+	baseURL := strings.TrimRight(os.Getenv("OPENAI_BASE_URL"), "/")
+	if baseURL == "" {
+		baseURL = "https://api.openai.com/v1"
+	}
+
+	model := os.Getenv("OPENAI_MODEL")
+	if model == "" {
+		model = "gpt-4o-mini"
+	}
+
+	fileMode := strings.ToLower(os.Getenv("OPENAI_FILE_MODE"))
+	if fileMode != openAIFileModeText {
+		fileMode = openAIFileModeFile
+	}
+
+	return &OpenAIProvider{
+		baseURL:    baseURL,
+		apiKey:     os.Getenv("OPENAI_API_KEY"),
+		apiVersion: os.Getenv("OPENAI_API_VERSION"),
+		model:      model,
+		jsonMode:   parseOpenAIJSONMode(os.Getenv("OPENAI_JSON_MODE")),
+		fileMode:   fileMode,
+		// 不设置整体超时，流式请求的生命周期由上下文控制
+		httpClient: &http.Client{},
+	}
+}
+
+// parseOpenAIJSONMode 解析 OPENAI_JSON_MODE，兼容旧的 true/false 取值
+func parseOpenAIJSONMode(value string) string {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "false", openAIJSONModeOff:
+		return openAIJSONModeOff
+	case "true", openAIJSONModeObject:
+		return openAIJSONModeObject
+	default:
+		return openAIJSONModeSchema
+	}
+}
+
+// Name 返回提供方名称
+func (p *OpenAIProvider) Name() string {
+	return ProviderOpenAI
+}
+
+// Model 返回当前使用的模型名称
+func (p *OpenAIProvider) Model() string {
+	return p.model
+}
+
+// openAIMessage 对话消息，content 可以是字符串或内容块数组
+type openAIMessage struct {
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"`
+}
+
+// openAIContentPart 多模态内容块
+type openAIContentPart struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	File     *openAIFilePart `json:"file,omitempty"`
+	ImageURL *openAIImageURL `json:"image_url,omitempty"`
+}
+
+type openAIFilePart struct {
+	Filename string `json:"filename"`
+	FileData string `json:"file_data"`
+}
+
+type openAIImageURL struct {
+	URL string `json:"url"`
+}
+
+type openAIResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *openAIJSONSchema `json:"json_schema,omitempty"`
+}
+
+type openAIJSONSchema struct {
+	Name   string                 `json:"name"`
+	Schema map[string]interface{} `json:"schema"`
+	Strict bool                   `json:"strict"`
+}
+
+// openAIChatRequest chat/completions 请求体
+type openAIChatRequest struct {
+	Model          string                `json:"model"`
+	Messages       []openAIMessage       `json:"messages"`
+	Temperature    float32               `json:"temperature"`
+	TopP           float32               `json:"top_p"`
+	MaxTokens      int32                 `json:"max_tokens"`
+	Stream         bool                  `json:"stream,omitempty"`
+	StreamOptions  *openAIStreamOptions  `json:"stream_options,omitempty"`
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+}
+
+// openAIStreamOptions 流式请求选项，要求在最后一个数据块中返回用量
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// openAIUsage 令牌用量
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// tokenUsage 转换为统一的用量结构
+func (u *openAIUsage) tokenUsage() TokenUsage {
+	if u == nil {
+		return TokenUsage{}
+	}
+	return TokenUsage{PromptTokens: u.PromptTokens, CandidateTokens: u.CompletionTokens, TotalTokens: u.TotalTokens}
+}
+
+// openAIChatResponse chat/completions 响应体（非流式和流式共用）
+type openAIChatResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"error"`
+}
+
+// GenerateContent 根据系统指令和文本提示生成内容
+func (p *OpenAIProvider) GenerateContent(ctx context.Context, systemInstruction, prompt string, opts ...GenerateOption) (*GenerateResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	req := p.newRequest(systemInstruction, sanitizeUTF8(prompt), defaultTextParams, applyGenerateOptions(opts))
+	return p.complete(ctx, req)
+}
+
+// GenerateContentWithBinaryFile 携带简历文件生成内容
+func (p *OpenAIProvider) GenerateContentWithBinaryFile(ctx context.Context, systemInstruction string, fileContent string, mimeType string, textPrompt string, opts ...GenerateOption) (*GenerateResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	fileData := []byte(fileContent)
+	if len(fileData) > 25*1024*1024 {
+		return nil, fmt.Errorf("%w: 文件过大，超过25MB限制: %d 字节", ErrBadInput, len(fileData))
+	}
+
+	combinedPrompt := "请分析以下简历文件："
+	if textPrompt != "" {
+		combinedPrompt += "\n\n" + sanitizeUTF8(strings.TrimSpace(textPrompt))
+	}
+
+	req := p.newRequest(systemInstruction, "", defaultFileParams, applyGenerateOptions(opts))
+
+	if p.fileMode == openAIFileModeText {
+		text, err := ExtractPlainText(ctx, fileData, mimeType)
+		if err != nil {
+			return nil, err
+		}
+		req.Messages[1].Content = combinedPrompt + "\n\n" + UntrustedBlock("简历内容", text)
+	} else {
+		req.Messages[1].Content = []openAIContentPart{
+			fileContentPart(fileData, mimeType),
+			{Type: "text", Text: combinedPrompt},
+		}
+	}
+
+	log.Printf("[DEBUG] 向 %s 发送文件内容, 大小: %d 字节, MIME类型: %s, 方式: %s", p.baseURL, len(fileData), mimeType, p.fileMode)
+	return p.complete(ctx, req)
+}
+
+// GenerateContentStream 通过 SSE 流式生成内容
+func (p *OpenAIProvider) GenerateContentStream(ctx context.Context, systemInstruction, prompt string, opts ...GenerateOption) (StreamIterator, error) {
+	req := p.newRequest(systemInstruction, sanitizeUTF8(prompt), defaultTextParams, applyGenerateOptions(opts))
+	req.Stream = true
+	req.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+
+	// 响应体的读取受流的上下文控制，流结束、空闲超时或关闭时连接随之释放
+	return openStream(ctx, func(ctx context.Context) (StreamIterator, error) {
+		resp, err := p.do(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		return &openAIStream{body: resp.Body, scanner: scanner}, nil
+	})
+}
+
+// newRequest 构建基础请求，未指定生成参数时使用 def；OpenAI 协议不支持 TopK
+func (p *OpenAIProvider) newRequest(systemInstruction, prompt string, def models.GenerationParams, o generateOptions) *openAIChatRequest {
+	params := o.generationParams(def)
+	req := &openAIChatRequest{
+		Model: p.model,
+		Messages: []openAIMessage{
+			{Role: "system", Content: systemInstruction},
+			{Role: "user", Content: prompt},
+		},
+		Temperature: params.Temperature,
+		TopP:        params.TopP,
+		MaxTokens:   params.MaxOutputTokens,
+	}
+	switch {
+	case !o.wantsJSON():
+		// 自由文本输出不设置 response_format，json_object 模式还要求提示中出现 "json"
+	case p.jsonMode == openAIJSONModeSchema && o.schema != nil:
+		req.ResponseFormat = &openAIResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: &openAIJSONSchema{Name: o.schema.Name, Schema: o.schema.JSON, Strict: true},
+		}
+	case p.jsonMode != openAIJSONModeOff:
+		req.ResponseFormat = &openAIResponseFormat{Type: "json_object"}
+	}
+	return req
+}
+
+// complete 发送非流式请求并解析结果
+func (p *OpenAIProvider) complete(ctx context.Context, req *openAIChatRequest) (*GenerateResult, error) {
+	resp, err := p.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var chatResp openAIChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		return nil, fmt.Errorf("解析AI响应失败: %w", err)
+	}
+
+	if len(chatResp.Choices) == 0 {
+		return nil, ErrEmptyResponse
+	}
+
+	responseText := chatResp.Choices[0].Message.Content
+	if responseText == "" {
+		return nil, fmt.Errorf("%w: 响应中没有文本", ErrEmptyResponse)
+	}
+
+	log.Printf("[DEBUG] %s 响应接收成功，长度: %d 字符", p.model, len(responseText))
+
+	return &GenerateResult{
+		Text:         sanitizeUTF8(responseText),
+		Provider:     p.Name(),
+		Model:        p.model,
+		Usage:        chatResp.Usage.tokenUsage(),
+		FinishReason: openAIFinishReason(chatResp.Choices[0].FinishReason),
+	}, nil
+}
+
+// openAIFinishReason 转换 OpenAI 兼容接口返回的 finish_reason
+func openAIFinishReason(reason string) FinishReason {
+	switch reason {
+	case "":
+		return ""
+	case "stop":
+		return FinishReasonStop
+	case "length":
+		return FinishReasonMaxTokens
+	case "content_filter":
+		return FinishReasonSafety
+	default:
+		return FinishReasonOther
+	}
+}
+
+// do 发送 HTTP 请求，非 2xx 状态码时返回错误
+func (p *OpenAIProvider) do(ctx context.Context, chatReq *openAIChatRequest) (*http.Response, error) {
+	body, err := json.Marshal(chatReq)
+	if err != nil {
+		return nil, fmt.Errorf("序列化请求失败: %w", err)
+	}
+
+	url := p.baseURL + "/chat/completions"
+	if p.apiVersion != "" {
+		url += "?api-version=" + p.apiVersion
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if chatReq.Stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+	if p.apiKey != "" {
+		if p.apiVersion != "" {
+			// Azure OpenAI 使用 api-key 请求头
+			req.Header.Set("api-key", p.apiKey)
+		} else {
+			req.Header.Set("Authorization", "Bearer "+p.apiKey)
+		}
+	}
+
+	log.Printf("[DEBUG] 开始向 %s 发送请求, 模型: %s, 流式: %v", url, p.model, chatReq.Stream)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("AI内容生成失败: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, &HTTPStatusError{StatusCode: resp.StatusCode, Body: string(errBody)}
+	}
+
+	return resp, nil
+}
+
+// openAIStream 解析 SSE 格式的流式响应
+type openAIStream struct {
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+	done    bool
+	usage   TokenUsage
+	reason  FinishReason
+}
+
+// Next 返回下一段文本增量，收到 [DONE] 或连接关闭时返回 io.EOF
+func (s *openAIStream) Next() (string, error) {
+	if s.done {
+		return "", io.EOF
+	}
+
+	for s.scanner.Scan() {
+		line := strings.TrimSpace(s.scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			// 忽略空行、注释和 event 字段
+			continue
+		}
+
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			s.finish()
+			return "", io.EOF
+		}
+
+		var chunk openAIChatResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			s.finish()
+			return "", fmt.Errorf("解析流式响应失败: %w", err)
+		}
+		if chunk.Error != nil {
+			s.finish()
+			return "", fmt.Errorf("AI内容生成失败: %s", chunk.Error.Message)
+		}
+		if chunk.Usage != nil {
+			s.usage = chunk.Usage.tokenUsage()
+		}
+		if len(chunk.Choices) > 0 && chunk.Choices[0].FinishReason != "" {
+			s.reason = openAIFinishReason(chunk.Choices[0].FinishReason)
+		}
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			return chunk.Choices[0].Delta.Content, nil
+		}
+	}
+
+	err := s.scanner.Err()
+	s.finish()
+	if err != nil {
+		return "", err
+	}
+	return "", io.EOF
+}
+
+// Usage 返回流结束后的令牌用量
+func (s *openAIStream) Usage() TokenUsage {
+	return s.usage
+}
+
+// FinishReason 返回流结束后模型结束生成的原因
+func (s *openAIStream) FinishReason() FinishReason {
+	return s.reason
+}
+
+// Close 关闭响应体
+func (s *openAIStream) Close() error {
+	s.finish()
+	return nil
+}
+
+// finish 关闭响应体
+func (s *openAIStream) finish() {
+	if !s.done {
+		s.done = true
+		s.body.Close()
+	}
+}
+
+// fileContentPart 根据MIME类型构建文件内容块
+func fileContentPart(data []byte, mimeType string) openAIContentPart {
+	dataURL := fmt.Sprintf("data:%s;base64,%s", mimeType, base64.StdEncoding.EncodeToString(data))
+	if strings.HasPrefix(mimeType, "image/") {
+		return openAIContentPart{Type: "image_url", ImageURL: &openAIImageURL{URL: dataURL}}
+	}
+	return openAIContentPart{
+		Type: "file",
+		File: &openAIFilePart{Filename: "resume" + extensionForMimeType(mimeType), FileData: dataURL},
+	}
+}
+
+// extensionForMimeType 返回MIME类型对应的文件扩展名
+func extensionForMimeType(mimeType string) string {
+	switch {
+	case strings.HasPrefix(mimeType, "application/pdf"):
+		return ".pdf"
+	case strings.HasPrefix(mimeType, "application/vnd.openxmlformats-officedocument.wordprocessingml.document"):
+		return ".docx"
+	case strings.HasPrefix(mimeType, "application/msword"):
+		return ".doc"
+	case strings.HasPrefix(mimeType, "text/plain"):
+		return ".txt"
+	default:
+		return ""
+	}
+}
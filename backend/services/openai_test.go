@@ -0,0 +1,47 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/GiantClam/ai-resume/models"
+)
+
+func TestOpenAIResponseFormat(t *testing.T) {
+	schema := SchemaFor(models.ScreeningResponse{})
+	tests := []struct {
+		name     string
+		jsonMode string
+		opts     []GenerateOption
+		want     string
+	}{
+		{"free text", openAIJSONModeSchema, nil, ""},
+		{"free text json_object mode", openAIJSONModeObject, nil, ""},
+		{"schema", openAIJSONModeSchema, []GenerateOption{WithResponseSchema(schema)}, "json_schema"},
+		{"schema json_object mode", openAIJSONModeObject, []GenerateOption{WithResponseSchema(schema)}, "json_object"},
+		{"explicit json", openAIJSONModeSchema, []GenerateOption{WithJSONOutput()}, "json_object"},
+		{"off", openAIJSONModeOff, []GenerateOption{WithResponseSchema(schema)}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &OpenAIProvider{model: "m", jsonMode: tt.jsonMode}
+			req := p.newRequest("sys", "prompt", models.GenerationParams{}, applyGenerateOptions(tt.opts))
+			got := ""
+			if req.ResponseFormat != nil {
+				got = req.ResponseFormat.Type
+			}
+			if got != tt.want {
+				t.Errorf("response_format = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOllamaFormat(t *testing.T) {
+	p := &LocalProvider{model: "m"}
+	if req := p.newOllamaRequest("sys", "prompt", models.GenerationParams{}, applyGenerateOptions(nil)); req.Format != nil {
+		t.Errorf("free text format = %v, want nil", req.Format)
+	}
+	if req := p.newOllamaRequest("sys", "prompt", models.GenerationParams{}, applyGenerateOptions([]GenerateOption{WithJSONOutput()})); req.Format != "json" {
+		t.Errorf("json format = %v, want json", req.Format)
+	}
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/GiantClam/ai-resume/models"
+)
+
+// 未指定生成参数时各提供方使用的默认值：文本调用偏向确定性的短输出，携带文件的调用允许更长的输出，多轮问答的回答更自然
+var (
+	defaultTextParams = models.GenerationParams{Temperature: 0.1, TopP: 0.7, TopK: 30, MaxOutputTokens: 4096}
+	defaultFileParams = models.GenerationParams{Temperature: 0.2, TopP: 0.8, TopK: 40, MaxOutputTokens: 8192}
+	defaultChatParams = models.GenerationParams{Temperature: 0.4, TopP: 0.9, TopK: 40, MaxOutputTokens: 2048}
+)
+
+// taskDefaultParams 各任务的默认生成参数，可通过 LLM_PARAMS_DEFAULT 和 LLM_PARAMS_<TASK> 覆盖
+var taskDefaultParams = map[Task]models.GenerationParams{
+	TaskScreening:  defaultFileParams,
+	TaskQuestions:  defaultTextParams,
+	TaskSummary:    defaultTextParams,
+	TaskStream:     defaultTextParams,
+	TaskChat:       defaultChatParams,
+	TaskCompliance: defaultTextParams,
+}
+
+// ErrInvalidParams 生成参数格式错误或超出允许范围
+var ErrInvalidParams = errors.New("无效的生成参数")
+
+// ParamOverrides 对生成参数的部分覆盖，未设置的字段保持原值
+type ParamOverrides struct {
+	Temperature     *float32
+	TopP            *float32
+	TopK            *int32
+	MaxOutputTokens *int32
+}
+
+// Empty 是否没有覆盖任何参数
+func (o ParamOverrides) Empty() bool {
+	return o.Temperature == nil && o.TopP == nil && o.TopK == nil && o.MaxOutputTokens == nil
+}
+
+// Apply 返回覆盖后的参数
+func (o ParamOverrides) Apply(p models.GenerationParams) models.GenerationParams {
+	if o.Temperature != nil {
+		p.Temperature = *o.Temperature
+	}
+	if o.TopP != nil {
+		p.TopP = *o.TopP
+	}
+	if o.TopK != nil {
+		p.TopK = *o.TopK
+	}
+	if o.MaxOutputTokens != nil {
+		p.MaxOutputTokens = *o.MaxOutputTokens
+	}
+	return p
+}
+
+// ParseParamOverrides 解析逗号分隔的 name=value 列表，例如 temperature=0.3,maxOutputTokens=2048
+// 参数名不区分大小写，支持 temperature、topP、topK、maxOutputTokens
+func ParseParamOverrides(spec string) (ParamOverrides, error) {
+	var o ParamOverrides
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return o, fmt.Errorf("%w: %s 缺少取值", ErrInvalidParams, item)
+		}
+		name, value = strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(value)
+
+		switch name {
+		case "temperature", "topp", "top_p":
+			f, err := strconv.ParseFloat(value, 32)
+			if err != nil {
+				return o, fmt.Errorf("%w: %s 不是有效的数字", ErrInvalidParams, item)
+			}
+			v := float32(f)
+			if name == "temperature" {
+				o.Temperature = &v
+			} else {
+				o.TopP = &v
+			}
+		case "topk", "top_k", "maxoutputtokens", "max_output_tokens":
+			n, err := strconv.ParseInt(value, 10, 32)
+			if err != nil {
+				return o, fmt.Errorf("%w: %s 不是有效的整数", ErrInvalidParams, item)
+			}
+			v := int32(n)
+			if strings.HasPrefix(name, "top") {
+				o.TopK = &v
+			} else {
+				o.MaxOutputTokens = &v
+			}
+		default:
+			return o, fmt.Errorf("%w: 未知的参数 %s", ErrInvalidParams, name)
+		}
+	}
+	return o, nil
+}
+
+// ParamsForTask 返回任务配置的生成参数：内置默认值依次被 LLM_PARAMS_DEFAULT 和 LLM_PARAMS_<TASK> 覆盖
+func ParamsForTask(task Task) models.GenerationParams {
+	params, ok := taskDefaultParams[task]
+	if !ok {
+		params = defaultTextParams
+	}
+
+	for _, name := range []string{"LLM_PARAMS_DEFAULT", "LLM_PARAMS_" + strings.ToUpper(string(task))} {
+		spec := os.Getenv(name)
+		if spec == "" {
+			continue
+		}
+		overrides, err := ParseParamOverrides(spec)
+		if err == nil {
+			err = ValidateParams(overrides.Apply(params))
+		}
+		if err != nil {
+			log.Printf("[WARN] 忽略无效的 %s: %v", name, err)
+			continue
+		}
+		params = overrides.Apply(params)
+	}
+	return params
+}
+
+// ValidateParams 检查生成参数是否在允许范围内
+// temperature 为 0 到 LLM_PARAMS_MAX_TEMPERATURE（默认 1），topP 为 0 到 1，topK 为 1 到 100，
+// maxOutputTokens 为 1 到 LLM_PARAMS_MAX_OUTPUT_TOKENS（默认 8192）
+func ValidateParams(p models.GenerationParams) error {
+	maxTemperature := float32(envFloat("LLM_PARAMS_MAX_TEMPERATURE", 1))
+	maxOutputTokens := int32(envInt("LLM_PARAMS_MAX_OUTPUT_TOKENS", 8192))
+
+	switch {
+	case p.Temperature < 0 || p.Temperature > maxTemperature:
+		return fmt.Errorf("%w: temperature 应在 0 到 %g 之间", ErrInvalidParams, maxTemperature)
+	case p.TopP <= 0 || p.TopP > 1:
+		return fmt.Errorf("%w: topP 应大于 0 且不超过 1", ErrInvalidParams)
+	case p.TopK < 1 || p.TopK > 100:
+		return fmt.Errorf("%w: topK 应在 1 到 100 之间", ErrInvalidParams)
+	case p.MaxOutputTokens < 1 || p.MaxOutputTokens > maxOutputTokens:
+		return fmt.Errorf("%w: maxOutputTokens 应在 1 到 %d 之间", ErrInvalidParams, maxOutputTokens)
+	}
+	return nil
+}
+
+// CanOverrideParams 用户是否在 LLM_PARAMS_TRUSTED_USERS 中，只有这些用户可以按请求覆盖生成参数
+func CanOverrideParams(userID uint) bool {
+	if userID == 0 {
+		return false
+	}
+	for _, item := range strings.Split(os.Getenv("LLM_PARAMS_TRUSTED_USERS"), ",") {
+		if id, err := strconv.ParseUint(strings.TrimSpace(item), 10, 64); err == nil && uint(id) == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// envFloat 读取浮点数环境变量，未设置或无效时返回默认值
+func envFloat(name string, def float64) float64 {
+	if value := os.Getenv(name); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+		log.Printf("[WARN] 无效的 %s: %s，使用默认值 %g", name, value, def)
+	}
+	return def
+}
+
+// envInt 读取整数环境变量，未设置或无效时返回默认值
+func envInt(name string, def int) int {
+	if value := os.Getenv(name); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+		log.Printf("[WARN] 无效的 %s: %s，使用默认值 %d", name, value, def)
+	}
+	return def
+}
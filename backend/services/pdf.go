@@ -0,0 +1,698 @@
+package services
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// PDF 文本提取的错误
+var (
+	// ErrNoTextLayer PDF 没有可提取的文字，通常是扫描件或图片导出的文件
+	ErrNoTextLayer = errors.New("PDF中没有可提取的文字，可能是扫描件")
+	// ErrPDFEncrypted PDF 已加密，无法读取内容
+	ErrPDFEncrypted = errors.New("PDF已加密，无法提取文字")
+	// ErrInvalidPDF 文件不是有效的 PDF
+	ErrInvalidPDF = errors.New("无效的PDF文件")
+)
+
+// maxUnmappedRatio 无法映射到 Unicode 的字符超过该比例时视为没有可用的文本层
+const maxUnmappedRatio = 0.5
+
+// ExtractPDFText 从 PDF 的文本层中按阅读顺序提取文字，支持多栏排版和带 ToUnicode 或预定义 CMap 的中日韩字体
+// 没有文本层（扫描件）或文字无法映射到 Unicode 时返回 ErrNoTextLayer
+func ExtractPDFText(data []byte) (string, error) {
+	doc, err := parsePDF(data)
+	if err != nil {
+		return "", err
+	}
+
+	pages := doc.pages()
+	if len(pages) == 0 {
+		return "", fmt.Errorf("%w: 未找到页面", ErrInvalidPDF)
+	}
+
+	var b strings.Builder
+	var stats pdfTextStats
+	for i, page := range pages {
+		text := doc.pageText(page, &stats)
+		if text == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+		b.WriteString(text)
+		log.Printf("[DEBUG] PDF 第 %d/%d 页提取 %d 个字符", i+1, len(pages), len([]rune(text)))
+	}
+
+	if doc.budget.exceeded {
+		return "", fmt.Errorf("%w: PDF流解压后超过 %d MB", ErrDocumentTooLarge, maxDecodedDocumentSize>>20)
+	}
+	text := strings.TrimSpace(b.String())
+	if stats.glyphs > 0 && float64(stats.unmapped)/float64(stats.glyphs) > maxUnmappedRatio {
+		return "", fmt.Errorf("%w（%d/%d 个字符的字体缺少 Unicode 映射）", ErrNoTextLayer, stats.unmapped, stats.glyphs)
+	}
+	if strings.IndexFunc(text, func(r rune) bool { return !unicode.IsSpace(r) }) < 0 {
+		return "", fmt.Errorf("%w（共 %d 页，%d 张图片）", ErrNoTextLayer, len(pages), stats.images)
+	}
+	return text, nil
+}
+
+// PDF 对象类型
+type (
+	pdfName    string
+	pdfString  []byte
+	pdfKeyword string
+	pdfArray   []interface{}
+	pdfDict    map[string]interface{}
+	pdfRef     struct{ num, gen int }
+	pdfStream  struct {
+		dict pdfDict
+		raw  []byte
+	}
+)
+
+// pdfDocument 解析后的 PDF 对象表
+type pdfDocument struct {
+	objects map[int]interface{}
+	root    pdfDict
+
+	fonts   map[interface{}]*pdfFont // 按字体字典所在的引用缓存，直接内嵌的字体字典不缓存
+	streams map[*pdfStream][]byte    // 解码后的流数据
+	budget  *decodeBudget            // 解码流数据的额度
+}
+
+// pdfObjectRe 间接对象的开头
+var pdfObjectRe = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// pdfTrailerRe 文件尾字典的开头
+var pdfTrailerRe = regexp.MustCompile(`trailer\s*<<`)
+
+// parsePDF 扫描文件中的所有间接对象（包括对象流中的对象），不依赖交叉引用表，损坏或增量更新的文件也能读取
+func parsePDF(data []byte) (*pdfDocument, error) {
+	if !bytes.Contains(data[:min(len(data), 1024)], []byte("%PDF")) {
+		return nil, ErrInvalidPDF
+	}
+
+	doc := &pdfDocument{
+		objects: map[int]interface{}{},
+		fonts:   map[interface{}]*pdfFont{},
+		streams: map[*pdfStream][]byte{},
+		budget:  newDecodeBudget(),
+	}
+	var trailers []pdfDict
+	for _, loc := range pdfObjectRe.FindAllSubmatchIndex(data, -1) {
+		num, _ := strconv.Atoi(string(data[loc[2]:loc[3]]))
+		lex := &pdfLexer{data: data, pos: loc[1]}
+		obj, err := lex.readObject()
+		if err != nil {
+			continue
+		}
+		if dict, ok := obj.(pdfDict); ok {
+			if stream := lex.readStream(dict); stream != nil {
+				obj = stream
+				if dict["Type"] == pdfName("XRef") {
+					trailers = append(trailers, dict)
+				}
+			}
+		}
+		// 增量更新时后出现的定义覆盖之前的定义
+		doc.objects[num] = obj
+	}
+
+	for _, loc := range pdfTrailerRe.FindAllIndex(data, -1) {
+		lex := &pdfLexer{data: data, pos: loc[0] + len("trailer")}
+		if obj, err := lex.readObject(); err == nil {
+			if dict, ok := obj.(pdfDict); ok {
+				trailers = append(trailers, dict)
+			}
+		}
+	}
+
+	doc.loadObjectStreams()
+
+	for _, trailer := range trailers {
+		if trailer["Encrypt"] != nil {
+			return nil, ErrPDFEncrypted
+		}
+		if root, ok := doc.resolve(trailer["Root"]).(pdfDict); ok {
+			doc.root = root
+		}
+	}
+	if doc.root == nil {
+		// 交叉引用信息损坏时查找目录对象
+		for _, obj := range doc.objects {
+			if dict, ok := obj.(pdfDict); ok && dict["Type"] == pdfName("Catalog") {
+				doc.root = dict
+				break
+			}
+		}
+	}
+	if doc.root == nil {
+		return nil, fmt.Errorf("%w: 未找到文档目录", ErrInvalidPDF)
+	}
+	return doc, nil
+}
+
+// loadObjectStreams 展开 PDF 1.5 的对象流，对象流中的对象不覆盖文件中直接定义的同号对象
+func (d *pdfDocument) loadObjectStreams() {
+	var streams []*pdfStream
+	for _, obj := range d.objects {
+		if s, ok := obj.(*pdfStream); ok && s.dict["Type"] == pdfName("ObjStm") {
+			streams = append(streams, s)
+		}
+	}
+	for _, s := range streams {
+		data, err := d.decodeStream(s)
+		if err != nil {
+			log.Printf("[DEBUG] 解码PDF对象流失败: %v", err)
+			continue
+		}
+		n, _ := d.resolve(s.dict["N"]).(float64)
+		first, _ := d.resolve(s.dict["First"]).(float64)
+		if int(first) > len(data) {
+			continue
+		}
+
+		header := &pdfLexer{data: data[:int(first)]}
+		for i := 0; i < int(n); i++ {
+			num, err1 := header.readObject()
+			offset, err2 := header.readObject()
+			objNum, ok1 := num.(float64)
+			objOffset, ok2 := offset.(float64)
+			if err1 != nil || err2 != nil || !ok1 || !ok2 {
+				break
+			}
+			if _, exists := d.objects[int(objNum)]; exists {
+				continue
+			}
+			lex := &pdfLexer{data: data, pos: int(first) + int(objOffset)}
+			if obj, err := lex.readObject(); err == nil {
+				d.objects[int(objNum)] = obj
+			}
+		}
+	}
+}
+
+// resolve 解析间接引用，多层引用时逐层解析
+func (d *pdfDocument) resolve(obj interface{}) interface{} {
+	for i := 0; i < 8; i++ {
+		ref, ok := obj.(pdfRef)
+		if !ok {
+			return obj
+		}
+		obj = d.objects[ref.num]
+	}
+	return nil
+}
+
+// dict 解析为字典，流对象返回其字典
+func (d *pdfDocument) dict(obj interface{}) pdfDict {
+	switch v := d.resolve(obj).(type) {
+	case pdfDict:
+		return v
+	case *pdfStream:
+		return v.dict
+	}
+	return nil
+}
+
+// array 解析为数组
+func (d *pdfDocument) array(obj interface{}) pdfArray {
+	a, _ := d.resolve(obj).(pdfArray)
+	return a
+}
+
+// number 解析为数字，不是数字时返回 def
+func (d *pdfDocument) number(obj interface{}, def float64) float64 {
+	if n, ok := d.resolve(obj).(float64); ok {
+		return n
+	}
+	return def
+}
+
+// pdfPage 页面及其继承的资源
+type pdfPage struct {
+	dict      pdfDict
+	resources pdfDict
+}
+
+// pages 按顺序遍历页面树，资源字典可以从父节点继承
+func (d *pdfDocument) pages() []pdfPage {
+	var pages []pdfPage
+	visited := map[interface{}]bool{}
+	var walk func(node interface{}, resources pdfDict, depth int)
+	walk = func(node interface{}, resources pdfDict, depth int) {
+		if ref, ok := node.(pdfRef); ok {
+			if visited[ref] {
+				return
+			}
+			visited[ref] = true
+		}
+		dict := d.dict(node)
+		if dict == nil || depth > 32 {
+			return
+		}
+		if r := d.dict(dict["Resources"]); r != nil {
+			resources = r
+		}
+		kids := d.array(dict["Kids"])
+		if dict["Type"] == pdfName("Page") || (kids == nil && dict["Contents"] != nil) {
+			pages = append(pages, pdfPage{dict: dict, resources: resources})
+			return
+		}
+		for _, kid := range kids {
+			walk(kid, resources, depth+1)
+		}
+	}
+	walk(d.root["Pages"], nil, 0)
+	return pages
+}
+
+// contents 页面的内容流，多个内容流按顺序拼接
+func (d *pdfDocument) contents(page pdfDict) []byte {
+	var streams []interface{}
+	switch v := d.resolve(page["Contents"]).(type) {
+	case *pdfStream:
+		streams = append(streams, v)
+	case pdfArray:
+		streams = v
+	}
+
+	var buf bytes.Buffer
+	for _, obj := range streams {
+		s, ok := d.resolve(obj).(*pdfStream)
+		if !ok {
+			continue
+		}
+		data, err := d.decodeStream(s)
+		if err != nil {
+			log.Printf("[DEBUG] 解码PDF内容流失败: %v", err)
+			continue
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// decodeStream 按 Filter 依次解码流数据
+func (d *pdfDocument) decodeStream(s *pdfStream) ([]byte, error) {
+	if data, ok := d.streams[s]; ok {
+		return data, nil
+	}
+
+	var filters []string
+	switch f := d.resolve(s.dict["Filter"]).(type) {
+	case pdfName:
+		filters = []string{string(f)}
+	case pdfArray:
+		for _, item := range f {
+			if name, ok := d.resolve(item).(pdfName); ok {
+				filters = append(filters, string(name))
+			}
+		}
+	}
+
+	data := s.raw
+	for _, filter := range filters {
+		var err error
+		switch filter {
+		case "FlateDecode", "Fl":
+			data, err = inflate(data, d.budget)
+		case "ASCIIHexDecode", "AHx":
+			data, err = decodeASCIIHex(data)
+		case "ASCII85Decode", "A85":
+			data, err = decodeASCII85(data)
+		case "RunLengthDecode", "RL":
+			data = decodeRunLength(data, d.budget.limit())
+			err = d.budget.charge(len(data))
+		default:
+			// 图片等其他编码的流不包含文字
+			err = fmt.Errorf("不支持的PDF流编码: %s", filter)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	d.streams[s] = data
+	return data, nil
+}
+
+// inflate 解压 FlateDecode 数据，数据被截断时返回已解压的部分，解压后超过额度时返回 ErrDocumentTooLarge
+func inflate(data []byte, budget *decodeBudget) ([]byte, error) {
+	var r io.ReadCloser
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		// 部分生成器省略了 zlib 头
+		r = flate.NewReader(bytes.NewReader(data))
+	}
+	defer r.Close()
+	out, err := io.ReadAll(budget.reader(r))
+	if errors.Is(err, ErrDocumentTooLarge) || err != nil && len(out) == 0 {
+		return nil, err
+	}
+	return out, nil
+}
+
+// decodeASCIIHex 解码 ASCIIHexDecode 数据
+func decodeASCIIHex(data []byte) ([]byte, error) {
+	var digits []byte
+	for _, c := range data {
+		if c == '>' {
+			break
+		}
+		if isPDFWhitespace(c) {
+			continue
+		}
+		digits = append(digits, c)
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	_, err := hex.Decode(out, digits)
+	return out, err
+}
+
+// decodeASCII85 解码 ASCII85Decode 数据
+func decodeASCII85(data []byte) ([]byte, error) {
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~"))
+	if i := bytes.Index(data, []byte("~>")); i >= 0 {
+		data = data[:i]
+	}
+	out := make([]byte, len(data)*4/5+4)
+	n, _, err := ascii85.Decode(out, data, true)
+	return out[:n], err
+}
+
+// decodeRunLength 解码 RunLengthDecode 数据，输出超过 limit 字节时停止
+func decodeRunLength(data []byte, limit int) []byte {
+	var out []byte
+	for i := 0; i < len(data) && len(out) <= limit; {
+		n := int(data[i])
+		i++
+		switch {
+		case n == 128:
+			return out
+		case n < 128:
+			end := min(i+n+1, len(data))
+			out = append(out, data[i:end]...)
+			i = end
+		case i < len(data):
+			out = append(out, bytes.Repeat(data[i:i+1], 257-n)...)
+			i++
+		}
+	}
+	return out
+}
+
+// pdfLexer PDF 对象和内容流的词法分析器
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+// errPDFEOF 数据已读完
+var errPDFEOF = errors.New("PDF数据已结束")
+
+func isPDFWhitespace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+// skipSpace 跳过空白和注释
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isPDFWhitespace(c) {
+			l.pos++
+			continue
+		}
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		return
+	}
+}
+
+// readObject 读取一个对象；内容流中的操作符以 pdfKeyword 返回
+func (l *pdfLexer) readObject() (interface{}, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, errPDFEOF
+	}
+
+	c := l.data[l.pos]
+	switch {
+	case c == '/':
+		l.pos++
+		return pdfName(l.readRegular(true)), nil
+	case c == '(':
+		l.pos++
+		return l.readLiteralString(), nil
+	case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+		l.pos += 2
+		return l.readDict()
+	case c == '<':
+		l.pos++
+		end := bytes.IndexByte(l.data[l.pos:], '>')
+		if end < 0 {
+			return nil, errPDFEOF
+		}
+		s, _ := decodeASCIIHex(l.data[l.pos : l.pos+end])
+		l.pos += end + 1
+		return pdfString(s), nil
+	case c == '[':
+		l.pos++
+		var arr pdfArray
+		for {
+			l.skipSpace()
+			if l.pos >= len(l.data) {
+				return arr, errPDFEOF
+			}
+			if l.data[l.pos] == ']' {
+				l.pos++
+				return arr, nil
+			}
+			obj, err := l.readObject()
+			if err != nil {
+				return arr, err
+			}
+			arr = append(arr, obj)
+		}
+	case c == ']' || c == '>' || c == ')' || c == '{' || c == '}':
+		// 不成对的分隔符按操作符返回，由调用方忽略
+		l.pos++
+		return pdfKeyword(string(c)), nil
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		return l.readNumberOrRef(), nil
+	default:
+		word := l.readRegular(false)
+		switch word {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return pdfKeyword(word), nil
+	}
+}
+
+// readRegular 读取到下一个空白或分隔符为止的字符，名称中的 #xx 会被解码
+func (l *pdfLexer) readRegular(name bool) string {
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFWhitespace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	word := string(l.data[start:l.pos])
+	if l.pos == start && !name {
+		// 无法识别的字符，跳过以免死循环
+		l.pos++
+		return string(l.data[start:l.pos])
+	}
+	if name && strings.Contains(word, "#") {
+		var b strings.Builder
+		for i := 0; i < len(word); i++ {
+			if word[i] == '#' && i+2 < len(word) {
+				if v, err := strconv.ParseUint(word[i+1:i+3], 16, 8); err == nil {
+					b.WriteByte(byte(v))
+					i += 2
+					continue
+				}
+			}
+			b.WriteByte(word[i])
+		}
+		word = b.String()
+	}
+	return word
+}
+
+// readNumberOrRef 读取数字，后面跟着 "gen R" 时返回间接引用
+func (l *pdfLexer) readNumberOrRef() interface{} {
+	word := l.readRegular(false)
+	n, err := strconv.ParseFloat(word, 64)
+	if err != nil {
+		return pdfKeyword(word)
+	}
+	if strings.ContainsAny(word, ".+-") {
+		return n
+	}
+
+	// 尝试读取 "gen R"
+	save := l.pos
+	l.skipSpace()
+	genStart := l.pos
+	for l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '9' {
+		l.pos++
+	}
+	if l.pos > genStart {
+		gen, _ := strconv.Atoi(string(l.data[genStart:l.pos]))
+		l.skipSpace()
+		if l.pos < len(l.data) && l.data[l.pos] == 'R' && (l.pos+1 == len(l.data) || isPDFWhitespace(l.data[l.pos+1]) || isPDFDelimiter(l.data[l.pos+1])) {
+			l.pos++
+			return pdfRef{num: int(n), gen: gen}
+		}
+	}
+	l.pos = save
+	return n
+}
+
+// readLiteralString 读取 (...) 字符串，处理转义和嵌套括号
+func (l *pdfLexer) readLiteralString() pdfString {
+	var out []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '\\':
+			if l.pos >= len(l.data) {
+				return out
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r':
+				// 续行
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					out = append(out, byte(v))
+				} else {
+					out = append(out, e)
+				}
+			}
+		case '(':
+			depth++
+			out = append(out, c)
+		case ')':
+			depth--
+			if depth == 0 {
+				return out
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// readDict 读取 << ... >> 字典
+func (l *pdfLexer) readDict() (pdfDict, error) {
+	dict := pdfDict{}
+	for {
+		l.skipSpace()
+		if l.pos+1 < len(l.data) && l.data[l.pos] == '>' && l.data[l.pos+1] == '>' {
+			l.pos += 2
+			return dict, nil
+		}
+		key, err := l.readObject()
+		if err != nil {
+			return dict, err
+		}
+		name, ok := key.(pdfName)
+		if !ok {
+			continue
+		}
+		value, err := l.readObject()
+		if err != nil {
+			return dict, err
+		}
+		dict[string(name)] = value
+	}
+}
+
+// readStream 字典后面跟着 stream 关键字时读取流数据
+// 长度为直接数字、不超出文件且与 endstream 位置吻合时按长度读取，否则查找 endstream
+func (l *pdfLexer) readStream(dict pdfDict) *pdfStream {
+	save := l.pos
+	l.skipSpace()
+	if !bytes.HasPrefix(l.data[l.pos:], []byte("stream")) {
+		l.pos = save
+		return nil
+	}
+	start := l.pos + len("stream")
+	if start < len(l.data) && l.data[start] == '\r' {
+		start++
+	}
+	if start < len(l.data) && l.data[start] == '\n' {
+		start++
+	}
+
+	// 负数、超出文件末尾或无法转为整数的长度都视为无效
+	if length, ok := dict["Length"].(float64); ok && length >= 0 && length <= float64(len(l.data)-start) {
+		end := start + int(length)
+		rest := bytes.TrimLeft(l.data[end:min(end+32, len(l.data))], "\r\n \t")
+		if bytes.HasPrefix(rest, []byte("endstream")) {
+			l.pos = end
+			return &pdfStream{dict: dict, raw: l.data[start:end]}
+		}
+	}
+
+	end := bytes.Index(l.data[start:], []byte("endstream"))
+	if end < 0 {
+		return &pdfStream{dict: dict, raw: l.data[start:]}
+	}
+	raw := bytes.TrimRight(l.data[start:start+end], "\r\n")
+	l.pos = start + end
+	return &pdfStream{dict: dict, raw: raw}
+}
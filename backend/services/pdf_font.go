@@ -0,0 +1,451 @@
+package services
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+// pdfFont 解码文本所需的字体信息
+type pdfFont struct {
+	composite bool              // Type0 复合字体，字符编码可能为多字节
+	codespace []pdfCodeRange    // 复合字体的编码空间，用于确定每个字符编码的字节数
+	toUnicode map[uint32]string // ToUnicode CMap
+	simple    [256]string       // 简单字体按 Encoding 和 Differences 得到的字符
+	unicode16 bool              // 预定义的 UCS2/UTF16 CMap，字符编码即 UTF-16BE
+	charset   encoding.Encoding // 预定义的 GBK、Big5、Shift-JIS 等 CMap 对应的字符集
+
+	widths       map[uint32]float64 // 字形宽度，单位为千分之一字号
+	defaultWidth float64
+	widthScale   float64 // Type3 字体的 FontMatrix 缩放
+}
+
+// pdfCodeRange CMap 的编码空间
+type pdfCodeRange struct {
+	bytes     int
+	low, high uint32
+}
+
+// pdfGlyph 解码后的一个字符
+type pdfGlyph struct {
+	code  uint32
+	text  string // 无法映射到 Unicode 时为空
+	width float64
+	space bool // 单字节编码 32，字间距 Tw 只作用于该字符
+}
+
+// font 解析并缓存资源中的字体
+func (d *pdfDocument) font(obj interface{}) *pdfFont {
+	ref, isRef := obj.(pdfRef)
+	if isRef {
+		if f, ok := d.fonts[ref]; ok {
+			return f
+		}
+	}
+	f := d.loadFont(d.dict(obj))
+	if isRef {
+		d.fonts[ref] = f
+	}
+	return f
+}
+
+// loadFont 读取字体字典中的编码、ToUnicode 和宽度
+func (d *pdfDocument) loadFont(dict pdfDict) *pdfFont {
+	f := &pdfFont{widths: map[uint32]float64{}, defaultWidth: 500, widthScale: 1}
+	if dict == nil {
+		f.simple = winAnsiTable()
+		return f
+	}
+
+	if s, ok := d.resolve(dict["ToUnicode"]).(*pdfStream); ok {
+		if data, err := d.decodeStream(s); err == nil {
+			f.toUnicode, f.codespace = parseCMap(data)
+		}
+	}
+
+	if dict["Subtype"] == pdfName("Type0") {
+		f.composite = true
+		f.defaultWidth = 1000
+		d.loadCompositeEncoding(f, dict)
+		if descendants := d.array(dict["DescendantFonts"]); len(descendants) > 0 {
+			cid := d.dict(descendants[0])
+			f.defaultWidth = d.number(cid["DW"], 1000)
+			d.loadCIDWidths(f, d.array(cid["W"]))
+		}
+		return f
+	}
+
+	f.simple = d.simpleEncoding(dict)
+	first := int(d.number(dict["FirstChar"], 0))
+	for i, w := range d.array(dict["Widths"]) {
+		f.widths[uint32(first+i)] = d.number(w, 0)
+	}
+	if desc := d.dict(dict["FontDescriptor"]); desc != nil {
+		f.defaultWidth = d.number(desc["MissingWidth"], f.defaultWidth)
+	}
+	if dict["Subtype"] == pdfName("Type3") {
+		if m := d.array(dict["FontMatrix"]); len(m) > 0 {
+			f.widthScale = d.number(m[0], 0.001) * 1000
+		}
+	}
+	if strings.Contains(string(d.nameOf(dict["BaseFont"])), "Courier") {
+		f.defaultWidth = 600
+	}
+	return f
+}
+
+// nameOf 解析为名称
+func (d *pdfDocument) nameOf(obj interface{}) pdfName {
+	n, _ := d.resolve(obj).(pdfName)
+	return n
+}
+
+// loadCompositeEncoding 处理复合字体的 Encoding：预定义 CMap 按名称确定编码方式，嵌入的 CMap 读取其编码空间
+func (d *pdfDocument) loadCompositeEncoding(f *pdfFont, dict pdfDict) {
+	switch enc := d.resolve(dict["Encoding"]).(type) {
+	case pdfName:
+		name := string(enc)
+		switch {
+		case strings.HasPrefix(name, "Identity"):
+			f.codespace = []pdfCodeRange{{2, 0, 0xFFFF}}
+		case strings.Contains(name, "UCS2") || strings.Contains(name, "UTF16"):
+			f.unicode16 = true
+			f.codespace = []pdfCodeRange{{2, 0, 0xFFFF}}
+		default:
+			f.charset = predefinedCMapCharset(name)
+			f.codespace = []pdfCodeRange{{1, 0, 0x80}, {2, 0x8140, 0xFEFE}}
+			if strings.Contains(name, "RKSJ") {
+				// Shift-JIS 的半角片假名为单字节
+				f.codespace = []pdfCodeRange{{1, 0, 0x80}, {1, 0xA0, 0xDF}, {2, 0x8140, 0x9FFC}, {2, 0xE040, 0xFCFC}}
+			}
+		}
+	case *pdfStream:
+		if data, err := d.decodeStream(enc); err == nil {
+			if _, codespace := parseCMap(data); len(codespace) > 0 {
+				f.codespace = codespace
+			}
+		}
+	}
+	if len(f.codespace) == 0 {
+		f.codespace = []pdfCodeRange{{2, 0, 0xFFFF}}
+	}
+}
+
+// predefinedCMapCharset 预定义 CMap 名称对应的字符集
+func predefinedCMapCharset(name string) encoding.Encoding {
+	switch {
+	case strings.HasPrefix(name, "GB"):
+		return simplifiedchinese.GBK
+	case strings.HasPrefix(name, "B5") || strings.HasPrefix(name, "ETen") || strings.HasPrefix(name, "HKscs"):
+		return traditionalchinese.Big5
+	case strings.Contains(name, "RKSJ"):
+		return japanese.ShiftJIS
+	case strings.HasPrefix(name, "EUC"):
+		return japanese.EUCJP
+	case strings.HasPrefix(name, "KSC"):
+		return korean.EUCKR
+	}
+	return nil
+}
+
+// loadCIDWidths 读取 W 数组：c [w1 w2 ...] 或 cFirst cLast w
+func (d *pdfDocument) loadCIDWidths(f *pdfFont, w pdfArray) {
+	for i := 0; i < len(w); {
+		first, ok := d.resolve(w[i]).(float64)
+		if !ok || i+1 >= len(w) {
+			return
+		}
+		if list, ok := d.resolve(w[i+1]).(pdfArray); ok {
+			for j, width := range list {
+				f.widths[uint32(first)+uint32(j)] = d.number(width, f.defaultWidth)
+			}
+			i += 2
+			continue
+		}
+		if i+2 >= len(w) {
+			return
+		}
+		last := d.number(w[i+1], first)
+		width := d.number(w[i+2], f.defaultWidth)
+		for c := first; c <= last && c-first < 65536; c++ {
+			f.widths[uint32(c)] = width
+		}
+		i += 3
+	}
+}
+
+// simpleEncoding 简单字体的字符表：基础编码加上 Differences
+func (d *pdfDocument) simpleEncoding(dict pdfDict) [256]string {
+	table := winAnsiTable()
+	var differences pdfArray
+	switch enc := d.resolve(dict["Encoding"]).(type) {
+	case pdfName:
+		table = baseEncodingTable(string(enc))
+	case pdfDict:
+		if base, ok := d.resolve(enc["BaseEncoding"]).(pdfName); ok {
+			table = baseEncodingTable(string(base))
+		}
+		differences = d.array(enc["Differences"])
+	}
+
+	code := 0
+	for _, item := range differences {
+		switch v := d.resolve(item).(type) {
+		case float64:
+			code = int(v)
+		case pdfName:
+			if code >= 0 && code < 256 {
+				table[code] = glyphNameToUnicode(string(v))
+			}
+			code++
+		}
+	}
+	return table
+}
+
+// baseEncodingTable 预定义的单字节编码
+func baseEncodingTable(name string) [256]string {
+	if name == "MacRomanEncoding" {
+		return charmapTable(charmap.Macintosh)
+	}
+	table := winAnsiTable()
+	if name == "StandardEncoding" {
+		table['\''] = "’"
+		table['`'] = "‘"
+	}
+	return table
+}
+
+// winAnsiTable WinAnsiEncoding（Windows-1252）字符表，也用作未指定编码时的默认值
+func winAnsiTable() [256]string {
+	return charmapTable(charmap.Windows1252)
+}
+
+// charmapTable 将单字节字符集转换为字符表
+func charmapTable(cm *charmap.Charmap) [256]string {
+	var table [256]string
+	for i := 32; i < 256; i++ {
+		if r := cm.DecodeByte(byte(i)); r != utf8.RuneError {
+			table[i] = string(r)
+		}
+	}
+	table['\t'], table['\n'], table['\r'] = " ", " ", " "
+	return table
+}
+
+// glyphNames 常用字形名称到字符的映射，其余名称按 uniXXXX、uXXXX 和单个字符的规则解析
+var glyphNames = map[string]string{
+	"space": " ", "exclam": "!", "quotedbl": "\"", "numbersign": "#", "dollar": "$", "percent": "%",
+	"ampersand": "&", "quotesingle": "'", "quoteright": "’", "quoteleft": "‘", "parenleft": "(", "parenright": ")",
+	"asterisk": "*", "plus": "+", "comma": ",", "hyphen": "-", "minus": "−", "period": ".", "slash": "/",
+	"zero": "0", "one": "1", "two": "2", "three": "3", "four": "4", "five": "5", "six": "6", "seven": "7",
+	"eight": "8", "nine": "9", "colon": ":", "semicolon": ";", "less": "<", "equal": "=", "greater": ">",
+	"question": "?", "at": "@", "bracketleft": "[", "backslash": "\\", "bracketright": "]",
+	"asciicircum": "^", "underscore": "_", "grave": "`", "braceleft": "{", "bar": "|", "braceright": "}",
+	"asciitilde": "~", "bullet": "•", "endash": "–", "emdash": "—", "quotedblleft": "“", "quotedblright": "”",
+	"quotesinglbase": "‚", "quotedblbase": "„", "ellipsis": "…", "periodcentered": "·", "middot": "·",
+	"fi": "fi", "fl": "fl", "ff": "ff", "ffi": "ffi", "ffl": "ffl", "copyright": "©", "registered": "®",
+	"trademark": "™", "degree": "°", "section": "§", "paragraph": "¶", "dagger": "†", "daggerdbl": "‡",
+	"nbspace": " ", "nonbreakingspace": " ", "sfthyphen": "-", "softhyphen": "-", "multiply": "×", "divide": "÷",
+	"plusminus": "±", "Euro": "€", "sterling": "£", "yen": "¥", "cent": "¢", "arrowright": "→",
+}
+
+// glyphNameToUnicode 解析字形名称，无法识别时返回空字符串
+func glyphNameToUnicode(name string) string {
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		name = name[:i]
+	}
+	if s, ok := glyphNames[name]; ok {
+		return s
+	}
+	if len(name) == 1 {
+		return name
+	}
+	if strings.HasPrefix(name, "uni") && len(name) >= 7 {
+		var runes []rune
+		for i := 3; i+4 <= len(name); i += 4 {
+			v, err := strconv.ParseUint(name[i:i+4], 16, 16)
+			if err != nil {
+				return ""
+			}
+			runes = append(runes, rune(v))
+		}
+		return string(utf16.Decode(toUint16(runes)))
+	}
+	if strings.HasPrefix(name, "u") && len(name) >= 5 && len(name) <= 7 {
+		if v, err := strconv.ParseUint(name[1:], 16, 32); err == nil {
+			return string(rune(v))
+		}
+	}
+	return ""
+}
+
+func toUint16(runes []rune) []uint16 {
+	out := make([]uint16, len(runes))
+	for i, r := range runes {
+		out[i] = uint16(r)
+	}
+	return out
+}
+
+// decode 将字符串操作数拆分为字符并映射到 Unicode
+func (f *pdfFont) decode(s []byte) []pdfGlyph {
+	var glyphs []pdfGlyph
+	for i := 0; i < len(s); {
+		n := f.codeLength(s[i:])
+		var code uint32
+		for _, b := range s[i : i+n] {
+			code = code<<8 | uint32(b)
+		}
+
+		g := pdfGlyph{code: code, text: f.lookup(code, s[i:i+n]), space: n == 1 && code == 32}
+		w, ok := f.widths[code]
+		if !ok {
+			w = f.defaultWidth
+			if f.composite && n == 1 {
+				w = f.defaultWidth / 2
+			}
+		}
+		g.width = w * f.widthScale
+		glyphs = append(glyphs, g)
+		i += n
+	}
+	return glyphs
+}
+
+// codeLength 按编码空间确定下一个字符编码的字节数
+func (f *pdfFont) codeLength(s []byte) int {
+	if !f.composite || len(f.codespace) == 0 {
+		return 1
+	}
+	for n := 1; n <= 4 && n <= len(s); n++ {
+		var code uint32
+		for _, b := range s[:n] {
+			code = code<<8 | uint32(b)
+		}
+		for _, r := range f.codespace {
+			if r.bytes == n && code >= r.low && code <= r.high {
+				return n
+			}
+		}
+	}
+	// 不在编码空间中时按最短的编码长度跳过
+	shortest := 4
+	for _, r := range f.codespace {
+		shortest = min(shortest, r.bytes)
+	}
+	return min(shortest, len(s))
+}
+
+// lookup 将字符编码映射到 Unicode：优先使用 ToUnicode，其次使用预定义 CMap 或简单字体的编码
+func (f *pdfFont) lookup(code uint32, raw []byte) string {
+	if s, ok := f.toUnicode[code]; ok {
+		return s
+	}
+	switch {
+	case !f.composite:
+		return f.simple[code&0xFF]
+	case f.unicode16:
+		return string(utf16.Decode([]uint16{uint16(code)}))
+	case f.charset != nil:
+		if out, err := f.charset.NewDecoder().Bytes(raw); err == nil && utf8.Valid(out) && !bytes.ContainsRune(out, utf8.RuneError) {
+			return string(out)
+		}
+	}
+	return ""
+}
+
+// parseCMap 解析 CMap 中的 codespacerange、bfchar 和 bfrange
+func parseCMap(data []byte) (map[uint32]string, []pdfCodeRange) {
+	mapping := map[uint32]string{}
+	var codespace []pdfCodeRange
+	lex := &pdfLexer{data: data}
+	var operands []interface{}
+
+	for {
+		obj, err := lex.readObject()
+		if err != nil {
+			break
+		}
+		kw, ok := obj.(pdfKeyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+		switch kw {
+		case "endcodespacerange":
+			for i := 0; i+1 < len(operands); i += 2 {
+				low, _ := operands[i].(pdfString)
+				high, _ := operands[i+1].(pdfString)
+				if len(low) > 0 && len(low) == len(high) {
+					codespace = append(codespace, pdfCodeRange{len(low), bytesToCode(low), bytesToCode(high)})
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, _ := operands[i].(pdfString)
+				dst, _ := operands[i+1].(pdfString)
+				mapping[bytesToCode(src)] = utf16BE(dst)
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				low, _ := operands[i].(pdfString)
+				high, _ := operands[i+1].(pdfString)
+				lo, hi := bytesToCode(low), bytesToCode(high)
+				if hi < lo || hi-lo > 65535 {
+					continue
+				}
+				switch dst := operands[i+2].(type) {
+				case pdfString:
+					// 目标按最后一个字节递增
+					base := []byte(dst)
+					for c := lo; c <= hi; c++ {
+						target := append([]byte(nil), base...)
+						if len(target) > 0 {
+							target[len(target)-1] += byte(c - lo)
+						}
+						mapping[c] = utf16BE(target)
+					}
+				case pdfArray:
+					for j, item := range dst {
+						if s, ok := item.(pdfString); ok && lo+uint32(j) <= hi {
+							mapping[lo+uint32(j)] = utf16BE(s)
+						}
+					}
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+	return mapping, codespace
+}
+
+// bytesToCode 将大端字节序列转换为编码值
+func bytesToCode(b []byte) uint32 {
+	var code uint32
+	for _, c := range b {
+		code = code<<8 | uint32(c)
+	}
+	return code
+}
+
+// utf16BE 解码 UTF-16BE 字符串
+func utf16BE(b []byte) string {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+	if len(b)%2 == 1 {
+		units = append(units, uint16(b[len(b)-1]))
+	}
+	return string(utf16.Decode(units))
+}
@@ -0,0 +1,510 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// pdfTextStats 提取过程中的统计，用于判断是否有可用的文本层
+type pdfTextStats struct {
+	glyphs   int // 文本操作符中的字符数
+	unmapped int // 无法映射到 Unicode 的字符数
+	images   int // 图片数
+}
+
+// pdfSpan 页面上的一段文字，坐标为页面空间中的基线起点和终点
+type pdfSpan struct {
+	x, endX, y float64
+	size       float64
+	text       string
+}
+
+// pdfMatrix 仿射变换矩阵 [a b c d e f]
+type pdfMatrix [6]float64
+
+var identityMatrix = pdfMatrix{1, 0, 0, 1, 0, 0}
+
+// multiply 返回 m × n
+func (m pdfMatrix) multiply(n pdfMatrix) pdfMatrix {
+	return pdfMatrix{
+		m[0]*n[0] + m[1]*n[2], m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2], m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4], m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+// pdfGraphicsState 与文字位置相关的图形状态
+type pdfGraphicsState struct {
+	ctm      pdfMatrix
+	font     *pdfFont
+	fontSize float64
+	charSp   float64 // Tc
+	wordSp   float64 // Tw
+	scale    float64 // Tz/100
+	leading  float64 // TL
+	rise     float64 // Ts
+}
+
+// pdfContentRunner 执行内容流，收集文字片段
+type pdfContentRunner struct {
+	doc   *pdfDocument
+	stats *pdfTextStats
+	spans []pdfSpan
+	depth int
+}
+
+// pageText 提取一页的文字并按阅读顺序排列
+func (d *pdfDocument) pageText(page pdfPage, stats *pdfTextStats) string {
+	r := &pdfContentRunner{doc: d, stats: stats}
+	state := pdfGraphicsState{ctm: identityMatrix, scale: 1}
+	r.run(d.contents(page.dict), page.resources, state)
+	return layoutSpans(r.spans)
+}
+
+// run 解释内容流中与文字相关的操作符，表单 XObject 会递归执行
+func (r *pdfContentRunner) run(content []byte, resources pdfDict, gs pdfGraphicsState) {
+	d := r.doc
+	lex := &pdfLexer{data: content}
+	var stack []pdfGraphicsState
+	var operands []interface{}
+	tm, tlm := identityMatrix, identityMatrix
+
+	num := func(i int) float64 {
+		if i < len(operands) {
+			if v, ok := operands[i].(float64); ok {
+				return v
+			}
+		}
+		return 0
+	}
+	newLine := func(tx, ty float64) {
+		tlm = pdfMatrix{1, 0, 0, 1, tx, ty}.multiply(tlm)
+		tm = tlm
+	}
+
+	for {
+		obj, err := lex.readObject()
+		if err != nil {
+			return
+		}
+		op, ok := obj.(pdfKeyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+
+		switch op {
+		case "q":
+			stack = append(stack, gs)
+		case "Q":
+			if n := len(stack); n > 0 {
+				gs, stack = stack[n-1], stack[:n-1]
+			}
+		case "cm":
+			gs.ctm = pdfMatrix{num(0), num(1), num(2), num(3), num(4), num(5)}.multiply(gs.ctm)
+		case "BT":
+			tm, tlm = identityMatrix, identityMatrix
+		case "Tf":
+			if len(operands) < 2 {
+				break
+			}
+			if name, ok := operands[0].(pdfName); ok {
+				fonts := d.dict(resources["Font"])
+				gs.font = d.font(fonts[string(name)])
+				gs.fontSize = num(1)
+			}
+		case "Tc":
+			gs.charSp = num(0)
+		case "Tw":
+			gs.wordSp = num(0)
+		case "Tz":
+			gs.scale = num(0) / 100
+		case "TL":
+			gs.leading = num(0)
+		case "Ts":
+			gs.rise = num(0)
+		case "Td":
+			newLine(num(0), num(1))
+		case "TD":
+			gs.leading = -num(1)
+			newLine(num(0), num(1))
+		case "Tm":
+			tlm = pdfMatrix{num(0), num(1), num(2), num(3), num(4), num(5)}
+			tm = tlm
+		case "T*":
+			newLine(0, -gs.leading)
+		case "Tj":
+			if len(operands) > 0 {
+				r.show(operands[0], &gs, &tm)
+			}
+		case "'":
+			newLine(0, -gs.leading)
+			if len(operands) > 0 {
+				r.show(operands[0], &gs, &tm)
+			}
+		case "\"":
+			if len(operands) >= 3 {
+				gs.wordSp, gs.charSp = num(0), num(1)
+				newLine(0, -gs.leading)
+				r.show(operands[2], &gs, &tm)
+			}
+		case "TJ":
+			if len(operands) > 0 {
+				if arr, ok := operands[0].(pdfArray); ok {
+					for _, item := range arr {
+						if adjust, ok := item.(float64); ok {
+							tm = pdfMatrix{1, 0, 0, 1, -adjust / 1000 * gs.fontSize * gs.scale, 0}.multiply(tm)
+							continue
+						}
+						r.show(item, &gs, &tm)
+					}
+				}
+			}
+		case "Do":
+			if len(operands) > 0 {
+				if name, ok := operands[0].(pdfName); ok {
+					r.doXObject(d.dict(resources["XObject"])[string(name)], resources, gs)
+				}
+			}
+		case "BI":
+			// 跳过内联图片的数据
+			r.stats.images++
+			if end := strings.Index(string(content[lex.pos:]), "EI"); end >= 0 {
+				lex.pos += end + 2
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+// doXObject 执行表单 XObject，图片只计数
+func (r *pdfContentRunner) doXObject(obj interface{}, resources pdfDict, gs pdfGraphicsState) {
+	d := r.doc
+	s, ok := d.resolve(obj).(*pdfStream)
+	if !ok {
+		return
+	}
+	switch s.dict["Subtype"] {
+	case pdfName("Image"):
+		r.stats.images++
+	case pdfName("Form"):
+		if r.depth >= 10 {
+			return
+		}
+		data, err := d.decodeStream(s)
+		if err != nil {
+			return
+		}
+		if m := d.array(s.dict["Matrix"]); len(m) == 6 {
+			var matrix pdfMatrix
+			for i := range matrix {
+				matrix[i] = d.number(m[i], 0)
+			}
+			gs.ctm = matrix.multiply(gs.ctm)
+		}
+		if res := d.dict(s.dict["Resources"]); res != nil {
+			resources = res
+		}
+		r.depth++
+		r.run(data, resources, gs)
+		r.depth--
+	}
+}
+
+// show 输出一个字符串操作数，记录起止位置并推进文本矩阵
+func (r *pdfContentRunner) show(obj interface{}, gs *pdfGraphicsState, tm *pdfMatrix) {
+	s, ok := obj.(pdfString)
+	if !ok || gs.font == nil {
+		return
+	}
+
+	var text strings.Builder
+	start := pdfMatrix{gs.fontSize * gs.scale, 0, 0, gs.fontSize, 0, gs.rise}.multiply(*tm).multiply(gs.ctm)
+	for _, g := range gs.font.decode(s) {
+		r.stats.glyphs++
+		if g.text == "" {
+			r.stats.unmapped++
+		} else {
+			text.WriteString(g.text)
+		}
+		advance := g.width/1000*gs.fontSize + gs.charSp
+		if g.space {
+			advance += gs.wordSp
+		}
+		*tm = pdfMatrix{1, 0, 0, 1, advance * gs.scale, 0}.multiply(*tm)
+	}
+	end := pdfMatrix{gs.fontSize * gs.scale, 0, 0, gs.fontSize, 0, gs.rise}.multiply(*tm).multiply(gs.ctm)
+
+	if text.Len() == 0 {
+		return
+	}
+	size := math.Hypot(start[2], start[3])
+	if size <= 0 {
+		size = 1
+	}
+	r.spans = append(r.spans, pdfSpan{x: start[4], endX: end[4], y: start[5], size: size, text: text.String()})
+}
+
+// pdfLine 基线相近的一组文字片段
+type pdfLine struct {
+	spans      []pdfSpan
+	y          float64
+	size       float64
+	minX, maxX float64
+}
+
+// minColumnSideLines 分栏两侧各自至少需要的行数
+const minColumnSideLines = 3
+
+// minColumnWidthRatio 分栏每一侧的宽度至少占文字区域宽度的比例，避免把右对齐的日期当作一栏
+const minColumnWidthRatio = 0.1
+
+// layoutSpans 将文字片段组织为按阅读顺序排列的文本
+// 先按基线合并为行；连续多行在同一位置有足够宽的空白时视为分栏，先输出左栏再输出右栏
+func layoutSpans(spans []pdfSpan) string {
+	lines := groupLines(spans)
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(orderLines(lines), "\n")
+}
+
+// groupLines 按基线从上到下合并为行
+func groupLines(spans []pdfSpan) []*pdfLine {
+	sort.SliceStable(spans, func(i, j int) bool {
+		if math.Abs(spans[i].y-spans[j].y) > 0.01 {
+			return spans[i].y > spans[j].y
+		}
+		return spans[i].x < spans[j].x
+	})
+
+	var lines []*pdfLine
+	for _, s := range spans {
+		if s.endX < s.x {
+			s.x, s.endX = s.endX, s.x
+		}
+		var line *pdfLine
+		if n := len(lines); n > 0 && math.Abs(lines[n-1].y-s.y) < 0.5*math.Min(lines[n-1].size, s.size) {
+			line = lines[n-1]
+		} else {
+			line = &pdfLine{y: s.y, size: s.size, minX: s.x, maxX: s.endX}
+			lines = append(lines, line)
+		}
+		line.spans = append(line.spans, s)
+		line.size = math.Max(line.size, s.size)
+		line.minX = math.Min(line.minX, s.x)
+		line.maxX = math.Max(line.maxX, s.endX)
+	}
+	for _, line := range lines {
+		sort.SliceStable(line.spans, func(i, j int) bool { return line.spans[i].x < line.spans[j].x })
+		line.spans = dropOverprinted(line.spans)
+	}
+	return lines
+}
+
+// dropOverprinted 去掉为模拟粗体而错位重复绘制的文字
+func dropOverprinted(spans []pdfSpan) []pdfSpan {
+	out := spans[:0]
+	for _, s := range spans {
+		if n := len(out); n > 0 {
+			prev := out[n-1]
+			if prev.text == s.text && math.Abs(prev.x-s.x) < 0.3*s.size && math.Abs(prev.y-s.y) < 0.3*s.size {
+				continue
+			}
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// pdfInterval 水平方向的区间
+type pdfInterval struct{ lo, hi float64 }
+
+// orderLines 识别分栏并返回按阅读顺序排列的行文本
+func orderLines(lines []*pdfLine) []string {
+	left, right := lines[0].minX, lines[0].maxX
+	sizes := make([]float64, 0, len(lines))
+	for _, line := range lines {
+		left, right = math.Min(left, line.minX), math.Max(right, line.maxX)
+		sizes = append(sizes, line.size)
+	}
+	sort.Float64s(sizes)
+	minGutter := 2 * sizes[len(sizes)/2]
+	width := right - left
+
+	var out []string
+	var group []*pdfLine
+	var gutters []pdfInterval
+	flush := func() {
+		out = append(out, orderGroup(group, gutters, width)...)
+		group, gutters = nil, nil
+	}
+
+	for _, line := range lines {
+		free := freeIntervals(line, left, right, minGutter)
+		if len(group) == 0 {
+			group, gutters = []*pdfLine{line}, free
+			continue
+		}
+		common := intersectIntervals(gutters, free, minGutter)
+		// 加入该行会使已成立的分栏失效时（如横跨两栏的标题），在此处结束当前分组
+		if split, ok := columnSplit(group, gutters, width); ok && !coversPoint(common, split) {
+			common = nil
+		}
+		if len(common) > 0 {
+			group, gutters = append(group, line), common
+			continue
+		}
+		flush()
+		group, gutters = []*pdfLine{line}, free
+	}
+	flush()
+	return out
+}
+
+// orderGroup 两侧都有足够的内容时按栏输出，否则逐行输出
+func orderGroup(group []*pdfLine, gutters []pdfInterval, width float64) []string {
+	if split, ok := columnSplit(group, gutters, width); ok {
+		leftLines, rightLines := splitLines(group, split)
+		// 每一栏内部可能还有分栏
+		return append(orderLines(leftLines), orderLines(rightLines)...)
+	}
+
+	out := make([]string, 0, len(group))
+	for _, line := range group {
+		out = append(out, joinSpans(line.spans))
+	}
+	return out
+}
+
+// columnSplit 在共同的空白中找到两侧都有足够行数和宽度的分栏位置
+func columnSplit(group []*pdfLine, gutters []pdfInterval, width float64) (float64, bool) {
+	for _, g := range gutters {
+		split := (g.lo + g.hi) / 2
+		leftLines, rightLines := splitLines(group, split)
+		if len(leftLines) < minColumnSideLines || len(rightLines) < minColumnSideLines {
+			continue
+		}
+		if extent(leftLines) < minColumnWidthRatio*width || extent(rightLines) < minColumnWidthRatio*width {
+			continue
+		}
+		return split, true
+	}
+	return 0, false
+}
+
+// splitLines 按分隔位置将各行拆为左右两栏
+func splitLines(group []*pdfLine, split float64) (leftLines, rightLines []*pdfLine) {
+	for _, line := range group {
+		l, r := splitLine(line, split)
+		if l != nil {
+			leftLines = append(leftLines, l)
+		}
+		if r != nil {
+			rightLines = append(rightLines, r)
+		}
+	}
+	return leftLines, rightLines
+}
+
+// extent 一组行在水平方向上占据的宽度
+func extent(lines []*pdfLine) float64 {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, line := range lines {
+		lo, hi = math.Min(lo, line.minX), math.Max(hi, line.maxX)
+	}
+	return hi - lo
+}
+
+// coversPoint 区间中是否有包含 x 的
+func coversPoint(intervals []pdfInterval, x float64) bool {
+	for _, iv := range intervals {
+		if iv.lo <= x && x <= iv.hi {
+			return true
+		}
+	}
+	return false
+}
+
+// splitLine 按分隔位置将一行拆为左右两部分，没有内容的一侧为 nil
+func splitLine(line *pdfLine, split float64) (*pdfLine, *pdfLine) {
+	var left, right *pdfLine
+	for _, s := range line.spans {
+		side := &left
+		if s.x >= split {
+			side = &right
+		}
+		if *side == nil {
+			*side = &pdfLine{y: line.y, size: line.size, minX: s.x, maxX: s.endX}
+		}
+		(*side).spans = append((*side).spans, s)
+		(*side).minX = math.Min((*side).minX, s.x)
+		(*side).maxX = math.Max((*side).maxX, s.endX)
+	}
+	return left, right
+}
+
+// freeIntervals 一行在文字区域内不小于 minWidth 的空白区间
+func freeIntervals(line *pdfLine, left, right, minWidth float64) []pdfInterval {
+	var free []pdfInterval
+	cursor := left
+	for _, s := range line.spans {
+		if s.x-cursor >= minWidth {
+			free = append(free, pdfInterval{cursor, s.x})
+		}
+		cursor = math.Max(cursor, s.endX)
+	}
+	if right-cursor >= minWidth {
+		free = append(free, pdfInterval{cursor, right})
+	}
+	return free
+}
+
+// intersectIntervals 两组空白区间的交集中不小于 minWidth 的部分
+func intersectIntervals(a, b []pdfInterval, minWidth float64) []pdfInterval {
+	var out []pdfInterval
+	for _, x := range a {
+		for _, y := range b {
+			lo, hi := math.Max(x.lo, y.lo), math.Min(x.hi, y.hi)
+			if hi-lo >= minWidth {
+				out = append(out, pdfInterval{lo, hi})
+			}
+		}
+	}
+	return out
+}
+
+// joinSpans 拼接同一行的片段，间距较大时插入空格，很大时插入制表符
+func joinSpans(spans []pdfSpan) string {
+	var b strings.Builder
+	for i, s := range spans {
+		if i > 0 {
+			prev := spans[i-1]
+			gap := s.x - prev.endX
+			size := math.Max(prev.size, s.size)
+			prevRune, _ := utf8.DecodeLastRuneInString(b.String())
+			nextRune, _ := utf8.DecodeRuneInString(s.text)
+			threshold := 0.2 * size
+			if isCJK(prevRune) || isCJK(nextRune) {
+				threshold = 0.6 * size
+			}
+			switch {
+			case gap >= 2*size:
+				b.WriteString("\t")
+			case gap >= threshold && !unicode.IsSpace(prevRune) && !unicode.IsSpace(nextRune):
+				b.WriteString(" ")
+			}
+		}
+		b.WriteString(s.text)
+	}
+	return strings.TrimRightFunc(b.String(), unicode.IsSpace)
+}
+
+// isCJK 是否为中日韩文字或全角标点
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		(r >= 0x3000 && r <= 0x303F) || (r >= 0xFF00 && r <= 0xFFEF)
+}
@@ -0,0 +1,261 @@
+package services
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// testPDF 按顺序构造 PDF 的间接对象，1 号对象为目录，2 号对象为页面树
+type testPDF struct {
+	objects []string
+	pages   []int
+}
+
+func newTestPDF() *testPDF {
+	return &testPDF{objects: []string{"", ""}}
+}
+
+// add 加入一个对象，返回对象号
+func (p *testPDF) add(obj string) int {
+	p.objects = append(p.objects, obj)
+	return len(p.objects)
+}
+
+// stream 加入一个流对象，dict 为字典中除 Length 以外的内容
+func (p *testPDF) stream(dict string, data []byte) int {
+	return p.add(fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data))
+}
+
+// page 加入一页，resources 为资源字典，content 为内容流
+func (p *testPDF) page(resources, content string) {
+	contents := p.stream("", []byte(content))
+	p.pages = append(p.pages, p.add(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources %s /Contents %d 0 R >>", resources, contents)))
+}
+
+// bytes 输出完整的 PDF 文件
+func (p *testPDF) bytes() []byte {
+	kids := make([]string, len(p.pages))
+	for i, n := range p.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", n)
+	}
+	p.objects[0] = "<< /Type /Catalog /Pages 2 0 R >>"
+	p.objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(p.pages))
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.7\n")
+	for i, obj := range p.objects {
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	b.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return b.Bytes()
+}
+
+// helvetica 加入一个标准字体，返回包含该字体的资源字典
+func (p *testPDF) helvetica() string {
+	return fmt.Sprintf("<< /Font << /F1 %d 0 R >> >>", p.add("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>"))
+}
+
+// type0Font 加入一个复合字体，toUnicode 不为空时附带 ToUnicode CMap，返回包含该字体的资源字典
+func (p *testPDF) type0Font(encoding, toUnicode string) string {
+	dict := fmt.Sprintf("/Type /Font /Subtype /Type0 /BaseFont /SimSun /Encoding /%s /DescendantFonts [<< /Type /Font /Subtype /CIDFontType2 /DW 1000 >>]", encoding)
+	if toUnicode != "" {
+		dict += fmt.Sprintf(" /ToUnicode %d 0 R", p.stream("", []byte(toUnicode)))
+	}
+	return fmt.Sprintf("<< /Font << /F1 %d 0 R >> >>", p.add("<< "+dict+" >>"))
+}
+
+// testToUnicode 将 <0001><0002> 映射为“张三”，<0010>-<0012> 映射为 ABC
+const testToUnicode = `/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+1 begincodespacerange
+<0000> <FFFF>
+endcodespacerange
+2 beginbfchar
+<0001> <5F20>
+<0002> <4E09>
+endbfchar
+1 beginbfrange
+<0010> <0012> <0041>
+endbfrange
+endcmap
+end end`
+
+// pdfHex 将字节转为 PDF 十六进制字符串
+func pdfHex(b []byte) string {
+	return fmt.Sprintf("<%X>", b)
+}
+
+// testPDFs 构造用于表驱动测试和模糊测试种子的 PDF
+func testPDFs(t testing.TB) map[string][]byte {
+	docs := map[string][]byte{}
+
+	p := newTestPDF()
+	p.page(p.helvetica(), "BT /F1 12 Tf 72 720 Td (Zhang San) Tj 0 -20 Td (Senior Go Engineer) Tj ET")
+	docs["simple"] = p.bytes()
+
+	p = newTestPDF()
+	res := p.helvetica()
+	p.page(res, "BT /F1 12 Tf 72 720 Td [(Skills:) -3000 (Go, Kubernetes)] TJ ET")
+	p.page(res, "BT /F1 12 Tf 14 TL 72 720 Td (Page two) Tj T* (Second line) Tj ET")
+	docs["two pages"] = p.bytes()
+
+	// 左右两栏交错绘制，阅读顺序应为先左栏后右栏
+	p = newTestPDF()
+	var content strings.Builder
+	content.WriteString("BT /F1 10 Tf ")
+	for i := 1; i <= 4; i++ {
+		y := 720 - 20*i
+		fmt.Fprintf(&content, "1 0 0 1 50 %d Tm (Left line %d) Tj 1 0 0 1 320 %d Tm (Right line %d) Tj ", y, i, y, i)
+	}
+	content.WriteString("ET")
+	p.page(p.helvetica(), content.String())
+	docs["two columns"] = p.bytes()
+
+	p = newTestPDF()
+	p.page(p.type0Font("Identity-H", testToUnicode), "BT /F1 12 Tf 72 720 Td <00010002> Tj 0 -20 Td <001000110012> Tj ET")
+	docs["tounicode"] = p.bytes()
+
+	p = newTestPDF()
+	p.page(p.type0Font("UniGB-UCS2-H", ""), "BT /F1 12 Tf 72 720 Td <5F204E09> Tj ET")
+	docs["ucs2 cmap"] = p.bytes()
+
+	gbk, err := simplifiedchinese.GBK.NewEncoder().Bytes([]byte("张三"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p = newTestPDF()
+	p.page(p.type0Font("GBK-EUC-H", ""), "BT /F1 12 Tf 72 720 Td "+pdfHex(gbk)+" Tj ET")
+	docs["gbk cmap"] = p.bytes()
+
+	// 文字位于表单 XObject 中，表单带有自己的字体资源和变换矩阵
+	p = newTestPDF()
+	formRes := p.helvetica()
+	form := p.stream("/Type /XObject /Subtype /Form /BBox [0 0 612 792] /Matrix [1 0 0 1 0 -100] /Resources "+formRes,
+		[]byte("BT /F1 12 Tf 72 720 Td (Inside form) Tj ET"))
+	p.page(fmt.Sprintf("<< /XObject << /X1 %d 0 R >> >>", form), "q /X1 Do Q")
+	docs["form xobject"] = p.bytes()
+
+	var deflated bytes.Buffer
+	w := zlib.NewWriter(&deflated)
+	w.Write([]byte("BT /F1 12 Tf 72 720 Td (Compressed text) Tj ET"))
+	w.Close()
+	p = newTestPDF()
+	res = p.helvetica()
+	contents := p.stream("/Filter /FlateDecode", deflated.Bytes())
+	p.pages = append(p.pages, p.add(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /Resources %s /Contents %d 0 R >>", res, contents)))
+	docs["flate"] = p.bytes()
+
+	p = newTestPDF()
+	p.page(p.type0Font("Identity-H", ""), "BT /F1 12 Tf 72 720 Td <00010002000300040005> Tj ET")
+	docs["unmapped"] = p.bytes()
+
+	p = newTestPDF()
+	p.page("<< >>", "q 100 0 0 100 0 0 cm BI /W 1 /H 1 /CS /G /BPC 8 ID \x00 EI Q")
+	docs["image only"] = p.bytes()
+
+	return docs
+}
+
+func TestExtractPDFText(t *testing.T) {
+	docs := testPDFs(t)
+	tests := []struct {
+		name    string
+		want    string
+		wantErr error
+	}{
+		{name: "simple", want: "Zhang San\nSenior Go Engineer"},
+		{name: "two pages", want: "Skills:\tGo, Kubernetes\n\nPage two\nSecond line"},
+		{name: "two columns", want: "Left line 1\nLeft line 2\nLeft line 3\nLeft line 4\nRight line 1\nRight line 2\nRight line 3\nRight line 4"},
+		{name: "tounicode", want: "张三\nABC"},
+		{name: "ucs2 cmap", want: "张三"},
+		{name: "gbk cmap", want: "张三"},
+		{name: "form xobject", want: "Inside form"},
+		{name: "flate", want: "Compressed text"},
+		{name: "unmapped", wantErr: ErrNoTextLayer},
+		{name: "image only", wantErr: ErrNoTextLayer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExtractPDFText(docs[tt.name])
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractPDFTextInvalid(t *testing.T) {
+	encrypted := newTestPDF()
+	encrypted.page(encrypted.helvetica(), "BT /F1 12 Tf (secret) Tj ET")
+	data := bytes.Replace(encrypted.bytes(), []byte("<< /Root 1 0 R >>"), []byte("<< /Root 1 0 R /Encrypt << /Filter /Standard >> >>"), 1)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{"not a pdf", []byte("hello"), ErrInvalidPDF},
+		{"no catalog", []byte("%PDF-1.4\n1 0 obj << /Type /Page >> endobj\n"), ErrInvalidPDF},
+		{"encrypted", data, ErrPDFEncrypted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ExtractPDFText(tt.data); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// pdfWithStreamLength 内容流的 /Length 为 length 的 PDF，内容流显示“Bad length”
+func pdfWithStreamLength(length string) []byte {
+	p := newTestPDF()
+	res := p.helvetica()
+	contents := p.add("<< /Length " + length + " >>\nstream\nBT /F1 12 Tf 72 720 Td (Bad length) Tj ET\nendstream")
+	p.pages = append(p.pages, p.add(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /Resources %s /Contents %d 0 R >>", res, contents)))
+	return p.bytes()
+}
+
+// 负数、超出文件末尾和超出整数范围的流长度
+var badStreamLengths = []string{"-100000", "1e300", "-1e300", "99999999999999999999", "7"}
+
+func TestExtractPDFTextBadStreamLength(t *testing.T) {
+	for _, length := range badStreamLengths {
+		t.Run(length, func(t *testing.T) {
+			got, err := ExtractPDFText(pdfWithStreamLength(length))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != "Bad length" {
+				t.Errorf("got %q, want %q", got, "Bad length")
+			}
+		})
+	}
+}
+
+func FuzzExtractPDFText(f *testing.F) {
+	var seeds [][]byte
+	for _, doc := range testPDFs(f) {
+		seeds = append(seeds, doc)
+	}
+	for _, length := range badStreamLengths {
+		seeds = append(seeds, pdfWithStreamLength(length))
+	}
+	fuzzExtract(f, ExtractPDFText, seeds...)
+}
@@ -0,0 +1,366 @@
+package services
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// 内置的提示模板，文件名格式为 <名称>.v<版本>.tmpl，每个文件需定义 system 和 prompt 两个模板
+// 其他输出语言的模板命名为 <名称>.<语言>.v<版本>.tmpl，未带语言的模板为中文
+//
+//go:embed prompts/*.tmpl
+var embeddedPrompts embed.FS
+
+// PromptRef 提示模板的名称、版本和输出语言，随每次AI结果一起记录
+type PromptRef struct {
+	Name     string `json:"promptName"`
+	Version  int    `json:"promptVersion"`
+	Language string `json:"language,omitempty"`
+}
+
+// String 返回 名称@v版本 形式的描述，非中文模板附加语言
+func (r PromptRef) String() string {
+	if r.Language != "" && r.Language != DefaultLanguage {
+		return fmt.Sprintf("%s.%s@v%d", r.Name, r.Language, r.Version)
+	}
+	return fmt.Sprintf("%s@v%d", r.Name, r.Version)
+}
+
+// RenderedPrompt 渲染后的系统指令和用户提示
+type RenderedPrompt struct {
+	PromptRef
+	System string
+	Prompt string
+}
+
+// ScreeningPromptInput 简历筛选模板的输入
+type ScreeningPromptInput struct {
+	Industry        string
+	JobRequirements string
+	FileName        string // 放入系统指令，必须使用 PromptFileName 的结果，不能直接使用上传的文件名
+}
+
+// PromptFileName 返回放入系统指令的简历名称
+// 上传的文件名由候选人控制，可能夹带指令，这里只保留受支持的扩展名，名称统一为 resume；结果中的名称由调用方替换回原文件名
+func PromptFileName(filename string) string {
+	if format := ResumeFormatForFile(filename); format != FormatUnknown {
+		return "resume" + strings.ToLower(filepath.Ext(filename))
+	}
+	return "resume"
+}
+
+// QuestionsPromptInput 面试题生成模板的输入
+type QuestionsPromptInput struct {
+	Industry         string
+	IndustryKeywords string
+	JobRequirements  string
+	ResumeContent    string
+}
+
+// SummaryPromptInput 面试总结模板的输入
+type SummaryPromptInput struct {
+	Industry         string
+	IndustryKeywords string
+	JobRequirements  string
+	InterviewNotes   string
+}
+
+// ChatPromptInput 候选人多轮问答模板的输入
+type ChatPromptInput struct {
+	Industry        string
+	JobRequirements string
+	ResumeContent   string
+	PriorOutputs    string
+	History         []ChatTurn
+	Message         string
+}
+
+// ChatTurn 对话历史中的一条消息，Role 为 user（面试官）或 assistant（AI）
+type ChatTurn struct {
+	Role    string
+	Content string
+}
+
+// NotesPromptInput 长文本分块要点提取模板的输入
+type NotesPromptInput struct {
+	Kind            string
+	JobRequirements string
+	Part            int
+	Total           int
+	Content         string
+}
+
+// CompliancePromptInput 生成内容合规检查模板的输入
+type CompliancePromptInput struct {
+	Items []ComplianceItem
+}
+
+// ComplianceItem 待检查的条目，Index 从 1 开始
+type ComplianceItem struct {
+	Index int
+	Text  string
+}
+
+// PromptTemplate 输入类型确定的提示模板
+type PromptTemplate[T any] struct {
+	name string
+}
+
+// NewPromptTemplate 按名称引用注册表中的模板
+func NewPromptTemplate[T any](name string) PromptTemplate[T] {
+	return PromptTemplate[T]{name: name}
+}
+
+// Name 返回模板名称
+func (t PromptTemplate[T]) Name() string {
+	return t.name
+}
+
+// Render 使用当前生效的版本渲染中文模板
+func (t PromptTemplate[T]) Render(input T) (*RenderedPrompt, error) {
+	return prompts.render(t.name, DefaultLanguage, input)
+}
+
+// RenderIn 渲染指定输出语言的模板，该语言没有模板时使用中文模板
+func (t PromptTemplate[T]) RenderIn(lang string, input T) (*RenderedPrompt, error) {
+	return prompts.render(t.name, lang, input)
+}
+
+// 业务使用的提示模板
+var (
+	ScreeningPrompt  = NewPromptTemplate[ScreeningPromptInput]("resume_screening")
+	QuestionsPrompt  = NewPromptTemplate[QuestionsPromptInput]("interview_questions")
+	SummaryPrompt    = NewPromptTemplate[SummaryPromptInput]("interview_summary")
+	ChatPrompt       = NewPromptTemplate[ChatPromptInput]("candidate_chat")
+	NotesPrompt      = NewPromptTemplate[NotesPromptInput]("chunk_notes")
+	CompliancePrompt = NewPromptTemplate[CompliancePromptInput]("compliance_check")
+)
+
+// promptFileRe 匹配模板文件名
+var promptFileRe = regexp.MustCompile(`^([a-z0-9_]+)(?:\.([a-z]{2}))?\.v(\d+)\.tmpl$`)
+
+// promptFuncs 模板中可用的函数
+var promptFuncs = template.FuncMap{
+	// default 值为空时使用默认值: {{default "无" .Field}}
+	"default": func(def, value string) string {
+		if strings.TrimSpace(value) == "" {
+			return def
+		}
+		return value
+	},
+	// untrusted 将候选人提供的内容包裹在不可信内容块中: {{untrusted "候选人简历" .ResumeContent}}
+	"untrusted": UntrustedBlock,
+	// untrustedInstruction 要求模型忽略不可信内容块中的指令的说明，可指定语言: {{untrustedInstruction "en"}}
+	"untrustedInstruction": func(lang ...string) string {
+		if len(lang) == 0 {
+			return UntrustedInstruction
+		}
+		return UntrustedInstructionIn(lang[0])
+	},
+}
+
+// promptRegistry 按名称、语言和版本管理的提示模板，键为 名称 或 名称.语言
+type promptRegistry struct {
+	mu          sync.RWMutex
+	loaded      bool
+	templates   map[string]map[int]*template.Template
+	fingerprint string
+}
+
+var prompts = &promptRegistry{}
+
+// InitPrompts 加载内置模板和 PROMPT_TEMPLATES_DIR 中的模板，启动时调用以尽早发现模板错误
+func InitPrompts() error {
+	return prompts.load()
+}
+
+// load 加载全部模板，目录中的模板会覆盖同名同版本的内置模板
+func (r *promptRegistry) load() error {
+	templates := map[string]map[int]*template.Template{}
+	if err := loadPromptFS(embeddedPrompts, "prompts", templates); err != nil {
+		return err
+	}
+
+	fingerprint := ""
+	if dir := os.Getenv("PROMPT_TEMPLATES_DIR"); dir != "" {
+		if err := loadPromptFS(os.DirFS(dir), ".", templates); err != nil {
+			return err
+		}
+		fingerprint = promptDirFingerprint(dir)
+	}
+
+	r.mu.Lock()
+	r.templates = templates
+	r.fingerprint = fingerprint
+	r.loaded = true
+	r.mu.Unlock()
+
+	for key, versions := range templates {
+		name, _, _ := strings.Cut(key, ".")
+		log.Printf("[DEBUG] 已加载提示模板 %s，版本数: %d，当前使用 v%d", key, len(versions), selectPromptVersion(name, versions))
+	}
+	return nil
+}
+
+// loadPromptFS 解析目录下的模板文件
+func loadPromptFS(fsys fs.FS, dir string, templates map[string]map[int]*template.Template) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return fmt.Errorf("读取提示模板目录失败: %w", err)
+	}
+
+	for _, entry := range entries {
+		match := promptFileRe.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		key := promptKey(match[1], match[2])
+		version, _ := strconv.Atoi(match[3])
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("读取提示模板 %s 失败: %w", entry.Name(), err)
+		}
+		tmpl, err := template.New(entry.Name()).Funcs(promptFuncs).Option("missingkey=error").Parse(string(data))
+		if err != nil {
+			return fmt.Errorf("解析提示模板 %s 失败: %w", entry.Name(), err)
+		}
+		for _, block := range []string{"system", "prompt"} {
+			if tmpl.Lookup(block) == nil {
+				return fmt.Errorf("提示模板 %s 缺少 %s 定义", entry.Name(), block)
+			}
+		}
+
+		if templates[key] == nil {
+			templates[key] = map[int]*template.Template{}
+		}
+		templates[key][version] = tmpl
+	}
+	return nil
+}
+
+// promptKey 返回模板在注册表中的键，中文模板不带语言后缀
+func promptKey(name, lang string) string {
+	if lang == "" || lang == DefaultLanguage {
+		return name
+	}
+	return name + "." + lang
+}
+
+// render 渲染指定名称和输出语言的模板，开发模式下目录中的模板变化后自动重新加载
+func (r *promptRegistry) render(name, lang string, input interface{}) (*RenderedPrompt, error) {
+	if err := r.reloadIfNeeded(); err != nil {
+		return nil, err
+	}
+	if lang == "" {
+		lang = DefaultLanguage
+	}
+
+	r.mu.RLock()
+	versions := r.templates[promptKey(name, lang)]
+	if len(versions) == 0 && lang != DefaultLanguage {
+		log.Printf("[WARN] 提示模板 %s 没有 %s 版本，使用中文模板", name, lang)
+		lang = DefaultLanguage
+		versions = r.templates[name]
+	}
+	r.mu.RUnlock()
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("未找到提示模板: %s", name)
+	}
+
+	version := selectPromptVersion(name, versions)
+	tmpl, ok := versions[version]
+	if !ok {
+		return nil, fmt.Errorf("未找到提示模板: %s@v%d", name, version)
+	}
+
+	rendered := &RenderedPrompt{PromptRef: PromptRef{Name: name, Version: version, Language: lang}}
+	for block, out := range map[string]*string{"system": &rendered.System, "prompt": &rendered.Prompt} {
+		var buf bytes.Buffer
+		if err := tmpl.ExecuteTemplate(&buf, block, input); err != nil {
+			return nil, fmt.Errorf("渲染提示模板 %s 失败: %w", rendered.PromptRef, err)
+		}
+		*out = strings.TrimSpace(buf.String())
+	}
+	return rendered, nil
+}
+
+// reloadIfNeeded 首次使用时加载模板；PROMPT_TEMPLATES_RELOAD=true 时检查目录变化并重新加载
+func (r *promptRegistry) reloadIfNeeded() error {
+	r.mu.RLock()
+	loaded, fingerprint := r.loaded, r.fingerprint
+	r.mu.RUnlock()
+
+	if !loaded {
+		return r.load()
+	}
+
+	dir := os.Getenv("PROMPT_TEMPLATES_DIR")
+	if dir == "" || os.Getenv("PROMPT_TEMPLATES_RELOAD") != "true" {
+		return nil
+	}
+	if promptDirFingerprint(dir) == fingerprint {
+		return nil
+	}
+
+	log.Printf("[INFO] 检测到提示模板目录 %s 有变化，重新加载", dir)
+	if err := r.load(); err != nil {
+		// 保留之前加载的模板，避免编辑过程中的错误导致服务不可用
+		log.Printf("[ERROR] 重新加载提示模板失败，继续使用之前的版本: %v", err)
+	}
+	return nil
+}
+
+// promptDirFingerprint 根据模板文件的名称、大小和修改时间计算目录指纹
+func promptDirFingerprint(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+
+	var parts []string
+	for _, entry := range entries {
+		if !promptFileRe.MatchString(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s:%d:%d", entry.Name(), info.Size(), info.ModTime().UnixNano()))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "|")
+}
+
+// selectPromptVersion 返回 PROMPT_VERSION_<NAME> 指定的版本，未指定时使用最新版本
+func selectPromptVersion(name string, versions map[int]*template.Template) int {
+	if value := os.Getenv("PROMPT_VERSION_" + strings.ToUpper(name)); value != "" {
+		if v, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(value), "v")); err == nil {
+			if _, ok := versions[v]; ok {
+				return v
+			}
+		}
+		log.Printf("[WARN] 提示模板 %s 不存在版本 %s，使用最新版本", name, value)
+	}
+
+	latest := 0
+	for v := range versions {
+		if v > latest {
+			latest = v
+		}
+	}
+	return latest
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Validator 可自行校验必填字段的AI响应模型
+type Validator interface {
+	Validate() error
+}
+
+// ErrInvalidAIResponse AI响应在多次重新请求后仍无法解析或校验失败
+var ErrInvalidAIResponse = errors.New("AI响应无效")
+
+// ErrTruncatedResponse 模型输出达到最大输出令牌数被截断
+var ErrTruncatedResponse = errors.New("AI输出超过长度限制被截断")
+
+// defaultJSONMaxAttempts 默认最多请求次数（含首次）
+const defaultJSONMaxAttempts = 3
+
+// GenerateJSON 调用模型生成JSON并解码为 T，解析或校验失败时携带错误信息重新请求
+// 模型报告输出因达到最大输出令牌数被截断时不再解析，直接要求模型精简输出后重新请求
+// call 接收本次使用的提示并返回模型结果，调用方负责选择提供方和附加文件
+func GenerateJSON[T any](label, prompt string, call func(prompt string) (*GenerateResult, error)) (*T, *GenerateResult, error) {
+	maxAttempts := jsonMaxAttempts()
+	currentPrompt := prompt
+
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		result, err := call(currentPrompt)
+		if err != nil {
+			log.Printf("[ERROR] %s 第 %d/%d 次生成失败: %v", label, attempt, maxAttempts, err)
+			return nil, nil, err
+		}
+
+		if result.FinishReason.Truncated() {
+			lastErr = ErrTruncatedResponse
+			log.Printf("[WARN] %s 第 %d/%d 次生成的输出达到最大输出令牌数被截断", label, attempt, maxAttempts)
+			currentPrompt = BuildTruncationPrompt(prompt)
+			continue
+		}
+
+		value, err := DecodeJSON[T](result.Text)
+		if err == nil {
+			log.Printf("[INFO] %s 第 %d/%d 次生成成功，响应有效", label, attempt, maxAttempts)
+			return value, result, nil
+		}
+
+		lastErr = err
+		result.Discard()
+		log.Printf("[WARN] %s 第 %d/%d 次生成的响应无效: %v", label, attempt, maxAttempts, err)
+		currentPrompt = BuildRepairPrompt(prompt, result.Text, err)
+	}
+
+	return nil, nil, fmt.Errorf("%w: 已尝试 %d 次, 最后错误: %w", ErrInvalidAIResponse, maxAttempts, lastErr)
+}
+
+// DecodeJSON 清理模型输出并解码为 T，T 实现 Validator 时同时校验必填字段
+func DecodeJSON[T any](text string) (*T, error) {
+	cleaned := extractJSONObject(CleanMarkdownCodeBlock(text))
+
+	var value T
+	if err := json.Unmarshal([]byte(cleaned), &value); err != nil {
+		return nil, fmt.Errorf("JSON解析失败: %w", err)
+	}
+
+	if v, ok := any(&value).(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return nil, fmt.Errorf("字段校验失败: %w", err)
+		}
+	}
+
+	return &value, nil
+}
+
+// BuildRepairPrompt 在原始提示后附加上一次的无效输出和错误原因，要求模型修正
+func BuildRepairPrompt(prompt, previousOutput string, validationErr error) string {
+	return fmt.Sprintf(`%s
+
+你上一次的回复无法被系统接受，错误原因: %v
+上一次的回复（可能被截断）:
+%s
+
+请修正上述问题，重新输出完整、有效的JSON，所有必填字段都不能为空。直接返回JSON，不要使用Markdown代码块，不要添加任何额外的解释。`,
+		prompt, validationErr, truncateRunes(previousOutput, 2000))
+}
+
+// BuildTruncationPrompt 上一次的输出被截断时，在原始提示后要求模型精简内容
+func BuildTruncationPrompt(prompt string) string {
+	return fmt.Sprintf(`%s
+
+你上一次的回复超出了输出长度限制，被截断了。请精简内容：缩短每一项的描述，只保留最关键的要点，重新输出完整、有效的JSON。直接返回JSON，不要使用Markdown代码块，不要添加任何额外的解释。`, prompt)
+}
+
+// extractJSONObject 去掉模型在JSON对象前后附加的说明文字，不尝试补全不完整的JSON
+func extractJSONObject(text string) string {
+	start := strings.Index(text, "{")
+	end := strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return text
+	}
+	return text[start : end+1]
+}
+
+// jsonMaxAttempts 读取 LLM_JSON_MAX_ATTEMPTS，默认3次
+func jsonMaxAttempts() int {
+	if value := os.Getenv("LLM_JSON_MAX_ATTEMPTS"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			return n
+		}
+		log.Printf("[WARN] 无效的 LLM_JSON_MAX_ATTEMPTS: %s，使用默认值 %d", value, defaultJSONMaxAttempts)
+	}
+	return defaultJSONMaxAttempts
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"strconv"
+	"time"
+)
+
+// 重试默认参数
+const (
+	defaultRetryMaxAttempts = 3
+	defaultRetryBaseDelay   = 500 * time.Millisecond
+	defaultRetryMaxDelay    = 8 * time.Second
+)
+
+// retryPolicy 指数退避重试策略
+type retryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}
+
+// retryProvider 为提供方增加错误分类和临时错误的自动重试
+type retryProvider struct {
+	inner  LLMProvider
+	policy retryPolicy
+}
+
+var _ LLMProvider = (*retryProvider)(nil)
+
+// WithRetry 包装提供方：所有错误归类为 *LLMError，配额、超时、服务不可用和空响应按带抖动的指数退避重试
+// 重试参数读取 LLM_RETRY_MAX_ATTEMPTS、LLM_RETRY_BASE_DELAY、LLM_RETRY_MAX_DELAY
+func WithRetry(p LLMProvider) LLMProvider {
+	return &retryProvider{inner: p, policy: retryPolicyFromEnv()}
+}
+
+// Name 返回被包装提供方的名称
+func (p *retryProvider) Name() string {
+	return p.inner.Name()
+}
+
+// Model 返回被包装提供方的模型名称
+func (p *retryProvider) Model() string {
+	return p.inner.Model()
+}
+
+// GenerateContent 带重试地生成内容
+func (p *retryProvider) GenerateContent(ctx context.Context, systemInstruction, prompt string, opts ...GenerateOption) (*GenerateResult, error) {
+	var result *GenerateResult
+	err := p.do(ctx, "GenerateContent", func() error {
+		var err error
+		result, err = p.inner.GenerateContent(ctx, systemInstruction, prompt, opts...)
+		return err
+	})
+	return result, err
+}
+
+// GenerateContentWithBinaryFile 带重试地基于文件生成内容
+func (p *retryProvider) GenerateContentWithBinaryFile(ctx context.Context, systemInstruction string, fileContent string, mimeType string, textPrompt string, opts ...GenerateOption) (*GenerateResult, error) {
+	var result *GenerateResult
+	err := p.do(ctx, "GenerateContentWithBinaryFile", func() error {
+		var err error
+		result, err = p.inner.GenerateContentWithBinaryFile(ctx, systemInstruction, fileContent, mimeType, textPrompt, opts...)
+		return err
+	})
+	return result, err
+}
+
+// GenerateContentStream 只对建立流式连接的调用重试，已开始输出后的错误直接返回给调用方
+func (p *retryProvider) GenerateContentStream(ctx context.Context, systemInstruction, prompt string, opts ...GenerateOption) (StreamIterator, error) {
+	var iter StreamIterator
+	err := p.do(ctx, "GenerateContentStream", func() error {
+		var err error
+		iter, err = p.inner.GenerateContentStream(ctx, systemInstruction, prompt, opts...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &classifyingStream{inner: iter, provider: p.inner.Name()}, nil
+}
+
+// do 执行调用，遇到可重试错误时等待后重试，返回分类后的错误
+func (p *retryProvider) do(ctx context.Context, op string, call func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = ClassifyLLMError(p.inner.Name(), call())
+		if err == nil {
+			if attempt > 1 {
+				log.Printf("[INFO] %s %s 第 %d 次尝试成功", p.inner.Name(), op, attempt)
+			}
+			return nil
+		}
+
+		var llmErr *LLMError
+		if !errors.As(err, &llmErr) || !llmErr.Retryable() || attempt >= p.policy.maxAttempts {
+			return err
+		}
+
+		delay := p.policy.backoff(attempt)
+		log.Printf("[WARN] %s %s 第 %d/%d 次尝试失败 (%s)，%v 后重试: %v",
+			p.inner.Name(), op, attempt, p.policy.maxAttempts, llmErr.Kind, delay, llmErr.Err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ClassifyLLMError(p.inner.Name(), ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff 计算第 attempt 次失败后的等待时间：指数增长并在 [delay/2, delay] 内随机抖动
+func (r retryPolicy) backoff(attempt int) time.Duration {
+	delay := r.baseDelay << (attempt - 1)
+	if delay <= 0 || delay > r.maxDelay {
+		delay = r.maxDelay
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// retryPolicyFromEnv 从环境变量读取重试参数
+func retryPolicyFromEnv() retryPolicy {
+	policy := retryPolicy{
+		maxAttempts: defaultRetryMaxAttempts,
+		baseDelay:   defaultRetryBaseDelay,
+		maxDelay:    defaultRetryMaxDelay,
+	}
+
+	if value := os.Getenv("LLM_RETRY_MAX_ATTEMPTS"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			policy.maxAttempts = n
+		} else {
+			log.Printf("[WARN] 无效的 LLM_RETRY_MAX_ATTEMPTS: %s，使用默认值 %d", value, defaultRetryMaxAttempts)
+		}
+	}
+	if value := os.Getenv("LLM_RETRY_BASE_DELAY"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			policy.baseDelay = d
+		} else {
+			log.Printf("[WARN] 无效的 LLM_RETRY_BASE_DELAY: %s，使用默认值 %v", value, defaultRetryBaseDelay)
+		}
+	}
+	if value := os.Getenv("LLM_RETRY_MAX_DELAY"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			policy.maxDelay = d
+		} else {
+			log.Printf("[WARN] 无效的 LLM_RETRY_MAX_DELAY: %s，使用默认值 %v", value, defaultRetryMaxDelay)
+		}
+	}
+	if policy.maxDelay < policy.baseDelay {
+		policy.maxDelay = policy.baseDelay
+	}
+
+	return policy
+}
+
+// classifyingStream 对流式输出过程中的错误进行分类
+type classifyingStream struct {
+	inner    StreamIterator
+	provider string
+}
+
+// Next 返回下一段文本，io.EOF 原样返回
+func (s *classifyingStream) Next() (string, error) {
+	chunk, err := s.inner.Next()
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", ClassifyLLMError(s.provider, err)
+	}
+	return chunk, err
+}
+
+// Usage 返回被包装流的令牌用量
+func (s *classifyingStream) Usage() TokenUsage {
+	return s.inner.Usage()
+}
+
+// FinishReason 返回被包装流的结束原因
+func (s *classifyingStream) FinishReason() FinishReason {
+	return s.inner.FinishReason()
+}
+
+// Close 关闭被包装的流
+func (s *classifyingStream) Close() error {
+	return s.inner.Close()
+}
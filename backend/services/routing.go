@@ -0,0 +1,233 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// Task 调用大模型的业务任务，不同任务可以路由到不同的提供方和模型
+type Task string
+
+const (
+	TaskScreening  Task = "screening"  // 简历筛选
+	TaskQuestions  Task = "questions"  // 面试题生成
+	TaskSummary    Task = "summary"    // 面试总结
+	TaskStream     Task = "stream"     // 面试题流式生成
+	TaskChat       Task = "chat"       // 候选人多轮问答
+	TaskCompliance Task = "compliance" // 生成内容的合规检查
+)
+
+// Route 路由链中的一项：提供方和模型，模型为空时使用该提供方的默认模型
+type Route struct {
+	Provider string
+	Model    string
+}
+
+// NewLLMProviderForTask 按任务的路由配置创建提供方
+// 路由读取 LLM_ROUTE_<TASK>，未配置时读取 LLM_ROUTE_DEFAULT，都未配置时使用 LLM_PROVIDER
+// 路由链中的每一项都会先按退避策略重试，仍失败时自动切换到下一项
+func NewLLMProviderForTask(task Task) LLMProvider {
+	routes, err := RoutesForTask(task)
+	if err != nil {
+		log.Printf("[WARN] 任务 %s 的路由配置无效: %v，使用 LLM_PROVIDER", task, err)
+		return NewLLMProvider()
+	}
+
+	chain := make([]LLMProvider, 0, len(routes))
+	for _, route := range routes {
+		chain = append(chain, WithRetry(newRoutedProvider(route)))
+	}
+	if len(chain) == 1 {
+		return chain[0]
+	}
+	return &fallbackProvider{chain: chain}
+}
+
+// RoutesForTask 解析任务的路由链
+func RoutesForTask(task Task) ([]Route, error) {
+	spec := os.Getenv("LLM_ROUTE_" + strings.ToUpper(string(task)))
+	if spec == "" {
+		spec = os.Getenv("LLM_ROUTE_DEFAULT")
+	}
+	if spec == "" {
+		return []Route{{Provider: normalizeProviderName(os.Getenv("LLM_PROVIDER"))}}, nil
+	}
+	return ParseRoutes(spec)
+}
+
+// ParseRoutes 解析逗号分隔的 provider:model 列表，模型名中可以包含冒号（如 local:qwen2.5:7b）
+func ParseRoutes(spec string) ([]Route, error) {
+	var routes []Route
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		provider, model, _ := strings.Cut(item, ":")
+		provider = normalizeProviderName(provider)
+		switch provider {
+		case ProviderVertex, ProviderOpenAI, ProviderLocal, ProviderFake:
+		default:
+			return nil, fmt.Errorf("未知的提供方: %s", provider)
+		}
+		routes = append(routes, Route{Provider: provider, Model: strings.TrimSpace(model)})
+	}
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("路由为空")
+	}
+	return routes, nil
+}
+
+// normalizeProviderName 规范化提供方名称，空值视为 Vertex AI
+func normalizeProviderName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return ProviderVertex
+	}
+	return name
+}
+
+// newRoutedProvider 创建路由项对应的提供方并覆盖模型
+func newRoutedProvider(route Route) LLMProvider {
+	p := newProviderByName(route.Provider)
+	if route.Model == "" {
+		return p
+	}
+
+	switch p := p.(type) {
+	case *VertexAIClient:
+		p.model = route.Model
+	case *OpenAIProvider:
+		p.model = route.Model
+	case *LocalProvider:
+		p.model = route.Model
+		if p.openAI != nil {
+			p.openAI.model = route.Model
+		}
+	case *FakeProvider:
+		if p.upstream != nil {
+			p.upstream = newRoutedProvider(Route{Provider: p.upstream.Name(), Model: route.Model})
+		}
+	}
+	return p
+}
+
+// configuredProviders 返回所有任务路由中出现过的提供方名称
+func configuredProviders() []string {
+	specs := []string{os.Getenv("LLM_ROUTE_DEFAULT")}
+	for _, task := range []Task{TaskScreening, TaskQuestions, TaskSummary, TaskStream, TaskChat, TaskCompliance} {
+		specs = append(specs, os.Getenv("LLM_ROUTE_"+strings.ToUpper(string(task))))
+	}
+
+	names := []string{normalizeProviderName(os.Getenv("LLM_PROVIDER"))}
+	for _, spec := range specs {
+		if spec == "" {
+			continue
+		}
+		routes, err := ParseRoutes(spec)
+		if err != nil {
+			continue
+		}
+		for _, route := range routes {
+			names = append(names, route.Provider)
+		}
+	}
+	return names
+}
+
+// fallbackProvider 按顺序尝试路由链中的提供方，前一项失败时切换到下一项
+type fallbackProvider struct {
+	chain []LLMProvider
+}
+
+var _ LLMProvider = (*fallbackProvider)(nil)
+
+// Name 返回首选提供方的名称，实际处理请求的提供方见 GenerateResult.Provider
+func (p *fallbackProvider) Name() string {
+	return p.chain[0].Name()
+}
+
+// Model 返回首选提供方的模型名称
+func (p *fallbackProvider) Model() string {
+	return p.chain[0].Model()
+}
+
+// GenerateContent 依次尝试路由链生成内容
+func (p *fallbackProvider) GenerateContent(ctx context.Context, systemInstruction, prompt string, opts ...GenerateOption) (*GenerateResult, error) {
+	var result *GenerateResult
+	err := p.do(ctx, "GenerateContent", func(provider LLMProvider) error {
+		var err error
+		result, err = provider.GenerateContent(ctx, systemInstruction, prompt, opts...)
+		return err
+	})
+	return result, err
+}
+
+// GenerateContentWithBinaryFile 依次尝试路由链基于文件生成内容
+func (p *fallbackProvider) GenerateContentWithBinaryFile(ctx context.Context, systemInstruction string, fileContent string, mimeType string, textPrompt string, opts ...GenerateOption) (*GenerateResult, error) {
+	var result *GenerateResult
+	err := p.do(ctx, "GenerateContentWithBinaryFile", func(provider LLMProvider) error {
+		var err error
+		result, err = provider.GenerateContentWithBinaryFile(ctx, systemInstruction, fileContent, mimeType, textPrompt, opts...)
+		return err
+	})
+	return result, err
+}
+
+// GenerateContentStream 依次尝试路由链建立流式连接，开始输出后不再切换
+func (p *fallbackProvider) GenerateContentStream(ctx context.Context, systemInstruction, prompt string, opts ...GenerateOption) (StreamIterator, error) {
+	var iter StreamIterator
+	err := p.do(ctx, "GenerateContentStream", func(provider LLMProvider) error {
+		var err error
+		iter, err = provider.GenerateContentStream(ctx, systemInstruction, prompt, opts...)
+		if err == nil {
+			iter = &routedStream{StreamIterator: iter, provider: provider.Name(), model: provider.Model()}
+		}
+		return err
+	})
+	return iter, err
+}
+
+// routedStream 记录实际处理流式请求的提供方和模型
+type routedStream struct {
+	StreamIterator
+	provider string
+	model    string
+}
+
+// Source 返回实际处理请求的提供方和模型
+func (s *routedStream) Source() (string, string) {
+	return s.provider, s.model
+}
+
+// do 依次调用路由链，失败时切换到下一个提供方，包括凭证缺失、输入无法被该模型处理等只与单个提供方有关的错误
+// 调用方取消或超过截止时间后不再切换；被安全策略拦截的内容换提供方也不应绕过，直接返回
+func (p *fallbackProvider) do(ctx context.Context, op string, call func(provider LLMProvider) error) error {
+	var err error
+	for i, provider := range p.chain {
+		err = call(provider)
+		if err == nil {
+			if i > 0 {
+				log.Printf("[INFO] %s 已切换到备用提供方 %s/%s", op, provider.Name(), provider.Model())
+			}
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		kind := LLMErrorKindOf(err)
+		if kind == ErrKindCanceled || kind == ErrKindSafety {
+			return err
+		}
+		if i+1 < len(p.chain) {
+			next := p.chain[i+1]
+			log.Printf("[WARN] %s/%s %s 失败 (%s)，切换到 %s/%s: %v",
+				provider.Name(), provider.Model(), op, kind, next.Name(), next.Model(), err)
+		}
+	}
+	return err
+}
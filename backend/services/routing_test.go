@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// failingProvider 每次调用都返回指定错误并计数
+type failingProvider struct {
+	*FakeProvider
+	err   error
+	calls int
+}
+
+func (p *failingProvider) GenerateContent(ctx context.Context, systemInstruction, prompt string, opts ...GenerateOption) (*GenerateResult, error) {
+	p.calls++
+	return nil, p.err
+}
+
+func TestFallbackProviderSwitchesUnlessCanceledOrSafety(t *testing.T) {
+	tests := []struct {
+		kind     LLMErrorKind
+		fallback bool
+	}{
+		{ErrKindQuota, true},
+		{ErrKindUnavailable, true},
+		{ErrKindTimeout, true},
+		{ErrKindEmpty, true},
+		{ErrKindBadInput, true},
+		{ErrKindAuth, true},
+		{ErrKindUnknown, true},
+		{ErrKindSafety, false},
+		{ErrKindCanceled, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.kind), func(t *testing.T) {
+			first := &failingProvider{FakeProvider: NewFakeProvider(), err: &LLMError{Kind: tt.kind, Err: errors.New("失败")}}
+			second := NewFakeProvider()
+			p := &fallbackProvider{chain: []LLMProvider{first, second}}
+
+			_, err := p.GenerateContent(context.Background(), "system", "prompt")
+			if tt.fallback && err != nil {
+				t.Fatalf("expected fallback to succeed, got %v", err)
+			}
+			if !tt.fallback && LLMErrorKindOf(err) != tt.kind {
+				t.Fatalf("err = %v, want kind %s without fallback", err, tt.kind)
+			}
+		})
+	}
+
+	// 未包装为 LLMError 的错误（如缺少凭证文件）同样切换
+	first := &failingProvider{FakeProvider: NewFakeProvider(), err: ErrCredentials}
+	p := &fallbackProvider{chain: []LLMProvider{first, NewFakeProvider()}}
+	if _, err := p.GenerateContent(context.Background(), "system", "prompt"); err != nil {
+		t.Fatalf("expected fallback after %v, got %v", ErrCredentials, err)
+	}
+}
+
+func TestFallbackProviderStopsWhenContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	first := &failingProvider{FakeProvider: NewFakeProvider(), err: &LLMError{Kind: ErrKindTimeout, Err: errors.New("超时")}}
+	second := &failingProvider{FakeProvider: NewFakeProvider(), err: errors.New("不应调用")}
+	p := &fallbackProvider{chain: []LLMProvider{first, second}}
+
+	if _, err := p.GenerateContent(ctx, "system", "prompt"); err == nil {
+		t.Fatal("expected error")
+	}
+	if second.calls != 0 {
+		t.Fatalf("fallback called %d times after the context ended", second.calls)
+	}
+}
@@ -0,0 +1,262 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// rtfSkipDestinations 不包含正文的目标组，整组跳过
+var rtfSkipDestinations = map[string]bool{
+	"colortbl": true, "stylesheet": true, "info": true, "pict": true, "object": true,
+	"header": true, "headerl": true, "headerr": true, "headerf": true,
+	"footer": true, "footerl": true, "footerr": true, "footerf": true,
+	"footnote": true, "annotation": true, "fldinst": true, "listtable": true,
+	"listoverridetable": true, "rsidtbl": true, "generator": true, "xmlnstbl": true,
+	"themedata": true, "colorschememapping": true, "datastore": true, "latentstyles": true,
+	"filetbl": true, "revtbl": true, "pgdsctbl": true, "mmathPr": true, "bkmkstart": true,
+	"bkmkend": true, "listtext": true, "pntext": true, "pntxta": true, "pntxtb": true,
+}
+
+// rtfSymbols 表示特殊字符的控制字
+var rtfSymbols = map[string]string{
+	"par": "\n", "line": "\n", "sect": "\n", "page": "\n", "row": "\n",
+	"tab": "\t", "cell": "\t", "bullet": "•", "emdash": "—", "endash": "–",
+	"emspace": " ", "enspace": " ", "qmspace": " ", "lquote": "‘", "rquote": "’",
+	"ldblquote": "“", "rdblquote": "”",
+}
+
+// rtfCharsetCodePages 字体表 \fcharset 对应的代码页
+var rtfCharsetCodePages = map[int]int{
+	128: 932, 129: 949, 134: 936, 136: 950, 161: 1253, 162: 1254,
+	177: 1255, 178: 1256, 186: 1257, 204: 1251, 222: 874, 238: 1250,
+}
+
+// rtfState 组内的解析状态，进入组时复制，离开组时恢复
+type rtfState struct {
+	skip      bool
+	ucSkip    int
+	codePage  int
+	fontTable bool
+}
+
+// rtfParser RTF 文本提取器
+type rtfParser struct {
+	data      []byte
+	pos       int
+	state     rtfState
+	stack     []rtfState
+	out       strings.Builder
+	pending   []byte // 等待按代码页解码的 \'hh 字节
+	skipChars int    // \uN 之后需要跳过的替代字符数
+	ansiCP    int
+	fonts     map[int]int // 字体编号 -> 代码页
+	font      int         // 字体表中正在定义的字体
+}
+
+// ExtractRTFText 从 RTF 文件中提取正文
+// 按 \ansicpg 和字体的 \fcharset 解码 \'hh 字节（支持 GBK、Big5、Shift-JIS 等），处理 \uN Unicode 字符，跳过页眉页脚、图片和域代码
+func ExtractRTFText(data []byte) (string, error) {
+	if !strings.HasPrefix(string(data), `{\rtf`) {
+		return "", fmt.Errorf("无效的RTF文件")
+	}
+	p := &rtfParser{data: data, ansiCP: 1252, fonts: map[int]int{}}
+	p.state = rtfState{ucSkip: 1, codePage: 1252}
+	p.run()
+
+	lines := strings.Split(p.out.String(), "\n")
+	out := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimRight(line, "\t ")
+		if strings.TrimSpace(line) != "" {
+			out = append(out, line)
+		}
+	}
+	return strings.Join(out, "\n"), nil
+}
+
+// run 逐个处理组、控制字和文字
+func (p *rtfParser) run() {
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		switch c {
+		case '{':
+			p.flush()
+			p.stack = append(p.stack, p.state)
+			p.pos++
+		case '}':
+			p.flush()
+			if n := len(p.stack); n > 0 {
+				p.state, p.stack = p.stack[n-1], p.stack[:n-1]
+			}
+			p.pos++
+		case '\\':
+			p.control()
+		case '\r', '\n':
+			p.pos++
+		default:
+			p.pos++
+			p.text(c)
+		}
+	}
+	p.flush()
+}
+
+// control 处理控制字或控制符号
+func (p *rtfParser) control() {
+	p.pos++
+	if p.pos >= len(p.data) {
+		return
+	}
+	c := p.data[p.pos]
+	if !isASCIILetter(c) {
+		p.pos++
+		switch c {
+		case '\'':
+			if p.pos+2 <= len(p.data) {
+				if b, err := strconv.ParseUint(string(p.data[p.pos:p.pos+2]), 16, 8); err == nil {
+					p.pos += 2
+					p.raw(byte(b))
+				}
+			}
+		case '*':
+			// 无法识别的可选目标整组跳过
+			p.state.skip = true
+		case '~':
+			p.emit(" ")
+		case '_':
+			p.emit("-")
+		case '\r', '\n':
+			p.emit("\n")
+		case '\\', '{', '}':
+			p.text(c)
+		}
+		return
+	}
+
+	start := p.pos
+	for p.pos < len(p.data) && isASCIILetter(p.data[p.pos]) {
+		p.pos++
+	}
+	word := string(p.data[start:p.pos])
+	param, hasParam := 0, false
+	if p.pos < len(p.data) && (p.data[p.pos] == '-' || isASCIIDigit(p.data[p.pos])) {
+		numStart := p.pos
+		p.pos++
+		for p.pos < len(p.data) && isASCIIDigit(p.data[p.pos]) {
+			p.pos++
+		}
+		param, _ = strconv.Atoi(string(p.data[numStart:p.pos]))
+		hasParam = true
+	}
+	if p.pos < len(p.data) && p.data[p.pos] == ' ' {
+		p.pos++
+	}
+	p.word(word, param, hasParam)
+}
+
+// word 处理控制字
+func (p *rtfParser) word(word string, param int, hasParam bool) {
+	if rtfSkipDestinations[word] {
+		p.flush()
+		p.state.skip = true
+		return
+	}
+	switch word {
+	case "fonttbl":
+		p.state.fontTable = true
+	case "ansicpg":
+		p.ansiCP = param
+		p.state.codePage = param
+	case "f":
+		if p.state.fontTable {
+			p.font = param
+			return
+		}
+		p.flush()
+		if cp, ok := p.fonts[param]; ok {
+			p.state.codePage = cp
+		} else {
+			p.state.codePage = p.ansiCP
+		}
+	case "fcharset":
+		if cp, ok := rtfCharsetCodePages[param]; ok && p.state.fontTable {
+			p.fonts[p.font] = cp
+		}
+	case "uc":
+		if hasParam {
+			p.state.ucSkip = param
+		}
+	case "u":
+		if param < 0 {
+			param += 65536
+		}
+		p.flush()
+		p.emit(string(rune(param)))
+		p.skipChars = p.state.ucSkip
+	default:
+		if symbol, ok := rtfSymbols[word]; ok {
+			p.flush()
+			p.emit(symbol)
+		}
+	}
+}
+
+// text 输出普通字符
+func (p *rtfParser) text(c byte) {
+	if p.skipChars > 0 {
+		p.skipChars--
+		return
+	}
+	if p.state.fontTable || p.state.skip {
+		return
+	}
+	if c < 0x80 && len(p.pending) == 0 {
+		p.out.WriteByte(c)
+		return
+	}
+	p.pending = append(p.pending, c)
+}
+
+// raw 收集 \'hh 字节，多字节编码的字符由相邻的多个 \'hh 组成
+func (p *rtfParser) raw(b byte) {
+	if p.skipChars > 0 {
+		p.skipChars--
+		return
+	}
+	if p.state.skip || p.state.fontTable {
+		return
+	}
+	p.pending = append(p.pending, b)
+}
+
+// emit 输出已解码的文字
+func (p *rtfParser) emit(s string) {
+	p.skipChars = 0
+	if p.state.skip || p.state.fontTable {
+		return
+	}
+	p.flush()
+	p.out.WriteString(s)
+}
+
+// flush 按当前代码页解码等待中的字节
+func (p *rtfParser) flush() {
+	if len(p.pending) == 0 {
+		return
+	}
+	decoded, err := windowsCodePage(p.state.codePage).NewDecoder().Bytes(p.pending)
+	if err != nil {
+		decoded = []byte(strings.ToValidUTF8(string(p.pending), ""))
+	}
+	p.out.Write(decoded)
+	p.pending = p.pending[:0]
+}
+
+func isASCIILetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isASCIIDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
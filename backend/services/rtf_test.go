@@ -0,0 +1,76 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+// testRTF 包含 GBK 字体、\'hh 和 \uN 转义、域、页眉和表格的 RTF
+const testRTF = `{\rtf1\ansi\ansicpg1252\deff0` +
+	`{\fonttbl{\f0\fswiss Arial;}{\f1\fnil\fcharset134 SimSun;}}` +
+	`{\colortbl;\red0\green0\blue0;}` +
+	`{\*\generator Writer;}` +
+	`{\header Page header\par}` +
+	`\pard\f1 \'d5\'c5\'c8\'fd\f0\par ` +
+	`Caf\'e9 \u24037\'b9\u20316?\par ` +
+	`\uc2\u38754\'c3\'e6\uc1 \u-26782?\par ` +
+	`{\field{\*\fldinst HYPERLINK "https://example.com"}{\fldrslt example.com}}\line` +
+	`\{braces\} and \\backslash\par ` +
+	`Go\cell Kubernetes\cell\row` +
+	`{\pict\pngblip 89504e47}` +
+	`}`
+
+func TestExtractRTFText(t *testing.T) {
+	got, err := ExtractRTFText([]byte(testRTF))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "张三\nCafé 工作\n面面\nexample.com\n{braces} and \\backslash\nGo\tKubernetes"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestExtractRTFTextEscapes(t *testing.T) {
+	tests := []struct {
+		name string
+		rtf  string
+		want string
+	}{
+		{"windows-1252", `{\rtf1\ansi\ansicpg1252 na\'efve \'93quoted\'94}`, "naïve “quoted”"},
+		{"shift-jis font", `{\rtf1{\fonttbl{\f0\fcharset128 MS Mincho;}}\f0 \'93\'fa\'96\'7b}`, "日本"},
+		{"ansicpg936", `{\rtf1\ansi\ansicpg936 \'b9\'a4\'b3\'cc\'ca\'a6}`, "工程师"},
+		{"unicode with default fallback", `{\rtf1\u24352?\u19977?}`, "张三"},
+		{"unicode without fallback", `{\rtf1\uc0\u24352\u19977}`, "张三"},
+		{"unicode fallback skips escaped byte", `{\rtf1\u24352\'3f end}`, "张 end"},
+		{"group restores uc", `{\rtf1{\uc2\u24352??}\u19977?}`, "张三"},
+		{"symbols", `{\rtf1 a\tab b\emdash c\bullet\~d}`, "a\tb—c• d"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExtractRTFText([]byte(tt.rtf))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractRTFTextInvalid(t *testing.T) {
+	if _, err := ExtractRTFText([]byte("plain text")); err == nil {
+		t.Error("expected error for non-RTF data")
+	}
+}
+
+func FuzzExtractRTFText(f *testing.F) {
+	fuzzExtract(f, ExtractRTFText,
+		[]byte(testRTF),
+		[]byte(`{\rtf1\uc9\u-1\'ff{{{\*\x}}}`),
+		// 参数超出整数范围、跳过字符数极大和未闭合的深层分组
+		[]byte(`{\rtf1\uc2147483647\u99999999999999999999 x\u-2147483648?\ansicpg-1\f99999999999 y\'`),
+		[]byte(`{\rtf1 `+strings.Repeat("{\\b ", 10000)+"z"),
+	)
+}
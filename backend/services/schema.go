@@ -0,0 +1,161 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/vertexai/genai"
+)
+
+// ResponseSchema 由响应模型结构体生成的输出约束
+// 同时提供 Gemini 使用的 genai.Schema 和其他提供方使用的 JSON Schema
+type ResponseSchema struct {
+	Name   string
+	Gemini *genai.Schema
+	JSON   map[string]interface{}
+}
+
+var schemaCache sync.Map // reflect.Type -> *ResponseSchema
+
+// SchemaFor 根据结构体的 json 和 desc 标签生成响应约束，结果按类型缓存
+func SchemaFor(v interface{}) *ResponseSchema {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if cached, ok := schemaCache.Load(t); ok {
+		return cached.(*ResponseSchema)
+	}
+
+	schema := &ResponseSchema{
+		Name:   t.Name(),
+		Gemini: geminiSchemaFor(t, ""),
+		JSON:   jsonSchemaFor(t, ""),
+	}
+	schemaCache.Store(t, schema)
+	return schema
+}
+
+// schemaField 结构体中参与序列化的字段
+type schemaField struct {
+	name        string
+	description string
+	optional    bool
+	typ         reflect.Type
+}
+
+// schemaFields 解析结构体字段的 json 名称和描述
+func schemaFields(t reflect.Type) []schemaField {
+	var fields []schemaField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		// schema:"-" 的字段由服务端填写，不要求模型输出
+		if !f.IsExported() || f.Tag.Get("schema") == "-" {
+			continue
+		}
+
+		name := f.Name
+		optional := false
+		if tag := f.Tag.Get("json"); tag != "" {
+			parts := strings.Split(tag, ",")
+			if parts[0] == "-" {
+				continue
+			}
+			if parts[0] != "" {
+				name = parts[0]
+			}
+			for _, opt := range parts[1:] {
+				if opt == "omitempty" {
+					optional = true
+				}
+			}
+		}
+
+		fields = append(fields, schemaField{
+			name:        name,
+			description: f.Tag.Get("desc"),
+			optional:    optional,
+			typ:         f.Type,
+		})
+	}
+	return fields
+}
+
+// geminiSchemaFor 生成 Gemini 的 OpenAPI 子集 Schema
+func geminiSchemaFor(t reflect.Type, description string) *genai.Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	s := &genai.Schema{Description: description}
+	switch t.Kind() {
+	case reflect.String:
+		s.Type = genai.TypeString
+	case reflect.Bool:
+		s.Type = genai.TypeBoolean
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s.Type = genai.TypeInteger
+	case reflect.Float32, reflect.Float64:
+		s.Type = genai.TypeNumber
+	case reflect.Slice, reflect.Array:
+		s.Type = genai.TypeArray
+		s.Items = geminiSchemaFor(t.Elem(), "")
+	case reflect.Struct:
+		s.Type = genai.TypeObject
+		s.Properties = make(map[string]*genai.Schema)
+		for _, f := range schemaFields(t) {
+			s.Properties[f.name] = geminiSchemaFor(f.typ, f.description)
+			if !f.optional {
+				s.Required = append(s.Required, f.name)
+			}
+		}
+	default:
+		s.Type = genai.TypeString
+	}
+	return s
+}
+
+// jsonSchemaFor 生成标准 JSON Schema，满足 OpenAI strict 模式的要求
+func jsonSchemaFor(t reflect.Type, description string) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	s := map[string]interface{}{}
+	if description != "" {
+		s["description"] = description
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		s["type"] = "string"
+	case reflect.Bool:
+		s["type"] = "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s["type"] = "integer"
+	case reflect.Float32, reflect.Float64:
+		s["type"] = "number"
+	case reflect.Slice, reflect.Array:
+		s["type"] = "array"
+		s["items"] = jsonSchemaFor(t.Elem(), "")
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		// strict 模式要求列出全部属性
+		required := []string{}
+		for _, f := range schemaFields(t) {
+			properties[f.name] = jsonSchemaFor(f.typ, f.description)
+			required = append(required, f.name)
+		}
+		s["type"] = "object"
+		s["properties"] = properties
+		s["required"] = required
+		s["additionalProperties"] = false
+	default:
+		s["type"] = "string"
+	}
+	return s
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultStreamIdleTimeout 流式响应两段内容之间的默认最长等待时间
+const defaultStreamIdleTimeout = 60 * time.Second
+
+// ErrStreamIdleTimeout 流式响应超过空闲时间未收到新内容
+var ErrStreamIdleTimeout = errors.New("流式响应空闲超时")
+
+// streamIdleTimeout 读取 LLM_STREAM_IDLE_TIMEOUT
+func streamIdleTimeout() time.Duration {
+	if value := os.Getenv("LLM_STREAM_IDLE_TIMEOUT"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			return d
+		}
+		log.Printf("[WARN] 无效的 LLM_STREAM_IDLE_TIMEOUT: %s，使用默认值 %v", value, defaultStreamIdleTimeout)
+	}
+	return defaultStreamIdleTimeout
+}
+
+// openStream 使用从 ctx 派生的可取消上下文建立流式连接，返回的迭代器拥有该上下文：
+// 流正常结束、出错、空闲超时或调用方关闭时取消上下文并释放连接，调用方取消 ctx 时连接随之中断
+func openStream(ctx context.Context, open func(ctx context.Context) (StreamIterator, error)) (StreamIterator, error) {
+	streamCtx, cancel := context.WithCancel(ctx)
+	inner, err := open(streamCtx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	s := &managedStream{inner: inner, cancel: cancel, idle: streamIdleTimeout()}
+	s.timer = time.AfterFunc(s.idle, func() {
+		s.idleFired.Store(true)
+		cancel()
+	})
+	s.timer.Stop()
+	return s, nil
+}
+
+// managedStream 管理一次流式调用的上下文、空闲超时和连接释放
+type managedStream struct {
+	inner     StreamIterator
+	cancel    context.CancelFunc
+	idle      time.Duration
+	timer     *time.Timer
+	idleFired atomic.Bool
+	closed    atomic.Bool
+	closeOnce sync.Once
+}
+
+// Next 返回下一段文本增量，超过空闲时间未收到内容时返回 ErrStreamIdleTimeout，流结束或出错后自动释放连接
+func (s *managedStream) Next() (string, error) {
+	if s.closed.Load() {
+		return "", io.EOF
+	}
+
+	s.timer.Reset(s.idle)
+	chunk, err := s.inner.Next()
+	s.timer.Stop()
+
+	if err != nil {
+		if s.idleFired.Load() {
+			err = fmt.Errorf("%w: 超过 %v 未收到新内容", ErrStreamIdleTimeout, s.idle)
+		}
+		s.Close()
+		return "", err
+	}
+	return chunk, nil
+}
+
+// Usage 返回流结束后的令牌用量
+func (s *managedStream) Usage() TokenUsage {
+	return s.inner.Usage()
+}
+
+// FinishReason 返回流结束后模型结束生成的原因
+func (s *managedStream) FinishReason() FinishReason {
+	return s.inner.FinishReason()
+}
+
+// Close 取消流的上下文并释放连接，可重复调用
+func (s *managedStream) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		s.closed.Store(true)
+		s.timer.Stop()
+		s.cancel()
+		err = s.inner.Close()
+	})
+	return err
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	textunicode "golang.org/x/text/encoding/unicode"
+)
+
+// decodeTextBytes 将纯文本文件解码为 UTF-8
+// 依次识别 BOM（UTF-8/UTF-16）、UTF-8，以及中文 Windows 常见的 GB18030，都不符合时按 Windows-1252 解码
+func decodeTextBytes(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return string(data[3:])
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}), bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		decoded, err := textunicode.UTF16(textunicode.BigEndian, textunicode.ExpectBOM).NewDecoder().Bytes(data)
+		if err == nil {
+			return string(decoded)
+		}
+	case utf8.Valid(data):
+		return string(data)
+	}
+	if decoded, err := simplifiedchinese.GB18030.NewDecoder().Bytes(data); err == nil && !bytes.ContainsRune(decoded, utf8.RuneError) {
+		return string(decoded)
+	}
+	if decoded, err := charmap.Windows1252.NewDecoder().Bytes(data); err == nil {
+		return string(decoded)
+	}
+	return strings.ToValidUTF8(string(data), "")
+}
+
+// invisibleRunes 零宽字符和方向控制符，常见于网页复制的文本，也可能被用来隐藏内容
+var invisibleRunes = strings.NewReplacer(
+	"\u200B", "", "\u200C", "", "\u200D", "", "\u2060", "", "\uFEFF", "",
+	"\u202A", "", "\u202B", "", "\u202C", "", "\u202D", "", "\u202E", "",
+	"\u00A0", " ", "\u3000", " ", "\u2028", "\n", "\u2029", "\n",
+)
+
+// NormalizeResumeText 规范化简历文本：统一换行，去掉控制字符和零宽字符，去掉行尾空白，连续的空行合并为一行
+func NormalizeResumeText(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	text = invisibleRunes.Replace(text)
+	text = strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
+		}
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, text)
+
+	lines := strings.Split(text, "\n")
+	out := make([]string, 0, len(lines))
+	blank := false
+	for _, line := range lines {
+		line = strings.TrimRightFunc(line, unicode.IsSpace)
+		if line == "" {
+			blank = len(out) > 0
+			continue
+		}
+		if blank {
+			out = append(out, "")
+			blank = false
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
+
+// Markdown 语法
+var (
+	mdFence        = regexp.MustCompile("^\\s*(```|~~~)")
+	mdHeading      = regexp.MustCompile(`^\s{0,3}#{1,6}\s+(.*?)\s*#*\s*$`)
+	mdSetextLine   = regexp.MustCompile(`^\s{0,3}(=+|-+)\s*$`)
+	mdRule         = regexp.MustCompile(`^\s{0,3}[-*_](\s*[-*_]){2,}\s*$`)
+	mdBlockquote   = regexp.MustCompile(`^\s*(>\s?)+`)
+	mdBullet       = regexp.MustCompile(`^(\s*)[-*+]\s+(\[[ xX]\]\s+)?`)
+	mdTableDivider = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+	mdImage        = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLink         = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)(\s+"[^"]*")?\)`)
+	mdRefLink      = regexp.MustCompile(`\[([^\]]+)\]\[[^\]]*\]`)
+	mdAutolink     = regexp.MustCompile(`<((?:https?://|mailto:)[^>\s]+|[^@<>\s]+@[^@<>\s]+)>`)
+	mdLineBreak    = regexp.MustCompile(`(?i)<br\s*/?>`)
+	mdHTMLTag      = regexp.MustCompile(`</?[a-zA-Z][a-zA-Z0-9-]*(\s[^<>]*)?/?>`)
+	mdStrong       = regexp.MustCompile(`(\*\*|__)(\S(?:.*?\S)?)(\*\*|__)`)
+	mdEmphasis     = regexp.MustCompile(`(^|[^\w*])[*_](\S(?:[^*_]*?\S)?)[*_]([^\w*]|$)`)
+	mdStrike       = regexp.MustCompile(`~~(\S(?:.*?\S)?)~~`)
+	mdCode         = regexp.MustCompile("`+([^`]+)`+")
+	mdEscape       = regexp.MustCompile(`\\([\\` + "`" + `*_{}\[\]()#+\-.!|>~])`)
+)
+
+// ExtractMarkdownText 去掉 Markdown 标记，保留标题、列表、表格和链接的文字
+// 列表项统一为“• ”开头，表格的单元格以制表符分隔，链接保留文字和地址
+func ExtractMarkdownText(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	out := make([]string, 0, len(lines))
+	inFence := false
+	for _, line := range lines {
+		if mdFence.MatchString(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			out = append(out, line)
+			continue
+		}
+
+		switch {
+		case mdTableDivider.MatchString(line) && strings.Contains(line, "|"):
+			continue
+		case mdSetextLine.MatchString(line) && len(out) > 0 && strings.TrimSpace(out[len(out)-1]) != "":
+			// 上一行是 Setext 风格的标题
+			continue
+		case mdRule.MatchString(line):
+			out = append(out, "")
+			continue
+		}
+
+		line = mdBlockquote.ReplaceAllString(line, "")
+		if m := mdHeading.FindStringSubmatch(line); m != nil {
+			line = m[1]
+		}
+		line = mdBullet.ReplaceAllString(line, "$1• ")
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "|") || strings.Count(line, "|") >= 2 {
+			cells := strings.Split(strings.Trim(trimmed, "|"), "|")
+			for i := range cells {
+				cells[i] = strings.TrimSpace(cells[i])
+			}
+			line = strings.Join(cells, "\t")
+		}
+		out = append(out, markdownInline(line))
+	}
+	return NormalizeResumeText(strings.Join(out, "\n"))
+}
+
+// markdownInline 去掉行内标记
+func markdownInline(line string) string {
+	line = mdImage.ReplaceAllString(line, "$1")
+	line = mdLink.ReplaceAllStringFunc(line, func(s string) string {
+		m := mdLink.FindStringSubmatch(s)
+		if m[1] == m[2] || strings.TrimPrefix(m[2], "mailto:") == m[1] {
+			return m[1]
+		}
+		return m[1] + " (" + strings.TrimPrefix(m[2], "mailto:") + ")"
+	})
+	line = mdRefLink.ReplaceAllString(line, "$1")
+	line = mdAutolink.ReplaceAllStringFunc(line, func(s string) string {
+		return strings.TrimPrefix(strings.Trim(s, "<>"), "mailto:")
+	})
+	line = mdLineBreak.ReplaceAllString(line, "\n")
+	line = mdHTMLTag.ReplaceAllString(line, "")
+	line = mdCode.ReplaceAllString(line, "$1")
+	line = mdStrong.ReplaceAllString(line, "$2")
+	line = mdStrike.ReplaceAllString(line, "$1")
+	line = mdEmphasis.ReplaceAllString(line, "$1$2$3")
+	return mdEscape.ReplaceAllString(line, "$1")
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	textunicode "golang.org/x/text/encoding/unicode"
+)
+
+func TestDecodeTextBytes(t *testing.T) {
+	const text = "张三 Café\n工作经历"
+	utf16LE, err := textunicode.UTF16(textunicode.LittleEndian, textunicode.UseBOM).NewEncoder().String(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	utf16BE, err := textunicode.UTF16(textunicode.BigEndian, textunicode.UseBOM).NewEncoder().String(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gb18030, err := simplifiedchinese.GB18030.NewEncoder().String(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	windows1252, err := charmap.Windows1252.NewEncoder().String("Café – naïve")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"utf-8", text, text},
+		{"utf-8 bom", "\xEF\xBB\xBF" + text, text},
+		{"utf-16le bom", utf16LE, text},
+		{"utf-16be bom", utf16BE, text},
+		{"gb18030", gb18030, text},
+		{"windows-1252", windows1252, "Café – naïve"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decodeTextBytes([]byte(tt.data)); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeResumeText(t *testing.T) {
+	in := "\r\n\r\n张三\u200b\u202e\u00a0 \r\n电话 138\x07\t\r\n\r\n\r\n\u3000\n工作经历\u2028某科技\ufeff\n\n"
+	want := "张三\n电话 138\n\n工作经历\n某科技"
+	if got := NormalizeResumeText(in); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestExtractMarkdownText(t *testing.T) {
+	md := "# 张三 #\n" +
+		"高级工程师\n" +
+		"===\n" +
+		"> 邮箱：<zhangsan@example.com>，主页：[博客](https://example.com \"博客\")\n" +
+		"\n" +
+		"## 技能\n" +
+		"- **Go**：熟悉 *并发* 与 `context`\n" +
+		"  * [x] gin\n" +
+		"+ ~~PHP~~ Kubernetes<br>Docker\n" +
+		"\n" +
+		"---\n" +
+		"\n" +
+		"| 公司 | 职位 |\n" +
+		"|:---|---:|\n" +
+		"| 某科技 | 工程师 |\n" +
+		"\n" +
+		"![头像](avatar.png) 1\\. 不是列表 snake_case_name\n" +
+		"```go\n" +
+		"# 代码中的注释\n" +
+		"```\n"
+	want := "张三\n" +
+		"高级工程师\n" +
+		"邮箱：zhangsan@example.com，主页：博客 (https://example.com)\n" +
+		"\n" +
+		"技能\n" +
+		"• Go：熟悉 并发 与 context\n" +
+		"  • gin\n" +
+		"• PHP Kubernetes\nDocker\n" +
+		"\n" +
+		"公司\t职位\n" +
+		"某科技\t工程师\n" +
+		"\n" +
+		"头像 1. 不是列表 snake_case_name\n" +
+		"# 代码中的注释"
+	if got := ExtractMarkdownText(md); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
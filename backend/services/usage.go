@@ -0,0 +1,392 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GiantClam/ai-resume/models"
+	"gorm.io/gorm"
+)
+
+// 用量记录的接口名称
+const (
+	UsageEndpointScreen          = "screen"
+	UsageEndpointQuestions       = "questions"
+	UsageEndpointQuestionsStream = "questions_stream"
+	UsageEndpointSummary         = "summary"
+	UsageEndpointChat            = "chat"
+)
+
+// UsageScope 用量归属：发起请求的用户、接口、请求ID和所用的提示模板
+type UsageScope struct {
+	RequestID string
+	UserID    uint // 未登录用户为0
+	Endpoint  string
+	Prompt    PromptRef
+}
+
+// 用量查询错误
+var (
+	ErrUsageStoreDisabled = errors.New("用量记录未启用")
+	ErrInvalidUsageFilter = errors.New("无效的用量查询条件")
+)
+
+// usageDB 用量记录使用的数据库连接，未初始化时只记录日志
+var usageDB *gorm.DB
+
+// InitUsageStore 设置用量记录使用的数据库连接，调用前需完成 models.LLMUsage 的迁移
+func InitUsageStore(db *gorm.DB) {
+	usageDB = db
+}
+
+// usageProvider 在每次成功调用后记录令牌用量
+type usageProvider struct {
+	inner LLMProvider
+	scope UsageScope
+}
+
+var _ LLMProvider = (*usageProvider)(nil)
+
+// WithUsageTracking 包装提供方，将每次调用的令牌用量归属到指定用户和接口并写入用量表
+func WithUsageTracking(p LLMProvider, scope UsageScope) LLMProvider {
+	return &usageProvider{inner: p, scope: scope}
+}
+
+// Name 返回被包装提供方的名称
+func (p *usageProvider) Name() string {
+	return p.inner.Name()
+}
+
+// Model 返回被包装提供方的模型名称
+func (p *usageProvider) Model() string {
+	return p.inner.Model()
+}
+
+// GenerateContent 生成内容并记录用量
+func (p *usageProvider) GenerateContent(ctx context.Context, systemInstruction, prompt string, opts ...GenerateOption) (*GenerateResult, error) {
+	result, err := p.inner.GenerateContent(ctx, systemInstruction, prompt, opts...)
+	if err == nil && result.CacheStatus != CacheHit {
+		usageRecorder(p.scope, result.Provider, result.Model, result.Usage)
+	}
+	return result, err
+}
+
+// GenerateContentWithBinaryFile 基于文件生成内容并记录用量
+func (p *usageProvider) GenerateContentWithBinaryFile(ctx context.Context, systemInstruction string, fileContent string, mimeType string, textPrompt string, opts ...GenerateOption) (*GenerateResult, error) {
+	result, err := p.inner.GenerateContentWithBinaryFile(ctx, systemInstruction, fileContent, mimeType, textPrompt, opts...)
+	if err == nil && result.CacheStatus != CacheHit {
+		usageRecorder(p.scope, result.Provider, result.Model, result.Usage)
+	}
+	return result, err
+}
+
+// GenerateContentStream 流式生成内容，流结束时记录用量
+func (p *usageProvider) GenerateContentStream(ctx context.Context, systemInstruction, prompt string, opts ...GenerateOption) (StreamIterator, error) {
+	iter, err := p.inner.GenerateContentStream(ctx, systemInstruction, prompt, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &usageStream{inner: iter, provider: p}, nil
+}
+
+// usageStream 在流结束、出错或被提前关闭时记录一次用量
+type usageStream struct {
+	inner    StreamIterator
+	provider *usageProvider
+	once     sync.Once
+}
+
+// Next 返回下一段文本，流结束或出错时记录用量
+func (s *usageStream) Next() (string, error) {
+	chunk, err := s.inner.Next()
+	if err != nil {
+		s.record()
+	}
+	return chunk, err
+}
+
+// record 记录流已消耗的令牌用量，只记录一次；出错或提前关闭时记录已产生的部分用量
+func (s *usageStream) record() {
+	s.once.Do(func() {
+		// 命中缓存时没有调用模型，不记录用量
+		if StreamCacheStatus(s.inner) == CacheHit {
+			return
+		}
+		// 经过路由链切换时以实际处理请求的提供方为准
+		provider, model := s.Source()
+		usageRecorder(s.provider.scope, provider, model, s.inner.Usage())
+	})
+}
+
+// Usage 返回被包装流的令牌用量
+func (s *usageStream) Usage() TokenUsage {
+	return s.inner.Usage()
+}
+
+// FinishReason 返回被包装流的结束原因
+func (s *usageStream) FinishReason() FinishReason {
+	return s.inner.FinishReason()
+}
+
+// Close 关闭被包装的流，未读到结尾时记录已产生的用量
+func (s *usageStream) Close() error {
+	s.record()
+	return s.inner.Close()
+}
+
+// Source 返回实际处理请求的提供方和模型
+func (s *usageStream) Source() (string, string) {
+	return StreamSource(s.inner, s.provider.Name(), s.provider.Model())
+}
+
+// CacheStatus 返回被包装流的缓存命中情况
+func (s *usageStream) CacheStatus() CacheStatus {
+	return StreamCacheStatus(s.inner)
+}
+
+// Discard 删除被包装流对应的缓存
+func (s *usageStream) Discard() {
+	DiscardStream(s.inner)
+}
+
+// usageRecorder 写入用量记录的函数，测试中可替换
+var usageRecorder = recordUsage
+
+// recordUsage 写入一条用量记录，写入失败只记录日志，不影响请求
+func recordUsage(scope UsageScope, provider, model string, usage TokenUsage) {
+	log.Printf("[INFO] 令牌用量: 请求=%s, 用户=%d, 接口=%s, 提示=%s, 模型=%s/%s, 输入=%d, 输出=%d, 合计=%d",
+		scope.RequestID, scope.UserID, scope.Endpoint, scope.Prompt, provider, model,
+		usage.PromptTokens, usage.CandidateTokens, usage.TotalTokens)
+
+	if usageDB == nil {
+		return
+	}
+
+	record := models.LLMUsage{
+		RequestID:       scope.RequestID,
+		UserID:          scope.UserID,
+		Endpoint:        scope.Endpoint,
+		PromptName:      scope.Prompt.Name,
+		PromptVersion:   scope.Prompt.Version,
+		Provider:        provider,
+		Model:           model,
+		PromptTokens:    usage.PromptTokens,
+		CandidateTokens: usage.CandidateTokens,
+		TotalTokens:     usage.TotalTokens,
+	}
+	if err := usageDB.Create(&record).Error; err != nil {
+		log.Printf("[ERROR] 写入用量记录失败: %v", err)
+	}
+}
+
+// ModelPrice 模型单价，单位为每百万令牌的美元价格
+type ModelPrice struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// defaultPriceTable 内置的模型价格，可通过 LLM_PRICE_TABLE_FILE 覆盖或补充
+var defaultPriceTable = map[string]ModelPrice{
+	"gemini-2.0-flash":      {Input: 0.10, Output: 0.40},
+	"gemini-2.0-flash-lite": {Input: 0.075, Output: 0.30},
+	"gemini-1.5-flash":      {Input: 0.075, Output: 0.30},
+	"gemini-1.5-pro":        {Input: 1.25, Output: 5.00},
+	"gpt-4o-mini":           {Input: 0.15, Output: 0.60},
+	"gpt-4o":                {Input: 2.50, Output: 10.00},
+	"fake":                  {},
+}
+
+var (
+	priceTableOnce sync.Once
+	priceTable     map[string]ModelPrice
+)
+
+// loadPriceTable 合并内置价格和 LLM_PRICE_TABLE_FILE 指定的 JSON 价格表
+func loadPriceTable() map[string]ModelPrice {
+	priceTableOnce.Do(func() {
+		priceTable = make(map[string]ModelPrice, len(defaultPriceTable))
+		for model, price := range defaultPriceTable {
+			priceTable[model] = price
+		}
+
+		path := os.Getenv("LLM_PRICE_TABLE_FILE")
+		if path == "" {
+			return
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("[WARN] 读取价格表失败: %v，使用内置价格", err)
+			return
+		}
+		var custom map[string]ModelPrice
+		if err := json.Unmarshal(data, &custom); err != nil {
+			log.Printf("[WARN] 解析价格表失败: %v，使用内置价格", err)
+			return
+		}
+		for model, price := range custom {
+			priceTable[model] = price
+		}
+		log.Printf("[INFO] 已加载价格表 %s，共 %d 个模型", path, len(custom))
+	})
+	return priceTable
+}
+
+// priceFor 查找模型单价，精确匹配失败时使用最长的前缀匹配（如 gemini-2.0-flash 匹配 gemini-2.0-flash-001）
+func priceFor(model string) (ModelPrice, bool) {
+	table := loadPriceTable()
+	if price, ok := table[model]; ok {
+		return price, true
+	}
+
+	best := ""
+	for name := range table {
+		if strings.HasPrefix(model, name) && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return ModelPrice{}, false
+	}
+	return table[best], true
+}
+
+// EstimateCost 按价格表估算费用（美元）
+func EstimateCost(model string, promptTokens, candidateTokens int) (float64, bool) {
+	price, ok := priceFor(model)
+	if !ok {
+		return 0, false
+	}
+	return (float64(promptTokens)*price.Input + float64(candidateTokens)*price.Output) / 1e6, true
+}
+
+// UsageFilter 用量查询条件，From 和 To 均为包含的日期
+type UsageFilter struct {
+	From    time.Time
+	To      time.Time
+	UserID  *uint
+	GroupBy []string // 可选 day、user、endpoint、request
+}
+
+// usageGroups 支持的汇总维度对应的分组表达式和结果列名
+var usageGroups = map[string]struct{ expr, column string }{
+	"day":      {"DATE(created_at)", "day"},
+	"user":     {"user_id", "user_id"},
+	"endpoint": {"endpoint", "endpoint"},
+	"request":  {"request_id", "request_id"},
+}
+
+// usageRow 按汇总维度和模型分组的查询结果
+type usageRow struct {
+	Day             time.Time
+	UserID          uint
+	Endpoint        string
+	RequestID       string
+	Model           string
+	Requests        int
+	PromptTokens    int
+	CandidateTokens int
+	TotalTokens     int
+}
+
+// QueryUsage 按条件汇总用量，并按模型单价估算费用
+func QueryUsage(filter UsageFilter) (*models.UsageReport, error) {
+	if usageDB == nil {
+		return nil, ErrUsageStoreDisabled
+	}
+
+	selects := []string{"model", "COUNT(*) AS requests", "SUM(prompt_tokens) AS prompt_tokens",
+		"SUM(candidate_tokens) AS candidate_tokens", "SUM(total_tokens) AS total_tokens"}
+	groups := []string{"model"}
+	for _, dim := range filter.GroupBy {
+		group, ok := usageGroups[dim]
+		if !ok {
+			return nil, fmt.Errorf("%w: 不支持的汇总维度 %s", ErrInvalidUsageFilter, dim)
+		}
+		selects = append(selects, group.expr+" AS "+group.column)
+		groups = append(groups, group.expr)
+	}
+
+	query := usageDB.Model(&models.LLMUsage{}).
+		Select(strings.Join(selects, ", ")).
+		Where("created_at >= ? AND created_at < ?", filter.From, filter.To.AddDate(0, 0, 1))
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+
+	var rows []usageRow
+	if err := query.Group(strings.Join(groups, ", ")).Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("查询用量失败: %w", err)
+	}
+
+	report := &models.UsageReport{
+		From:     filter.From.Format("2006-01-02"),
+		To:       filter.To.Format("2006-01-02"),
+		Currency: "USD",
+		Rows:     []models.UsageSummary{},
+	}
+
+	// 同一维度下不同模型的用量合并为一行
+	index := map[string]int{}
+	unpriced := map[string]bool{}
+	for _, row := range rows {
+		cost, ok := EstimateCost(row.Model, row.PromptTokens, row.CandidateTokens)
+		if !ok {
+			unpriced[row.Model] = true
+		}
+
+		summary := models.UsageSummary{}
+		for _, dim := range filter.GroupBy {
+			switch dim {
+			case "day":
+				summary.Date = row.Day.Format("2006-01-02")
+			case "user":
+				userID := row.UserID
+				summary.UserID = &userID
+			case "endpoint":
+				summary.Endpoint = row.Endpoint
+			case "request":
+				summary.RequestID = row.RequestID
+			}
+		}
+		key := fmt.Sprintf("%s|%v|%s|%s", summary.Date, row.UserID, summary.Endpoint, summary.RequestID)
+
+		i, ok := index[key]
+		if !ok {
+			i = len(report.Rows)
+			index[key] = i
+			report.Rows = append(report.Rows, summary)
+		}
+		addUsage(&report.Rows[i], row, cost)
+		addUsage(&report.Total, row, cost)
+	}
+
+	for model := range unpriced {
+		report.UnpricedModels = append(report.UnpricedModels, model)
+	}
+	sort.Strings(report.UnpricedModels)
+	sort.SliceStable(report.Rows, func(i, j int) bool {
+		if report.Rows[i].Date != report.Rows[j].Date {
+			return report.Rows[i].Date < report.Rows[j].Date
+		}
+		return report.Rows[i].EstimatedCost > report.Rows[j].EstimatedCost
+	})
+
+	return report, nil
+}
+
+// addUsage 将一行查询结果累加到汇总
+func addUsage(summary *models.UsageSummary, row usageRow, cost float64) {
+	summary.Requests += row.Requests
+	summary.PromptTokens += row.PromptTokens
+	summary.CandidateTokens += row.CandidateTokens
+	summary.TotalTokens += row.TotalTokens
+	summary.EstimatedCost += cost
+}
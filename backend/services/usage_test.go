@@ -0,0 +1,68 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+// scriptedStream 依次返回片段，片段耗尽后返回 err
+type scriptedStream struct {
+	chunks []string
+	err    error
+}
+
+func (s *scriptedStream) Next() (string, error) {
+	if len(s.chunks) == 0 {
+		return "", s.err
+	}
+	chunk := s.chunks[0]
+	s.chunks = s.chunks[1:]
+	return chunk, nil
+}
+
+func (s *scriptedStream) Usage() TokenUsage {
+	return TokenUsage{PromptTokens: 10, CandidateTokens: 2, TotalTokens: 12}
+}
+
+func (s *scriptedStream) FinishReason() FinishReason { return FinishReasonStop }
+
+func (s *scriptedStream) Close() error { return nil }
+
+func TestUsageStreamRecordsOnce(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		read int // 关闭前调用 Next 的次数
+	}{
+		{name: "eof", err: io.EOF, read: 3},
+		{name: "error", err: errors.New("连接中断"), read: 3},
+		{name: "closed early", err: io.EOF, read: 1},
+		{name: "closed unread", err: io.EOF, read: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var records []TokenUsage
+			orig := usageRecorder
+			usageRecorder = func(_ UsageScope, _, _ string, usage TokenUsage) {
+				records = append(records, usage)
+			}
+			defer func() { usageRecorder = orig }()
+
+			provider := &usageProvider{inner: NewFakeProvider(), scope: UsageScope{Endpoint: UsageEndpointChat}}
+			stream := &usageStream{inner: &scriptedStream{chunks: []string{"a", "b"}, err: tt.err}, provider: provider}
+			for i := 0; i < tt.read; i++ {
+				stream.Next()
+			}
+			stream.Close()
+			stream.Close()
+
+			if len(records) != 1 {
+				t.Fatalf("recorded %d times, want 1", len(records))
+			}
+			if records[0].TotalTokens != 12 {
+				t.Fatalf("usage = %+v", records[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+
+	"cloud.google.com/go/vertexai/genai"
+	"google.golang.org/api/option"
+)
+
+// vertexClientPool 进程级的 genai.Client 池，按项目和区域各保留一个长连接客户端
+// genai.Client 可以在多个 goroutine 间安全共享，每次调用只需创建轻量的 GenerativeModel
+type vertexClientPool struct {
+	mu      sync.Mutex
+	clients map[string]*genai.Client
+	closed  bool
+}
+
+var vertexPool = &vertexClientPool{clients: make(map[string]*genai.Client)}
+
+// InitVertexAI 在启动时创建共享的 Vertex AI 客户端，避免首个请求承担建连开销
+func InitVertexAI() error {
+	c := NewVertexAIClient()
+	_, err := getVertexClient(c.projectID, c.location)
+	return err
+}
+
+// CloseVertexAI 关闭所有共享的 Vertex AI 客户端，应在服务退出时调用
+func CloseVertexAI() {
+	vertexPool.mu.Lock()
+	defer vertexPool.mu.Unlock()
+
+	for key, client := range vertexPool.clients {
+		if err := client.Close(); err != nil {
+			log.Printf("[WARN] 关闭 Vertex AI 客户端 %s 失败: %v", key, err)
+		}
+		delete(vertexPool.clients, key)
+	}
+	vertexPool.closed = true
+	log.Printf("[INFO] Vertex AI 客户端已关闭")
+}
+
+// getVertexClient 返回指定项目和区域的共享客户端，不存在时创建
+func getVertexClient(projectID, location string) (*genai.Client, error) {
+	key := projectID + "/" + location
+
+	vertexPool.mu.Lock()
+	defer vertexPool.mu.Unlock()
+
+	if vertexPool.closed {
+		return nil, fmt.Errorf("Vertex AI 客户端已关闭")
+	}
+	if client, ok := vertexPool.clients[key]; ok {
+		return client, nil
+	}
+
+	// 使用环境变量中的凭证文件路径
+	credentialsFile := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+
+	// 检查凭证文件是否存在
+	if _, err := os.Stat(credentialsFile); os.IsNotExist(err) {
+		log.Printf("[ERROR] 凭证文件不存在: %s", credentialsFile)
+		return nil, fmt.Errorf("%w: 凭证文件不存在: %s", ErrCredentials, credentialsFile)
+	}
+
+	log.Printf("[DEBUG] 开始创建共享 Vertex AI 客户端: 项目ID: %s, 位置: %s", projectID, location)
+
+	// 客户端生命周期与进程一致，不能使用请求级上下文创建
+	// gRPC 会自动使用环境变量中的 HTTP_PROXY/HTTPS_PROXY 设置
+	client, err := genai.NewClient(context.Background(), projectID, location, option.WithCredentialsFile(credentialsFile))
+	if err != nil {
+		log.Printf("[ERROR] 创建AI客户端失败: %v", err)
+		return nil, fmt.Errorf("创建AI客户端失败: %w", err)
+	}
+
+	vertexPool.clients[key] = client
+	log.Printf("[DEBUG] 共享 Vertex AI 客户端创建成功")
+	return client, nil
+}
@@ -0,0 +1,21 @@
+package utils
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// IsAdminUser 用户是否在 ADMIN_USER_IDS（逗号分隔的用户ID）管理员名单中，匿名用户始终不是管理员
+func IsAdminUser(userID uint) bool {
+	if userID == 0 {
+		return false
+	}
+	for _, value := range strings.Split(os.Getenv("ADMIN_USER_IDS"), ",") {
+		id, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+		if err == nil && uint(id) == userID {
+			return true
+		}
+	}
+	return false
+}